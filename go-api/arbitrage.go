@@ -0,0 +1,271 @@
+// arbitrage.go
+// Atomic arbitrage: one transaction buys a token in one pool and sells it in another, ending with
+// more of the token it started with. Nothing can go wrong in between (if the prices moved, the
+// searcher's contract simply reverts), which is why searchers fight over these in every block.
+//
+// We follow the normalized swaps of each transaction (swap_decoders.go) hop by hop: a path whose
+// token out is the next swap's token in, until it gets back to the token it started with. Pools
+// that only name their tokens by position ("coin1") are matched against the transaction's ERC-20
+// Transfer logs, which also tell us how much of the start token the searcher actually kept.
+package main
+
+import (
+	"math/big"
+	"net/http"
+	"strings"
+)
+
+var (
+	// erc20TransferTopic is Transfer(address indexed from, address indexed to, uint256 value)
+	erc20TransferTopic = strings.ToLower(keccakTopic("Transfer(address,address,uint256)"))
+
+	// wethAddress lets us price WETH-denominated profits in ETH
+	wethAddress = "0xc02aaa39b223fe8d0a0e5c4f27ead9083c756cc2"
+)
+
+// tokenTransfer is one ERC-20 Transfer log
+type tokenTransfer struct {
+	Token string
+	From  string
+	To    string
+	Value *big.Int
+}
+
+// arbitrage is one cyclic path found inside a single transaction
+type arbitrage struct {
+	TxHash   string `json:"txHash"`
+	TxIndex  int    `json:"txIndex"`
+	From     string `json:"from"`     // account that sent the tx
+	Searcher string `json:"searcher"` // contract that ran the arbitrage (the sender itself if it went through a public router)
+
+	Token     string   `json:"token"`     // asset the cycle starts and ends in
+	AmountIn  string   `json:"amountIn"`  // raw units put into the first pool
+	AmountOut string   `json:"amountOut"` // raw units taken out of the last pool
+	Profit    string   `json:"profit"`    // amountOut - amountIn, before gas
+	Path      []string `json:"path"`      // tokens in order, ending where it started
+	Pools     []string `json:"pools"`     // pools traversed, in order
+	Dexes     []string `json:"dexes"`     // protocol of each hop
+
+	// What the Transfer logs say the searcher kept of the start token (omitted if they don't mention it)
+	SearcherNet string `json:"searcherNet,omitempty"`
+
+	GasUsed    uint64 `json:"gasUsed"`
+	GasCostWei string `json:"gasCostWei"`
+	GasCostEth string `json:"gasCostEth"`
+
+	// Only when the cycle is in WETH, so profit and gas are in the same unit
+	ProfitEth    string `json:"profitEth,omitempty"`
+	NetProfitEth string `json:"netProfitEth,omitempty"` // profit - gas (builder tips paid from the contract aren't seen)
+}
+
+// transfersFromReceipt decodes the ERC-20 Transfer logs of one receipt. ERC-721 transfers share the
+// event signature but index the token id, so they have no data and are skipped.
+func transfersFromReceipt(rcpt *receipt) []tokenTransfer {
+	var out []tokenTransfer
+	for _, lg := range rcpt.Logs {
+		if len(lg.Topics) != 3 || strings.ToLower(lg.Topics[0]) != erc20TransferTopic {
+			continue
+		}
+		v := logWord(decodeHex(lg.Data), 0)
+		if v == nil {
+			continue
+		}
+		out = append(out, tokenTransfer{
+			Token: strings.ToLower(lg.Address),
+			From:  topicAddress(lg.Topics[1]),
+			To:    topicAddress(lg.Topics[2]),
+			Value: v,
+		})
+	}
+	return out
+}
+
+// isTokenAddress tells real token addresses from pool-relative names like "token0" or "coin2"
+func isTokenAddress(t string) bool {
+	return reAddress.MatchString(t)
+}
+
+// resolveByTransfers names a swap's tokens from the transfers into and out of the contract holding
+// the pool's tokens (same amount, same direction). What can't be resolved is qualified with the pool
+// so it can never be mistaken for a token of another pool.
+func resolveByTransfers(s swapEvent, transfers []tokenTransfer) swapEvent {
+	if !isTokenAddress(s.TokenIn) {
+		for _, t := range transfers {
+			if t.To == s.Contract && t.Value.Cmp(s.AmountIn) == 0 {
+				s.TokenIn = t.Token
+				break
+			}
+		}
+	}
+	if !isTokenAddress(s.TokenOut) {
+		for _, t := range transfers {
+			if t.From == s.Contract && t.Value.Cmp(s.AmountOut) == 0 {
+				s.TokenOut = t.Token
+				break
+			}
+		}
+	}
+	if !isTokenAddress(s.TokenIn) {
+		s.TokenIn = s.Pool + "/" + s.TokenIn
+	}
+	if !isTokenAddress(s.TokenOut) {
+		s.TokenOut = s.Pool + "/" + s.TokenOut
+	}
+	return s
+}
+
+// findCycles chains one transaction's swaps (in log order) into paths and returns the ones that
+// come back to their start token. Each swap extends an open path that ends in its token in, or
+// starts a new one; a path closes the moment it returns to where it began. When several open paths
+// end in that token (other trades in the same tx), the swap goes to the one whose last output it
+// spends exactly, then to one it would close, then to the most recent.
+func findCycles(swaps []swapEvent) [][]swapEvent {
+	var open, closed [][]swapEvent
+	for _, s := range swaps {
+		attached, rank := -1, 0
+		for i := len(open) - 1; i >= 0; i-- {
+			last := open[i][len(open[i])-1]
+			if last.TokenOut != s.TokenIn {
+				continue
+			}
+			r := 1
+			if last.AmountOut.Cmp(s.AmountIn) == 0 {
+				r = 3
+			} else if open[i][0].TokenIn == s.TokenOut {
+				r = 2
+			}
+			if r > rank {
+				attached, rank = i, r
+			}
+		}
+		if attached < 0 {
+			open = append(open, []swapEvent{s})
+			continue
+		}
+		open[attached] = append(open[attached], s)
+		if p := open[attached]; p[0].TokenIn == s.TokenOut {
+			closed = append(closed, p)
+			open = append(open[:attached], open[attached+1:]...)
+		}
+	}
+	return closed
+}
+
+// detectArbitrage finds profitable cycles per transaction. swaps must come from the same receipts
+// (swapsFromReceipts), so TxIndex lines up with the receipt index.
+func detectArbitrage(receipts []*receipt, swaps []swapEvent) []arbitrage {
+	byTx := map[int][]swapEvent{}
+	for _, s := range swaps {
+		if s.AmountIn != nil && s.AmountOut != nil {
+			byTx[s.TxIndex] = append(byTx[s.TxIndex], s)
+		}
+	}
+
+	out := []arbitrage{}
+	for idx := 0; idx < len(receipts); idx++ {
+		txSwaps := byTx[idx]
+		if len(txSwaps) < 2 || receipts[idx] == nil {
+			continue
+		}
+		transfers := transfersFromReceipt(receipts[idx])
+		for i := range txSwaps {
+			txSwaps[i] = resolveByTransfers(txSwaps[i], transfers)
+		}
+
+		for _, cycle := range findCycles(txSwaps) {
+			first, last := cycle[0], cycle[len(cycle)-1]
+			profit := new(big.Int).Sub(last.AmountOut, first.AmountIn)
+			if profit.Sign() <= 0 {
+				continue
+			}
+			pools := map[string]bool{}
+			arb := arbitrage{
+				TxHash:    first.TxHash,
+				TxIndex:   idx,
+				From:      first.TxFrom,
+				Searcher:  first.TxTo,
+				Token:     first.TokenIn,
+				AmountIn:  first.AmountIn.String(),
+				AmountOut: last.AmountOut.String(),
+				Profit:    profit.String(),
+				Path:      []string{first.TokenIn},
+				GasUsed:   first.TxGasUsed,
+			}
+			for _, s := range cycle {
+				pools[s.Pool] = true
+				arb.Path = append(arb.Path, s.TokenOut)
+				arb.Pools = append(arb.Pools, s.Pool)
+				arb.Dexes = append(arb.Dexes, s.Dex)
+			}
+			if len(pools) < 2 {
+				continue // in and out of the same pool is a round trip, not an arbitrage
+			}
+			if arb.Searcher == "" || publicRouters[arb.Searcher] {
+				arb.Searcher = arb.From
+			}
+
+			if isTokenAddress(arb.Token) {
+				net, seen := new(big.Int), false
+				for _, t := range transfers {
+					if t.Token != arb.Token {
+						continue
+					}
+					if t.To == arb.Searcher {
+						net.Add(net, t.Value)
+						seen = true
+					}
+					if t.From == arb.Searcher {
+						net.Sub(net, t.Value)
+						seen = true
+					}
+				}
+				if seen {
+					arb.SearcherNet = net.String()
+				}
+			}
+
+			gasCost := new(big.Int)
+			if first.TxGasPrice != nil {
+				gasCost.Mul(new(big.Int).SetUint64(first.TxGasUsed), first.TxGasPrice)
+			}
+			arb.GasCostWei = gasCost.String()
+			arb.GasCostEth = weiToETHDecimal(gasCost)
+			if arb.Token == wethAddress {
+				arb.ProfitEth = weiToETHDecimal(profit)
+				arb.NetProfitEth = weiToETHDecimal(new(big.Int).Sub(profit, gasCost))
+			}
+			out = append(out, arb)
+		}
+	}
+	return out
+}
+
+// handleArbitrage serves GET /api/mev/arbitrage?block=<number|latest>
+func handleArbitrage(w http.ResponseWriter, r *http.Request) {
+	blockTag := r.URL.Query().Get("block")
+	if blockTag == "" {
+		blockTag = "latest"
+	}
+
+	b, err := fetchBlockFull(blockTag)
+	if err != nil {
+		writeErr(w, http.StatusInternalServerError, "EL_BLOCK_FETCH", "Failed to fetch block", "Check RPC_HTTP_URL and node sync state")
+		return
+	}
+	receipts, stats, err := scanBlockReceipts(b)
+	if err != nil {
+		writeErr(w, http.StatusInternalServerError, "EL_RECEIPTS", "Failed to scan receipts", "Node may still be syncing or pruning receipts")
+		return
+	}
+	swaps := swapsFromReceipts(b, receipts)
+
+	writeOK(w, map[string]any{
+		"block":      b.Number,
+		"blockHash":  b.Hash,
+		"swapCount":  len(swaps),
+		"arbitrages": detectArbitrage(receipts, swaps),
+		"receipts":   stats,
+		"sources":    sourcesInfo(),
+		"note":       "Heuristic: swaps inside one transaction that form a path back to the token it started with, through at least two pools, and end with more of it. Amounts are raw token units; gas excludes direct builder payments.",
+	})
+}
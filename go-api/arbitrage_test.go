@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"testing"
+)
+
+// Hand-built swaps for one searcher transaction, fed straight to detectArbitrage
+
+var (
+	usdc = "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48"
+	usdt = "0xdac17f958d2ee523a2206206994597c13d831ec7"
+	dai  = "0x6b175474e89094c44da98b954eedeac495271d0f"
+
+	arbPool1  = "0x0000000000000000000000000000000000000a01"
+	arbPool2  = "0x0000000000000000000000000000000000000a02"
+	arbPool3  = "0x0000000000000000000000000000000000000a03"
+	threePool = "0xbebc44782c7db0a1a60cb6fe97d0b483032ff1c7"
+
+	searcherEOA      = "0x00000000000000000000000000000000000e0a99"
+	searcherContract = "0x00000000000000000000000000000000005ea7c4"
+)
+
+// amt parses a decimal amount of raw token units
+func amt(dec string) *big.Int {
+	v, ok := new(big.Int).SetString(dec, 10)
+	if !ok {
+		panic("bad amount " + dec)
+	}
+	return v
+}
+
+// arbHop is one swap in the searcher's transaction, in a pool that holds its own tokens
+func arbHop(pool, dex, tokenIn, tokenOut, in, out string) swapEvent {
+	return swapEvent{
+		TxHash: "0xa4b", TxFrom: searcherEOA, TxTo: searcherContract,
+		Pool: pool, Contract: pool, Dex: dex,
+		TokenIn: tokenIn, TokenOut: tokenOut, AmountIn: amt(in), AmountOut: amt(out),
+		TxGasUsed: 200000, TxGasPrice: big.NewInt(1e9),
+	}
+}
+
+// arbReceipt is the transaction's receipt holding just its ERC-20 Transfer logs
+func arbReceipt(t *testing.T, transfers ...tokenTransfer) *receipt {
+	t.Helper()
+	type jsonLog struct {
+		Address string   `json:"address"`
+		Topics  []string `json:"topics"`
+		Data    string   `json:"data"`
+	}
+	logs := []jsonLog{}
+	for _, tr := range transfers {
+		logs = append(logs, jsonLog{
+			Address: tr.Token,
+			Topics:  []string{erc20TransferTopic, topic(tr.From), topic(tr.To)},
+			Data:    fmt.Sprintf("0x%064x", tr.Value),
+		})
+	}
+	raw, _ := json.Marshal(map[string]any{"transactionHash": "0xa4b", "logs": logs})
+	var r receipt
+	if err := json.Unmarshal(raw, &r); err != nil {
+		t.Fatal(err)
+	}
+	return &r
+}
+
+func TestArbitrageTwoHopWETH(t *testing.T) {
+	swaps := []swapEvent{
+		arbHop(arbPool1, "uniswap_v2", wethAddress, usdc, "1000000000000000000", "2500000000"),
+		arbHop(arbPool2, "uniswap_v3", usdc, wethAddress, "2500000000", "1010000000000000000"),
+	}
+	rcpt := arbReceipt(t,
+		tokenTransfer{Token: wethAddress, From: searcherContract, To: arbPool1, Value: amt("1000000000000000000")},
+		tokenTransfer{Token: wethAddress, From: arbPool2, To: searcherContract, Value: amt("1010000000000000000")},
+	)
+
+	arbs := detectArbitrage([]*receipt{rcpt}, swaps)
+	if len(arbs) != 1 {
+		t.Fatalf("found %d arbitrages, want 1", len(arbs))
+	}
+	a := arbs[0]
+	if a.Searcher != searcherContract || a.Token != wethAddress || a.Profit != "10000000000000000" {
+		t.Errorf("searcher=%s token=%s profit=%s", a.Searcher, a.Token, a.Profit)
+	}
+	if fmt.Sprint(a.Path) != fmt.Sprint([]string{wethAddress, usdc, wethAddress}) || fmt.Sprint(a.Pools) != fmt.Sprint([]string{arbPool1, arbPool2}) {
+		t.Errorf("path=%v pools=%v", a.Path, a.Pools)
+	}
+	if a.SearcherNet != "10000000000000000" || a.GasCostEth != "0.0002" || a.ProfitEth != "0.01" || a.NetProfitEth != "0.0098" {
+		t.Errorf("searcherNet=%s gas=%s profit=%s net=%s", a.SearcherNet, a.GasCostEth, a.ProfitEth, a.NetProfitEth)
+	}
+}
+
+func TestArbitrageThreeHopWETH(t *testing.T) {
+	swaps := []swapEvent{
+		arbHop(arbPool1, "uniswap_v2", wethAddress, usdc, "1000000000000000000", "2500000000"),
+		arbHop(arbPool2, "uniswap_v3", usdc, dai, "2500000000", "2501000000000000000000"),
+		arbHop(arbPool3, "sushiswap_v2", dai, wethAddress, "2501000000000000000000", "1002000000000000000"),
+	}
+	arbs := detectArbitrage([]*receipt{arbReceipt(t)}, swaps)
+	if len(arbs) != 1 {
+		t.Fatalf("found %d arbitrages, want 1", len(arbs))
+	}
+	a := arbs[0]
+	if fmt.Sprint(a.Path) != fmt.Sprint([]string{wethAddress, usdc, dai, wethAddress}) || len(a.Dexes) != 3 || a.Profit != "2000000000000000" {
+		t.Errorf("path=%v dexes=%v profit=%s", a.Path, a.Dexes, a.Profit)
+	}
+	if a.SearcherNet != "" {
+		t.Errorf("searcherNet=%q with no WETH transfers in the receipt", a.SearcherNet)
+	}
+}
+
+func TestArbitrageRejectsRoundTripsAndLosses(t *testing.T) {
+	// In and back out of the same pool: a round trip, whatever it earned
+	roundTrip := []swapEvent{
+		arbHop(arbPool1, "uniswap_v2", wethAddress, usdc, "1000000000000000000", "2500000000"),
+		arbHop(arbPool1, "uniswap_v2", usdc, wethAddress, "2500000000", "1010000000000000000"),
+	}
+	if arbs := detectArbitrage([]*receipt{arbReceipt(t)}, roundTrip); len(arbs) != 0 {
+		t.Errorf("same-pool round trip reported: %+v", arbs)
+	}
+
+	// A cycle that comes back with less than it started with
+	loss := []swapEvent{
+		arbHop(arbPool1, "uniswap_v2", wethAddress, usdc, "1000000000000000000", "2500000000"),
+		arbHop(arbPool2, "uniswap_v3", usdc, wethAddress, "2500000000", "990000000000000000"),
+	}
+	if arbs := detectArbitrage([]*receipt{arbReceipt(t)}, loss); len(arbs) != 0 {
+		t.Errorf("unprofitable cycle reported: %+v", arbs)
+	}
+}
+
+func TestArbitrageCurveHopResolvedByTransfers(t *testing.T) {
+	curve := arbHop(threePool, "curve", "coin1", "coin2", "2500000000", "2499000000")
+	swaps := []swapEvent{
+		arbHop(arbPool1, "uniswap_v3", wethAddress, usdc, "1000000000000000000", "2500000000"),
+		curve,
+		arbHop(arbPool2, "uniswap_v2", usdt, wethAddress, "2499000000", "1005000000000000000"),
+	}
+	rcpt := arbReceipt(t,
+		tokenTransfer{Token: usdc, From: searcherContract, To: threePool, Value: amt("2500000000")},
+		tokenTransfer{Token: usdt, From: threePool, To: searcherContract, Value: amt("2499000000")},
+	)
+
+	arbs := detectArbitrage([]*receipt{rcpt}, swaps)
+	if len(arbs) != 1 {
+		t.Fatalf("found %d arbitrages, want 1", len(arbs))
+	}
+	if want := []string{wethAddress, usdc, usdt, wethAddress}; fmt.Sprint(arbs[0].Path) != fmt.Sprint(want) {
+		t.Errorf("path = %v, want %v", arbs[0].Path, want)
+	}
+
+	// Without the transfers the coins stay pool-relative and nothing chains through them
+	if arbs := detectArbitrage([]*receipt{arbReceipt(t)}, swaps); len(arbs) != 0 {
+		t.Errorf("unresolved Curve hop chained into %+v", arbs)
+	}
+}
+
+func TestArbitrageIgnoresInterleavedSwaps(t *testing.T) {
+	// A DAI->USDC trade in the same tx sits between the two legs and also ends in USDC
+	swaps := []swapEvent{
+		arbHop(arbPool1, "uniswap_v2", wethAddress, usdc, "1000000000000000000", "2500000000"),
+		arbHop(arbPool3, "uniswap_v2", dai, usdc, "1000000000000000000000", "999000000"),
+		arbHop(arbPool2, "uniswap_v3", usdc, wethAddress, "2500000000", "1010000000000000000"),
+	}
+	arbs := detectArbitrage([]*receipt{arbReceipt(t)}, swaps)
+	if len(arbs) != 1 {
+		t.Fatalf("found %d arbitrages, want 1", len(arbs))
+	}
+	if want := []string{arbPool1, arbPool2}; fmt.Sprint(arbs[0].Pools) != fmt.Sprint(want) {
+		t.Errorf("pools = %v, want %v", arbs[0].Pools, want)
+	}
+}
@@ -0,0 +1,179 @@
+// auction.go
+// The PBS auction for one slot, as a timeline. During every slot builders keep sending relays
+// better and better blocks, and when the proposer asks for a header the relay hands over the best
+// one it has. /api/auction/{slot} gathers every submission for the slot from all relays, orders
+// them by when the relay received them, measures that against the slot's start time, and marks
+// the bid that actually got delivered.
+package main
+
+import (
+	"math/big"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// auctionBid is one builder submission on the timeline
+type auctionBid struct {
+	BlockHash     string   `json:"block_hash"`
+	BuilderPubkey string   `json:"builder_pubkey"`
+	Value         string   `json:"value"` // wei
+	ValueETH      string   `json:"value_eth"`
+	TimestampMs   int64    `json:"timestamp_ms"`
+	OffsetMs      int64    `json:"offset_ms"` // from slot start; negative means before the slot began
+	NumTx         uint64   `json:"num_tx"`
+	GasUsed       uint64   `json:"gas_used"`
+	Optimistic    bool     `json:"optimistic_submission"`
+	Relays        []string `json:"relays"`
+	Winner        bool     `json:"winner"`
+}
+
+// auctionCurvePoint is one step of a builder's bid-value curve
+type auctionCurvePoint struct {
+	OffsetMs int64  `json:"offset_ms"`
+	Value    string `json:"value"`
+	ValueETH string `json:"value_eth"`
+}
+
+// auctionBuilder summarizes one builder's bidding during the slot
+type auctionBuilder struct {
+	BuilderPubkey string              `json:"builder_pubkey"`
+	BuilderName   string              `json:"builder_name,omitempty"`
+	Bids          int                 `json:"bids"`
+	MaxValue      string              `json:"max_value"`
+	MaxValueETH   string              `json:"max_value_eth"`
+	FirstOffsetMs int64               `json:"first_offset_ms"`
+	LastOffsetMs  int64               `json:"last_offset_ms"`
+	Won           bool                `json:"won"`
+	Curve         []auctionCurvePoint `json:"curve"`
+
+	maxValue *big.Int
+}
+
+// handleAuction serves GET /api/auction/{slot|latest}
+func handleAuction(w http.ResponseWriter, r *http.Request) {
+	clk, err := getChainClock()
+	if err != nil {
+		writeErr(w, http.StatusTooManyRequests, "BEACON", "Could not load chain genesis/spec", "The slot clock comes from /eth/v1/beacon/genesis and /eth/v1/config/spec. Check BEACON_API_URL.")
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/api/auction/")
+	var slot uint64
+	if id == "" || id == "latest" {
+		// The current slot's auction is still running - show the last finished one
+		slot = clk.CurrentSlot(time.Now())
+		if slot > 0 {
+			slot--
+		}
+	} else if slot, err = strconv.ParseUint(id, 10, 64); err != nil {
+		writeErr(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid slot", "Use /api/auction/{slot} with a decimal slot number, or /api/auction/latest")
+		return
+	}
+
+	// Every submission for the slot, from every relay
+	received, err := relayFanoutRecords(relayQuery{Slot: &slot}.path(relayReceivedEndpoint))
+	if err != nil {
+		writeErr(w, http.StatusTooManyRequests, "RELAY", "Failed to fetch builder submissions", "MEV relays may be rate limiting or unavailable")
+		return
+	}
+
+	// And which one won (no delivery means the proposer built locally or the slot was missed)
+	winner := ""
+	var winnerRelays []string
+	if delivered, err := relayFanoutRecords(relayQuery{Slot: &slot}.path(relayDeliveredEndpoint)); err == nil {
+		for _, t := range delivered.traces() {
+			if t.Slot == slot {
+				winner, winnerRelays = t.BlockHash, t.Relays
+				break
+			}
+		}
+	}
+
+	recs := received.Records
+	sort.SliceStable(recs, func(i, j int) bool {
+		if recs[i].TimestampMs != recs[j].TimestampMs {
+			return recs[i].TimestampMs < recs[j].TimestampMs
+		}
+		return recs[i].BlockHash < recs[j].BlockHash
+	})
+
+	slotStartMs := int64(clk.SlotStart(slot)) * 1000
+	bids := make([]auctionBid, 0, len(recs))
+	builders := map[string]*auctionBuilder{}
+	var winningBid, highestBid *auctionBid
+	var highestValue *big.Int
+
+	for _, rec := range recs {
+		if rec.Slot != slot {
+			continue
+		}
+		bid := auctionBid{
+			BlockHash:     rec.BlockHash,
+			BuilderPubkey: rec.BuilderPubkey,
+			Value:         rec.Value.String(),
+			ValueETH:      rec.ValueETH(),
+			TimestampMs:   rec.TimestampMs,
+			OffsetMs:      rec.TimestampMs - slotStartMs,
+			NumTx:         rec.NumTx,
+			GasUsed:       rec.GasUsed,
+			Optimistic:    rec.OptimisticSubmission,
+			Relays:        rec.Relays,
+			Winner:        winner != "" && rec.BlockHash == winner,
+		}
+		bids = append(bids, bid)
+
+		b, ok := builders[rec.BuilderPubkey]
+		if !ok {
+			b = &auctionBuilder{BuilderPubkey: rec.BuilderPubkey, BuilderName: builderName(rec.BuilderPubkey), FirstOffsetMs: bid.OffsetMs}
+			builders[rec.BuilderPubkey] = b
+		}
+		b.Bids++
+		b.LastOffsetMs = bid.OffsetMs
+		b.Won = b.Won || bid.Winner
+		b.Curve = append(b.Curve, auctionCurvePoint{OffsetMs: bid.OffsetMs, Value: bid.Value, ValueETH: bid.ValueETH})
+		if b.maxValue == nil || rec.Value.Cmp(b.maxValue) > 0 {
+			b.maxValue, b.MaxValue, b.MaxValueETH = rec.Value, bid.Value, bid.ValueETH
+		}
+		if highestValue == nil || rec.Value.Cmp(highestValue) > 0 {
+			highestValue = rec.Value
+		}
+	}
+	for i := range bids {
+		if bids[i].Winner {
+			winningBid = &bids[i]
+		}
+		if highestBid == nil && highestValue != nil && bids[i].Value == highestValue.String() {
+			highestBid = &bids[i]
+		}
+	}
+
+	builderList := make([]*auctionBuilder, 0, len(builders))
+	for _, b := range builders {
+		builderList = append(builderList, b)
+	}
+	sort.Slice(builderList, func(i, j int) bool {
+		if c := builderList[i].maxValue.Cmp(builderList[j].maxValue); c != 0 {
+			return c > 0
+		}
+		return builderList[i].BuilderPubkey < builderList[j].BuilderPubkey
+	})
+
+	writeOK(w, map[string]any{
+		"slot":          slot,
+		"epoch":         clk.EpochOf(slot),
+		"slot_start":    clk.SlotStart(slot),
+		"bid_count":     len(bids),
+		"builder_count": len(builderList),
+		"delivered":     winner != "",
+		"winner":        winningBid,
+		"winner_relays": winnerRelays,
+		"highest_bid":   highestBid,
+		"bids":          bids,
+		"builders":      builderList,
+		"relays":        received.Relays,
+		"timed_out":     received.TimedOut,
+	})
+}
@@ -4,6 +4,7 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -24,6 +25,17 @@ func beaconGET(path string) (json.RawMessage, int, error) {
 		return body, status, nil
 	}
 
+	return beaconFetch(path)
+}
+
+// beaconGETFresh skips the cache read but still refreshes the cache entry.
+// Use it when we know the cached answer is stale (e.g. an event said finality moved).
+func beaconGETFresh(path string) (json.RawMessage, int, error) {
+	return beaconFetch(path)
+}
+
+// beaconFetch does the network request and updates the cache and health monitor
+func beaconFetch(path string) (json.RawMessage, int, error) {
 	url := strings.TrimRight(beaconBase, "/") + path
 	resp, err := beaconHTTPClient.Get(url)
 	if err != nil {
@@ -48,6 +60,39 @@ func beaconGET(path string) (json.RawMessage, int, error) {
 	return json.RawMessage(body), resp.StatusCode, nil
 }
 
+// beaconPOST sends a JSON body to the beacon API (duty lookups take the validator indices as a
+// POST body). Responses are cached like GETs, keyed by path and body.
+func beaconPOST(path string, payload any) (json.RawMessage, int, error) {
+	reqBody, err := json.Marshal(payload)
+	if err != nil {
+		return nil, 0, err
+	}
+	key := "POST " + path + " " + string(reqBody)
+	if body, status, ok := beaconCacheGet(key); ok {
+		return body, status, nil
+	}
+
+	url := strings.TrimRight(beaconBase, "/") + path
+	resp, err := beaconHTTPClient.Post(url, "application/json", bytes.NewReader(reqBody))
+	if err != nil {
+		if beaconHealth != nil {
+			beaconHealth.SetError(err)
+		}
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	beaconCacheSet(key, json.RawMessage(body), resp.StatusCode)
+
+	if beaconHealth != nil && resp.StatusCode/100 == 2 {
+		beaconHealth.SetSuccess()
+	} else if beaconHealth != nil {
+		beaconHealth.SetError(fmt.Errorf("HTTP %d", resp.StatusCode))
+	}
+	return json.RawMessage(body), resp.StatusCode, nil
+}
+
 // === Beacon API caching ===
 // Same idea as relay caching - reduce load on public beacon APIs which rate limit heavily
 
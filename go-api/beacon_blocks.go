@@ -0,0 +1,217 @@
+// beacon_blocks.go
+// Maps an execution-layer block onto the beacon block that carried it.
+// Since the Merge every execution payload lives inside exactly one beacon block, and the payload's
+// timestamp pins down which slot that must be. But the slot might be empty on the canonical chain
+// (missed, or our execution block was orphaned), or hold a different payload (reorged), so we
+// fetch the beacon block and check execution_payload.block_hash instead of trusting arithmetic.
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// beaconBlockMatch describes how an execution block relates to the canonical beacon chain
+type beaconBlockMatch struct {
+	Slot   uint64 `json:"slot"`
+	Epoch  uint64 `json:"epoch"`
+	Status string `json:"status"` // "canonical", "missed", "reorged" or "unknown"
+	Detail string `json:"detail"`
+
+	// Only set when the slot has a beacon block
+	BlockRoot          string `json:"block_root,omitempty"`
+	ProposerIndex      string `json:"proposer_index,omitempty"`
+	Graffiti           string `json:"graffiti,omitempty"` // decoded text when printable
+	GraffitiHex        string `json:"graffiti_hex,omitempty"`
+	AttestationCount   int    `json:"attestation_count"`
+	ExecutionBlockHash string `json:"execution_block_hash,omitempty"` // payload actually in the slot
+	Finalized          bool   `json:"finalized"`
+
+	// Empty slots between this block and its parent
+	ParentSlot        *uint64 `json:"parent_slot,omitempty"`
+	MissedSlotsBefore uint64  `json:"missed_slots_before"`
+}
+
+// beaconBlockV2 is the part of /eth/v2/beacon/blocks/{id} we read
+type beaconBlockV2 struct {
+	Version   string `json:"version"`
+	Finalized bool   `json:"finalized"`
+	Data      struct {
+		Message struct {
+			Slot          string `json:"slot"`
+			ProposerIndex string `json:"proposer_index"`
+			ParentRoot    string `json:"parent_root"`
+			Body          struct {
+				Graffiti         string            `json:"graffiti"`
+				Attestations     []json.RawMessage `json:"attestations"`
+				ExecutionPayload struct {
+					BlockHash    string `json:"block_hash"`
+					BlockNumber  string `json:"block_number"`
+					FeeRecipient string `json:"fee_recipient"`
+					ExtraData    string `json:"extra_data"`
+				} `json:"execution_payload"`
+			} `json:"body"`
+		} `json:"message"`
+	} `json:"data"`
+}
+
+// fetchBeaconBlock gets a beacon block by slot/root. found is false on 404 (empty slot).
+func fetchBeaconBlock(id string) (blk *beaconBlockV2, found bool, err error) {
+	raw, status, err := beaconGET("/eth/v2/beacon/blocks/" + id)
+	if err != nil {
+		return nil, false, err
+	}
+	if status == http.StatusNotFound {
+		return nil, false, nil
+	}
+	if status/100 != 2 {
+		return nil, false, fmt.Errorf("beacon block %s: HTTP %d", id, status)
+	}
+	var b beaconBlockV2
+	if err := json.Unmarshal(raw, &b); err != nil {
+		return nil, false, err
+	}
+	return &b, true, nil
+}
+
+// resolveBeaconBlock finds the beacon block for the execution block with hash elHash and
+// timestamp elTimestamp, and reports whether that block is canonical
+func resolveBeaconBlock(elHash string, elTimestamp uint64) (*beaconBlockMatch, error) {
+	clk, err := getChainClock()
+	if err != nil {
+		return nil, err
+	}
+	slot, ok := clk.SlotAt(elTimestamp)
+	if !ok {
+		return nil, fmt.Errorf("block timestamp %d is before beacon genesis", elTimestamp)
+	}
+	m := &beaconBlockMatch{Slot: slot, Epoch: clk.EpochOf(slot), Status: "unknown"}
+
+	blk, found, err := fetchBeaconBlock(strconv.FormatUint(slot, 10))
+	if err != nil {
+		m.Detail = "beacon block lookup failed: " + err.Error()
+		return m, nil
+	}
+	if !found {
+		m.Status = "missed"
+		m.Detail = fmt.Sprintf("slot %d has no block on the canonical beacon chain, so this execution block was orphaned (or the beacon node hasn't imported it yet)", slot)
+		return m, nil
+	}
+
+	msg := blk.Data.Message
+	m.ProposerIndex = msg.ProposerIndex
+	m.AttestationCount = len(msg.Body.Attestations)
+	m.GraffitiHex = msg.Body.Graffiti
+	m.Graffiti = decodeGraffiti(msg.Body.Graffiti)
+	m.ExecutionBlockHash = msg.Body.ExecutionPayload.BlockHash
+	m.Finalized = blk.Finalized
+
+	if raw, status, err := beaconGET(fmt.Sprintf("/eth/v1/beacon/blocks/%d/root", slot)); err == nil && status/100 == 2 {
+		var root struct {
+			Data struct {
+				Root string `json:"root"`
+			} `json:"data"`
+		}
+		if json.Unmarshal(raw, &root) == nil {
+			m.BlockRoot = root.Data.Root
+		}
+	}
+
+	// How many slots before this one were empty?
+	if msg.ParentRoot != "" {
+		if raw, status, err := beaconGET("/eth/v1/beacon/headers/" + msg.ParentRoot); err == nil && status/100 == 2 {
+			var hdr struct {
+				Data struct {
+					Header struct {
+						Message struct {
+							Slot string `json:"slot"`
+						} `json:"message"`
+					} `json:"header"`
+				} `json:"data"`
+			}
+			if json.Unmarshal(raw, &hdr) == nil {
+				if ps, err := strconv.ParseUint(hdr.Data.Header.Message.Slot, 10, 64); err == nil && ps < slot {
+					m.ParentSlot = &ps
+					m.MissedSlotsBefore = slot - ps - 1
+				}
+			}
+		}
+	}
+
+	if strings.EqualFold(m.ExecutionBlockHash, elHash) {
+		m.Status = "canonical"
+		m.Detail = fmt.Sprintf("beacon block at slot %d carries this execution payload", slot)
+		if m.MissedSlotsBefore > 0 {
+			m.Detail += fmt.Sprintf(" (%d missed slot(s) right before it)", m.MissedSlotsBefore)
+		}
+	} else {
+		m.Status = "reorged"
+		m.Detail = fmt.Sprintf("slot %d's canonical beacon block carries a different execution payload (%s); this execution block is not canonical", slot, m.ExecutionBlockHash)
+	}
+	return m, nil
+}
+
+// decodeGraffiti turns the 32-byte graffiti field into text when it is printable UTF-8
+func decodeGraffiti(h string) string {
+	b, err := hex.DecodeString(strings.TrimPrefix(h, "0x"))
+	if err != nil {
+		return ""
+	}
+	b = []byte(strings.TrimRight(string(b), "\x00"))
+	if len(b) == 0 || !utf8.Valid(b) {
+		return ""
+	}
+	for _, r := range string(b) {
+		if r < 0x20 && r != '\t' {
+			return ""
+		}
+	}
+	return string(b)
+}
+
+// handleBeaconBlockForExecution serves GET /api/beacon/execution-block/{number|hash|latest}
+// and shows which beacon block (if any) carries that execution block
+func handleBeaconBlockForExecution(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Path[len("/api/beacon/execution-block/"):]
+	if id == "" {
+		id = "latest"
+	}
+
+	method := "eth_getBlockByNumber"
+	if strings.HasPrefix(id, "0x") && len(id) == 66 {
+		method = "eth_getBlockByHash"
+	} else if n, err := strconv.ParseUint(id, 10, 64); err == nil {
+		id = fmt.Sprintf("0x%x", n)
+	}
+
+	raw, err := rpcCall(method, []any{id, false})
+	if err != nil || string(raw) == "null" {
+		writeErr(w, http.StatusNotFound, "EL_BLOCK", "Execution block not found", "Pass a block number, block hash or 'latest'")
+		return
+	}
+	var b struct {
+		Number    string `json:"number"`
+		Hash      string `json:"hash"`
+		Timestamp string `json:"timestamp"`
+	}
+	if err := json.Unmarshal(raw, &b); err != nil {
+		writeErr(w, http.StatusInternalServerError, "EL_BLOCK", "Failed to decode block", "")
+		return
+	}
+	ts, _ := parseHexUint64(b.Timestamp)
+
+	match, err := resolveBeaconBlock(b.Hash, ts)
+	if err != nil {
+		writeErr(w, http.StatusTooManyRequests, "BEACON", "Could not resolve beacon block", err.Error())
+		return
+	}
+	writeOK(w, map[string]any{
+		"execution_block": b,
+		"beacon_block":    match,
+	})
+}
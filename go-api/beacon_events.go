@@ -0,0 +1,376 @@
+// beacon_events.go
+// Subscribes to the beacon node's event stream (GET /eth/v1/events, Server-Sent Events) so we hear
+// about new heads, finality and reorgs the moment the consensus client does, instead of waiting
+// for CACHE_TTL_SECONDS to expire. What we learn is kept in beaconState, which handlers can read
+// without touching the network.
+//
+// Topics: head, block, finalized_checkpoint, chain_reorg, payload_attributes.
+// Set BEACON_EVENTS_DISABLE=1 to turn this off (some public beacon APIs don't allow streams).
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// beaconEventTopics are the standard beacon API event topics we subscribe to
+var beaconEventTopics = []string{"head", "block", "finalized_checkpoint", "chain_reorg", "payload_attributes"}
+
+// beaconHeadEvent is the payload of a "head" event
+type beaconHeadEvent struct {
+	Slot                string `json:"slot"`
+	Block               string `json:"block"`
+	State               string `json:"state"`
+	EpochTransition     bool   `json:"epoch_transition"`
+	ExecutionOptimistic bool   `json:"execution_optimistic"`
+}
+
+// beaconBlockEvent is the payload of a "block" event (a block was imported, maybe not head)
+type beaconBlockEvent struct {
+	Slot                string `json:"slot"`
+	Block               string `json:"block"`
+	ExecutionOptimistic bool   `json:"execution_optimistic"`
+}
+
+// beaconFinalizedEvent is the payload of a "finalized_checkpoint" event
+type beaconFinalizedEvent struct {
+	Block string `json:"block"`
+	State string `json:"state"`
+	Epoch string `json:"epoch"`
+}
+
+// beaconReorgEvent is the payload of a "chain_reorg" event
+type beaconReorgEvent struct {
+	Slot         string `json:"slot"`
+	Depth        string `json:"depth"`
+	OldHeadBlock string `json:"old_head_block"`
+	NewHeadBlock string `json:"new_head_block"`
+	Epoch        string `json:"epoch"`
+}
+
+// beaconPayloadAttributesEvent is the payload of a "payload_attributes" event: the node is
+// about to ask for an execution payload for the next slot
+type beaconPayloadAttributesEvent struct {
+	Version string `json:"version"`
+	Data    struct {
+		ProposerIndex     string `json:"proposer_index"`
+		ProposalSlot      string `json:"proposal_slot"`
+		ParentBlockNumber string `json:"parent_block_number"`
+		ParentBlockRoot   string `json:"parent_block_root"`
+		ParentBlockHash   string `json:"parent_block_hash"`
+		PayloadAttributes struct {
+			Timestamp             string `json:"timestamp"`
+			SuggestedFeeRecipient string `json:"suggested_fee_recipient"`
+		} `json:"payload_attributes"`
+	} `json:"data"`
+}
+
+// beaconChainState is everything the event stream has told us so far
+type beaconChainState struct {
+	mu sync.RWMutex
+
+	connected bool
+	lastEvent time.Time
+
+	Head              *beaconHeadEvent              `json:"head,omitempty"`
+	LastBlock         *beaconBlockEvent             `json:"last_block,omitempty"`
+	Finalized         *beaconFinalizedEvent         `json:"finalized,omitempty"`
+	LastReorg         *beaconReorgEvent             `json:"last_reorg,omitempty"`
+	PayloadAttributes *beaconPayloadAttributesEvent `json:"payload_attributes,omitempty"`
+
+	// checkpoints is the full finality_checkpoints response, refreshed whenever finality
+	// or the epoch changes so handleFinality can serve it straight from memory
+	checkpoints json.RawMessage
+}
+
+// beaconState is the process-wide view fed by the event stream
+var beaconState = &beaconChainState{}
+
+// beaconStateMaxAge is how long we trust the state without hearing any event (a head every 12s is normal)
+const beaconStateMaxAge = 60 * time.Second
+
+// live reports whether the stream is connected and recently active
+func (s *beaconChainState) live() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.connected && time.Since(s.lastEvent) < beaconStateMaxAge
+}
+
+// finalizedCheckpoints returns the cached finality_checkpoints body if the stream is live
+func (s *beaconChainState) finalizedCheckpoints() (json.RawMessage, bool) {
+	if !s.live() {
+		return nil, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.checkpoints, len(s.checkpoints) > 0
+}
+
+// finalizedEpoch returns the latest finalized epoch we heard about, if the stream is live
+func (s *beaconChainState) finalizedEpoch() (uint64, bool) {
+	if !s.live() {
+		return 0, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.Finalized == nil {
+		return 0, false
+	}
+	epoch, err := strconv.ParseUint(s.Finalized.Epoch, 10, 64)
+	return epoch, err == nil
+}
+
+// headSlot returns the current head slot, if the stream is live
+func (s *beaconChainState) headSlot() (uint64, bool) {
+	if !s.live() {
+		return 0, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.Head == nil {
+		return 0, false
+	}
+	slot, err := strconv.ParseUint(s.Head.Slot, 10, 64)
+	return slot, err == nil
+}
+
+// snapshot returns a copy of the state that is safe to serialize
+func (s *beaconChainState) snapshot() map[string]any {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return map[string]any{
+		"connected":          s.connected,
+		"last_event":         s.lastEvent.Unix(),
+		"head":               s.Head,
+		"last_block":         s.LastBlock,
+		"finalized":          s.Finalized,
+		"last_reorg":         s.LastReorg,
+		"payload_attributes": s.PayloadAttributes,
+	}
+}
+
+// startBeaconEvents starts the event stream consumer in the background
+func startBeaconEvents() {
+	if d := strings.ToLower(envOr("BEACON_EVENTS_DISABLE", "")); d == "1" || d == "true" || d == "yes" || d == "on" {
+		log.Println("beacon events: disabled via BEACON_EVENTS_DISABLE env")
+		return
+	}
+	go runBeaconEvents()
+}
+
+// runBeaconEvents keeps the stream connected, reconnecting with exponential backoff
+func runBeaconEvents() {
+	backoff := time.Second
+	for {
+		started := time.Now()
+		err := consumeBeaconEvents()
+
+		beaconState.mu.Lock()
+		beaconState.connected = false
+		beaconState.mu.Unlock()
+
+		log.Printf("beacon events: stream ended: %v (reconnecting in %s)\n", err, backoff)
+		if time.Since(started) > time.Minute {
+			backoff = time.Second
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > 2*time.Minute {
+			backoff = 2 * time.Minute
+		}
+	}
+}
+
+// consumeBeaconEvents runs one streaming session until it errors or goes quiet
+func consumeBeaconEvents() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	url := strings.TrimRight(beaconBase, "/") + "/eth/v1/events?topics=" + strings.Join(beaconEventTopics, ",")
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	// No client timeout - this response never ends. A watchdog cancels it if it goes quiet.
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	beaconState.mu.Lock()
+	beaconState.connected = true
+	beaconState.lastEvent = time.Now()
+	beaconState.mu.Unlock()
+	log.Println("beacon events: connected")
+
+	// Prime checkpoints so handlers can use the state right away
+	refreshBeaconCheckpoints()
+
+	activity := make(chan struct{}, 1)
+	go func() {
+		quiet := time.NewTimer(beaconStateMaxAge)
+		defer quiet.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-activity:
+				if !quiet.Stop() {
+					<-quiet.C
+				}
+				quiet.Reset(beaconStateMaxAge)
+			case <-quiet.C:
+				cancel() // no events for too long - force a reconnect
+				return
+			}
+		}
+	}()
+
+	return readSSE(resp.Body, func(event string, data []byte) {
+		select {
+		case activity <- struct{}{}:
+		default:
+		}
+		handleBeaconEvent(event, data)
+	})
+}
+
+// readSSE parses a text/event-stream body and calls fn for every complete event
+func readSSE(body io.Reader, fn func(event string, data []byte)) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+
+	var event string
+	var data []byte
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			// Blank line dispatches the event
+			if len(data) > 0 {
+				fn(event, data)
+			}
+			event, data = "", nil
+		case strings.HasPrefix(line, ":"):
+			// Comment / keep-alive
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			if len(data) > 0 {
+				data = append(data, '\n')
+			}
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " ")...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return errors.New("stream closed by server")
+}
+
+// handleBeaconEvent updates beaconState (and the live stream) from one event
+func handleBeaconEvent(event string, data []byte) {
+	beaconState.mu.Lock()
+	beaconState.lastEvent = time.Now()
+	beaconState.mu.Unlock()
+
+	switch event {
+	case "head":
+		var ev beaconHeadEvent
+		if json.Unmarshal(data, &ev) != nil {
+			return
+		}
+		beaconState.mu.Lock()
+		beaconState.Head = &ev
+		beaconState.mu.Unlock()
+		if ev.EpochTransition {
+			// Justification can move at every epoch boundary
+			go refreshBeaconCheckpoints()
+		}
+
+	case "block":
+		var ev beaconBlockEvent
+		if json.Unmarshal(data, &ev) != nil {
+			return
+		}
+		beaconState.mu.Lock()
+		beaconState.LastBlock = &ev
+		beaconState.mu.Unlock()
+
+	case "finalized_checkpoint":
+		var ev beaconFinalizedEvent
+		if json.Unmarshal(data, &ev) != nil {
+			return
+		}
+		beaconState.mu.Lock()
+		beaconState.Finalized = &ev
+		beaconState.mu.Unlock()
+		go refreshBeaconCheckpoints()
+		streamHub.publish("finalized_checkpoint", map[string]any{
+			"epoch": ev.Epoch,
+			"root":  ev.Block,
+		})
+
+	case "chain_reorg":
+		var ev beaconReorgEvent
+		if json.Unmarshal(data, &ev) != nil {
+			return
+		}
+		beaconState.mu.Lock()
+		beaconState.LastReorg = &ev
+		beaconState.mu.Unlock()
+		log.Printf("beacon events: reorg at slot %s (depth %s)\n", ev.Slot, ev.Depth)
+		streamHub.publish("chain_reorg", ev)
+
+	case "payload_attributes":
+		var ev beaconPayloadAttributesEvent
+		if json.Unmarshal(data, &ev) != nil {
+			return
+		}
+		beaconState.mu.Lock()
+		beaconState.PayloadAttributes = &ev
+		beaconState.mu.Unlock()
+	}
+}
+
+// refreshBeaconCheckpoints refetches finality checkpoints (bypassing the cache) into beaconState
+func refreshBeaconCheckpoints() {
+	raw, status, err := beaconGETFresh("/eth/v1/beacon/states/head/finality_checkpoints")
+	if err != nil || status/100 != 2 {
+		return
+	}
+	var cp struct {
+		Data struct {
+			Finalized struct {
+				Epoch string `json:"epoch"`
+				Root  string `json:"root"`
+			} `json:"finalized"`
+		} `json:"data"`
+	}
+	if json.Unmarshal(raw, &cp) != nil {
+		return
+	}
+
+	beaconState.mu.Lock()
+	beaconState.checkpoints = raw
+	if beaconState.Finalized == nil && cp.Data.Finalized.Epoch != "" {
+		beaconState.Finalized = &beaconFinalizedEvent{Epoch: cp.Data.Finalized.Epoch, Block: cp.Data.Finalized.Root}
+	}
+	beaconState.mu.Unlock()
+}
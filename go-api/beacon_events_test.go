@@ -0,0 +1,225 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestReadSSE(t *testing.T) {
+	body := strings.Join([]string{
+		": welcome",
+		"",
+		"event: head",
+		`data: {"slot":"10",`,
+		`data: "block":"0xaa"}`,
+		"id: 1",
+		"",
+		":",
+		"",
+		"event:block",
+		`data:{"slot":"11"}`,
+		"",
+		"",
+		"data: no event name",
+		"",
+		"event: finalized_checkpoint",
+		"", // an event with no data is never dispatched
+		"event: chain_reorg",
+		`data: {"slot":"12"}`, // cut off before the blank line - never dispatched
+	}, "\n")
+
+	type got struct{ event, data string }
+	var events []got
+	err := readSSE(strings.NewReader(body), func(event string, data []byte) {
+		events = append(events, got{event, string(data)})
+	})
+	if err == nil || err.Error() != "stream closed by server" {
+		t.Errorf("err = %v", err)
+	}
+
+	want := []got{
+		{"head", "{\"slot\":\"10\",\n\"block\":\"0xaa\"}"},
+		{"block", `{"slot":"11"}`},
+		{"", "no event name"},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events: %+v", len(events), events)
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Errorf("event %d = %+v, want %+v", i, events[i], want[i])
+		}
+	}
+}
+
+// beaconStub stands in for the beacon node. beaconBase is only ever pointed at it once, since
+// checkpoint refreshes started by one test may still be reading it while the next one runs.
+var beaconStub struct {
+	once sync.Once
+	mu   sync.Mutex
+
+	streams []string // body for each successive /eth/v1/events connection; "" answers 503
+	conns   int
+	accept  string
+	topics  string
+}
+
+const stubCheckpoints = `{"data":{"previous_justified":{"epoch":"100","root":"0xpj"},"current_justified":{"epoch":"101","root":"0xcj"},"finalized":{"epoch":"100","root":"0xfin"}}}`
+
+// useBeaconStub points beaconBase at the stub, queues the event streams it will serve and clears
+// whatever the event stream told beaconState before
+func useBeaconStub(t *testing.T, streams ...string) {
+	t.Helper()
+	beaconStub.once.Do(func() {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/eth/v1/beacon/states/head/finality_checkpoints":
+				w.Write([]byte(stubCheckpoints))
+			case "/eth/v1/events":
+				beaconStub.mu.Lock()
+				beaconStub.accept = r.Header.Get("Accept")
+				beaconStub.topics = r.URL.Query().Get("topics")
+				body := ""
+				if beaconStub.conns < len(beaconStub.streams) {
+					body = beaconStub.streams[beaconStub.conns]
+				}
+				beaconStub.conns++
+				beaconStub.mu.Unlock()
+				if body == "" {
+					http.Error(w, "unavailable", http.StatusServiceUnavailable)
+					return
+				}
+				w.Header().Set("Content-Type", "text/event-stream")
+				w.Write([]byte(body))
+			default:
+				http.NotFound(w, r)
+			}
+		}))
+		beaconBase = srv.URL // left running for the rest of the test binary
+	})
+
+	beaconStub.mu.Lock()
+	beaconStub.streams, beaconStub.conns = streams, 0
+	beaconStub.mu.Unlock()
+
+	beaconState.mu.Lock()
+	beaconState.connected = false
+	beaconState.Head, beaconState.LastBlock, beaconState.Finalized, beaconState.LastReorg = nil, nil, nil, nil
+	beaconState.PayloadAttributes, beaconState.checkpoints = nil, nil
+	beaconState.mu.Unlock()
+}
+
+// sse builds one event in wire format
+func sse(event, data string) string {
+	return "event: " + event + "\ndata: " + data + "\n\n"
+}
+
+func TestBeaconEventsUpdateState(t *testing.T) {
+	useBeaconStub(t, ": keepalive\n\n"+
+		sse("head", `{"slot":"3232","block":"0xhead","state":"0xstate","epoch_transition":true}`)+
+		sse("block", `{"slot":"3232","block":"0xhead"}`)+
+		sse("finalized_checkpoint", `{"block":"0xfin2","state":"0xs","epoch":"101"}`)+
+		": keepalive\n\n"+
+		sse("chain_reorg", `{"slot":"3233","depth":"2","old_head_block":"0xold","new_head_block":"0xnew","epoch":"101"}`)+
+		"event: payload_attributes\n"+
+		`data: {"version":"deneb","data":{"proposer_index":"7","proposal_slot":"3234",`+"\n"+
+		`data: "payload_attributes":{"suggested_fee_recipient":"0xfee"}}}`+"\n\n")
+
+	sub, _, _ := streamHub.subscribe(map[string]bool{"finalized_checkpoint": true, "chain_reorg": true}, 0)
+	defer streamHub.unsubscribe(sub)
+
+	if err := consumeBeaconEvents(); err == nil || err.Error() != "stream closed by server" {
+		t.Fatalf("err = %v", err)
+	}
+
+	beaconStub.mu.Lock()
+	accept, topics := beaconStub.accept, beaconStub.topics
+	beaconStub.mu.Unlock()
+	if accept != "text/event-stream" || topics != strings.Join(beaconEventTopics, ",") {
+		t.Errorf("requested Accept %q, topics %q", accept, topics)
+	}
+
+	if !beaconState.live() {
+		t.Fatal("state not live after a session with events")
+	}
+	if slot, ok := beaconState.headSlot(); !ok || slot != 3232 {
+		t.Errorf("head slot = %d, %v", slot, ok)
+	}
+	if epoch, ok := beaconState.finalizedEpoch(); !ok || epoch != 101 {
+		t.Errorf("finalized epoch = %d, %v (the event should win over the primed checkpoints)", epoch, ok)
+	}
+	if cp, ok := beaconState.finalizedCheckpoints(); !ok || string(cp) != stubCheckpoints {
+		t.Errorf("checkpoints = %s, %v", cp, ok)
+	}
+
+	beaconState.mu.RLock()
+	block, reorg, attrs := beaconState.LastBlock, beaconState.LastReorg, beaconState.PayloadAttributes
+	beaconState.mu.RUnlock()
+	if block == nil || block.Block != "0xhead" {
+		t.Errorf("last block = %+v", block)
+	}
+	if reorg == nil || reorg.Depth != "2" || reorg.NewHeadBlock != "0xnew" {
+		t.Errorf("last reorg = %+v", reorg)
+	}
+	if attrs == nil || attrs.Data.ProposalSlot != "3234" || attrs.Data.PayloadAttributes.SuggestedFeeRecipient != "0xfee" {
+		t.Errorf("payload attributes = %+v", attrs)
+	}
+
+	// Finality and reorgs go out on the live stream
+	var types []string
+	for len(sub.ch) > 0 {
+		types = append(types, (<-sub.ch).Type)
+	}
+	if strings.Join(types, ",") != "finalized_checkpoint,chain_reorg" {
+		t.Errorf("published %v", types)
+	}
+}
+
+func TestBeaconEventsReconnect(t *testing.T) {
+	useBeaconStub(t, "", sse("head", `{"slot":"500","block":"0xb"}`))
+
+	// The first attempt is refused and leaves the state offline
+	if err := consumeBeaconEvents(); err == nil || err.Error() != "HTTP 503" {
+		t.Fatalf("first attempt err = %v", err)
+	}
+	if beaconState.live() {
+		t.Fatal("state live after a refused connection")
+	}
+	if _, ok := beaconState.headSlot(); ok {
+		t.Error("head slot known without any events")
+	}
+
+	// The retry connects and picks the stream back up
+	if err := consumeBeaconEvents(); err == nil || err.Error() != "stream closed by server" {
+		t.Fatalf("second attempt err = %v", err)
+	}
+	if slot, ok := beaconState.headSlot(); !ok || slot != 500 {
+		t.Errorf("head slot after reconnect = %d, %v", slot, ok)
+	}
+	beaconStub.mu.Lock()
+	conns := beaconStub.conns
+	beaconStub.mu.Unlock()
+	if conns != 2 {
+		t.Errorf("stub saw %d connections, want 2", conns)
+	}
+}
+
+func TestBeaconEventsIgnoresMalformedPayloads(t *testing.T) {
+	useBeaconStub(t)
+	handleBeaconEvent("head", []byte(`{"slot":"77"}`))
+	handleBeaconEvent("head", []byte(`not json`))
+	handleBeaconEvent("chain_reorg", []byte(`{"slot":`))
+	handleBeaconEvent("some_future_topic", []byte(`{}`))
+
+	beaconState.mu.RLock()
+	defer beaconState.mu.RUnlock()
+	if beaconState.Head == nil || beaconState.Head.Slot != "77" {
+		t.Errorf("head = %+v, a bad payload must not clobber the last good one", beaconState.Head)
+	}
+	if beaconState.LastReorg != nil {
+		t.Errorf("reorg = %+v", beaconState.LastReorg)
+	}
+}
@@ -0,0 +1,331 @@
+// block_source.go
+// Was a block built by an MEV-Boost builder or by the proposer's own node?
+// The reliable signal is a relay saying it delivered the payload, so we ask every relay. But "no
+// relay reported it" only means "local" if every relay actually answered and its answer reaches
+// back far enough to include that slot - otherwise the honest answer is "we don't know". On top
+// of that we look at the execution block itself: builders usually put their name in extra_data
+// and receive the fees at a well-known coinbase, while execution clients building locally write
+// their own client name (geth, nethermind, ...).
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// knownBuilderCoinbases are fee recipients of the biggest builders (they pay proposers from these)
+var knownBuilderCoinbases = map[string]string{
+	"0x95222290dd7278aa3ddd389cc1e1d165cc4bafe5": "beaverbuild",
+	"0x4838b106fce9647bdf1e7877bf73ce8b0bad5f97": "titan",
+	"0x1f9090aae28b8a3dceadf281b0f12828e676c326": "rsync",
+	"0xdafea492d9c6733ae3d56b7ed1adb60692c98bc5": "flashbots",
+}
+
+// builderExtraDataHints are substrings builders put in extra_data (matched lowercase)
+var builderExtraDataHints = []string{
+	"beaverbuild", "titan", "rsync", "flashbots", "illuminate dmocratize", "bloxroute",
+	"buildernet", "quasar", "jetbldr", "penguinbuild", "builder0x69", "btcs", "eden",
+	"manifold", "loki", "gambit", "bob the builder", "smithbot", "boba",
+}
+
+// localClientExtraData are what execution clients write into extra_data when building locally
+var localClientExtraData = []string{"geth", "nethermind", "besu", "erigon", "reth"}
+
+// blockSource is the classification of one proposed block
+type blockSource struct {
+	Slot         uint64   `json:"slot"`
+	BlockNumber  *uint64  `json:"block_number,omitempty"`
+	Class        string   `json:"class"`      // mev_boost, local or unknown_relay_unavailable
+	Confidence   string   `json:"confidence"` // relay (a relay answered), heuristic or none
+	Relays       []string `json:"relays,omitempty"`
+	Builder      string   `json:"builder,omitempty"` // name, when we recognize it
+	FeeRecipient string   `json:"fee_recipient,omitempty"`
+	ExtraData    string   `json:"extra_data,omitempty"` // printable part
+	Evidence     []string `json:"evidence"`
+}
+
+// blockSourceSummary counts classes over a set of slots
+type blockSourceSummary struct {
+	Slots         int     `json:"slots"`
+	Blocks        int     `json:"blocks"`
+	Missed        int     `json:"missed"`
+	MEVBoost      int     `json:"mev_boost"`
+	Local         int     `json:"local"`
+	Unknown       int     `json:"unknown_relay_unavailable"`
+	MEVBoostRatio float64 `json:"mev_boost_ratio"` // % of classified blocks (unknowns excluded)
+}
+
+// sourceBlock is the part of an execution block the heuristics look at
+type sourceBlock struct {
+	Number    string `json:"number"`
+	Hash      string `json:"hash"`
+	Miner     string `json:"miner"`
+	ExtraData string `json:"extraData"`
+	Timestamp string `json:"timestamp"`
+}
+
+// fetchRecentELBlocksBySlot batch-fetches the execution blocks from fromSlot up to the head,
+// keyed by slot. Capped at 256 blocks.
+func fetchRecentELBlocksBySlot(clk *chainClock, fromSlot uint64) (map[uint64]*sourceBlock, error) {
+	raw, err := rpcCall("eth_getBlockByNumber", []any{"latest", false})
+	if err != nil {
+		return nil, err
+	}
+	var latest sourceBlock
+	if err := json.Unmarshal(raw, &latest); err != nil {
+		return nil, err
+	}
+	latestNum, err := parseHexUint64(latest.Number)
+	if err != nil {
+		return nil, err
+	}
+	ts, _ := parseHexUint64(latest.Timestamp)
+	latestSlot, _ := clk.SlotAt(ts)
+
+	out := map[uint64]*sourceBlock{latestSlot: &latest}
+	if fromSlot >= latestSlot {
+		return out, nil
+	}
+	// Never more blocks than slots in between (missed slots only make it fewer)
+	n := latestSlot - fromSlot
+	if n > 255 {
+		n = 255
+	}
+	if n > latestNum {
+		n = latestNum
+	}
+	calls := make([]rpcBatchRequest, 0, n)
+	for i := uint64(1); i <= n; i++ {
+		calls = append(calls, rpcBatchRequest{Method: "eth_getBlockByNumber", Params: []any{fmt.Sprintf("0x%x", latestNum-i), false}})
+	}
+	results, err := rpcBatchCall(calls)
+	if err != nil {
+		return out, err
+	}
+	for _, res := range results {
+		if res.Err != nil {
+			continue
+		}
+		var b sourceBlock
+		if err := json.Unmarshal(res.Result, &b); err != nil || b.Hash == "" {
+			continue
+		}
+		ts, _ := parseHexUint64(b.Timestamp)
+		if slot, ok := clk.SlotAt(ts); ok && slot >= fromSlot {
+			out[slot] = &b
+		}
+	}
+	return out, nil
+}
+
+// printableExtraData keeps the readable ASCII in a hex extra_data field
+func printableExtraData(h string) string {
+	raw, err := hex.DecodeString(strings.TrimPrefix(h, "0x"))
+	if err != nil {
+		return ""
+	}
+	var sb strings.Builder
+	for _, c := range raw {
+		if c >= 0x20 && c < 0x7f {
+			sb.WriteByte(c)
+		} else if sb.Len() > 0 && !strings.HasSuffix(sb.String(), " ") {
+			sb.WriteByte(' ')
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// relaysCoverSlot reports whether every relay answered and its answer reaches back to slot,
+// so "not in any response" really means "no relay delivered it"
+func relaysCoverSlot(merged *relayMerged, slot uint64, limit int) bool {
+	if merged == nil || len(merged.Relays) == 0 {
+		return false
+	}
+	for _, res := range merged.Relays {
+		if !res.OK {
+			return false
+		}
+		if res.Count >= limit && res.oldest > slot {
+			return false // a full page that stops short of this slot
+		}
+	}
+	return true
+}
+
+// classifyBlockSource combines the relay answer with the execution block heuristics
+func classifyBlockSource(slot uint64, bid *BidTrace, covered bool, el *sourceBlock) *blockSource {
+	src := &blockSource{Slot: slot, Evidence: []string{}}
+
+	builderHint, localHint := "", ""
+	if el != nil {
+		if n, err := parseHexUint64(el.Number); err == nil {
+			src.BlockNumber = &n
+		}
+		src.FeeRecipient = strings.ToLower(el.Miner)
+		src.ExtraData = printableExtraData(el.ExtraData)
+		lower := strings.ToLower(src.ExtraData)
+		if name, ok := knownBuilderCoinbases[src.FeeRecipient]; ok {
+			builderHint = name
+			src.Evidence = append(src.Evidence, "fee recipient is "+name+"'s builder coinbase")
+		}
+		for _, hint := range builderExtraDataHints {
+			if strings.Contains(lower, hint) {
+				if builderHint == "" {
+					builderHint = hint
+				}
+				src.Evidence = append(src.Evidence, "extra_data names a builder ("+hint+")")
+				break
+			}
+		}
+		for _, hint := range localClientExtraData {
+			if strings.Contains(lower, hint) {
+				localHint = hint
+				src.Evidence = append(src.Evidence, "extra_data is an execution client default ("+hint+")")
+				break
+			}
+		}
+	}
+
+	switch {
+	case bid != nil:
+		src.Class, src.Confidence, src.Relays = "mev_boost", "relay", bid.Relays
+		src.Evidence = append([]string{"delivered by " + strings.Join(bid.Relays, ", ")}, src.Evidence...)
+		if src.Builder = builderName(bid.BuilderPubkey); src.Builder == "" {
+			src.Builder = builderHint
+		}
+	case builderHint != "":
+		// Built by a builder, but through a relay we don't query (or one that didn't answer)
+		src.Class, src.Confidence, src.Builder = "mev_boost", "heuristic", builderHint
+	case covered:
+		src.Class, src.Confidence = "local", "relay"
+		src.Evidence = append([]string{"every relay answered and none delivered this slot"}, src.Evidence...)
+	case localHint != "":
+		src.Class, src.Confidence = "local", "heuristic"
+	default:
+		src.Class, src.Confidence = "unknown_relay_unavailable", "none"
+		src.Evidence = append(src.Evidence, "not every relay answered, and the block has no telltale extra_data")
+	}
+	return src
+}
+
+// classifySlots classifies each slot that has a block. Slots without an execution block (missed,
+// or older than we looked) are left out. Relay and EL failures degrade to heuristics/unknown.
+func classifySlots(slots []uint64) (map[uint64]*blockSource, *relayMerged) {
+	if len(slots) == 0 {
+		return map[uint64]*blockSource{}, nil
+	}
+	minSlot := slots[0]
+	for _, s := range slots {
+		if s < minSlot {
+			minSlot = s
+		}
+	}
+
+	// One fan-out, deep enough to cover the range on every relay
+	limit := 50
+	if clk, err := getChainClock(); err == nil {
+		if span := int(clk.CurrentSlot(time.Now())-minSlot) + 1; span > limit {
+			limit = span
+		}
+	}
+	if limit > 200 {
+		limit = 200
+	}
+	merged, _ := relayFanoutRecords(relayQuery{Limit: limit}.path(relayDeliveredEndpoint))
+	delivered := map[uint64]*BidTrace{}
+	if merged != nil {
+		for _, t := range merged.traces() {
+			if _, ok := delivered[t.Slot]; !ok {
+				delivered[t.Slot] = t
+			}
+		}
+	}
+
+	var blocks map[uint64]*sourceBlock
+	if clk, err := getChainClock(); err == nil {
+		blocks, _ = fetchRecentELBlocksBySlot(clk, minSlot)
+	}
+
+	out := make(map[uint64]*blockSource, len(slots))
+	for _, s := range slots {
+		bid := delivered[s]
+		el := blocks[s]
+		if bid == nil && el == nil && blocks != nil {
+			continue // no block in this slot
+		}
+		out[s] = classifyBlockSource(s, bid, relaysCoverSlot(merged, s, limit), el)
+	}
+	return out, merged
+}
+
+// summarizeBlockSources counts classes over slots (missing entries count as missed)
+func summarizeBlockSources(slots []uint64, sources map[uint64]*blockSource) blockSourceSummary {
+	sum := blockSourceSummary{Slots: len(slots)}
+	for _, s := range slots {
+		src, ok := sources[s]
+		if !ok {
+			sum.Missed++
+			continue
+		}
+		sum.Blocks++
+		switch src.Class {
+		case "mev_boost":
+			sum.MEVBoost++
+		case "local":
+			sum.Local++
+		default:
+			sum.Unknown++
+		}
+	}
+	if known := sum.MEVBoost + sum.Local; known > 0 {
+		sum.MEVBoostRatio = roundTo(float64(sum.MEVBoost)*100/float64(known), 2)
+	}
+	return sum
+}
+
+// handleBlockSources serves GET /api/blocks/sources?slots=32 - the last N slots, classified
+func handleBlockSources(w http.ResponseWriter, r *http.Request) {
+	clk, err := getChainClock()
+	if err != nil {
+		writeErr(w, http.StatusTooManyRequests, "BEACON", "Could not load chain genesis/spec", "The slot clock comes from /eth/v1/beacon/genesis and /eth/v1/config/spec. Check BEACON_API_URL.")
+		return
+	}
+	n := uint64(32)
+	if s := r.URL.Query().Get("slots"); s != "" {
+		if v, err := strconv.ParseUint(s, 10, 64); err == nil && v > 0 && v <= 200 {
+			n = v
+		}
+	}
+
+	// The current slot may not have a block yet, so end at the previous one
+	head := clk.CurrentSlot(time.Now())
+	if head > 0 {
+		head--
+	}
+	slots := make([]uint64, 0, n)
+	for i := uint64(0); i < n && i <= head; i++ {
+		slots = append(slots, head-i)
+	}
+
+	sources, merged := classifySlots(slots)
+	list := make([]*blockSource, 0, len(sources))
+	for _, src := range sources {
+		list = append(list, src)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Slot > list[j].Slot })
+
+	resp := map[string]any{
+		"blocks":  list,
+		"summary": summarizeBlockSources(slots, sources),
+	}
+	if merged != nil {
+		resp["relays"], resp["timed_out"] = merged.Relays, merged.TimedOut
+	}
+	writeOK(w, resp)
+}
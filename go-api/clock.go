@@ -0,0 +1,225 @@
+// clock.go
+// The beacon chain keeps time in slots and epochs. On mainnet a slot is 12 seconds and an epoch
+// is 32 slots, but testnets and devnets can use different values, so instead of hard-coding them
+// we load the chain's own genesis time and spec config once (from /eth/v1/beacon/genesis and
+// /eth/v1/config/spec) and do every slot <-> timestamp <-> epoch conversion through chainClock.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// farFutureEpoch is what the spec uses for forks that aren't scheduled yet
+const farFutureEpoch = ^uint64(0)
+
+// forkEpoch is one named hard fork and the epoch it activates at
+type forkEpoch struct {
+	Name  string `json:"name"`
+	Epoch uint64 `json:"epoch"`
+}
+
+// chainClock converts between wall-clock time, slots and epochs for one chain
+type chainClock struct {
+	GenesisTime           uint64      `json:"genesis_time"`
+	GenesisValidatorsRoot string      `json:"genesis_validators_root"`
+	GenesisForkVersion    string      `json:"genesis_fork_version"`
+	SecondsPerSlot        uint64      `json:"seconds_per_slot"`
+	SlotsPerEpoch         uint64      `json:"slots_per_epoch"`
+	SyncCommitteePeriod   uint64      `json:"epochs_per_sync_committee_period,omitempty"` // 0 before Altair
+	ConfigName            string      `json:"config_name,omitempty"`
+	Forks                 []forkEpoch `json:"forks"` // scheduled forks, oldest first
+}
+
+var (
+	clockMu sync.Mutex
+	clock   *chainClock
+)
+
+// getChainClock returns the chain clock, loading it from the beacon API on first use.
+// A failed load isn't remembered, so the next call simply tries again.
+func getChainClock() (*chainClock, error) {
+	clockMu.Lock()
+	defer clockMu.Unlock()
+	if clock != nil {
+		return clock, nil
+	}
+
+	c, err := loadChainClock()
+	if err != nil {
+		return nil, err
+	}
+	clock = c
+	return clock, nil
+}
+
+// loadChainClock fetches genesis and spec config and builds a chainClock
+func loadChainClock() (*chainClock, error) {
+	rawGenesis, status, err := beaconGET("/eth/v1/beacon/genesis")
+	if err != nil {
+		return nil, err
+	}
+	if status/100 != 2 {
+		return nil, fmt.Errorf("genesis: HTTP %d", status)
+	}
+	var genesis struct {
+		Data struct {
+			GenesisTime           string `json:"genesis_time"`
+			GenesisValidatorsRoot string `json:"genesis_validators_root"`
+			GenesisForkVersion    string `json:"genesis_fork_version"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rawGenesis, &genesis); err != nil {
+		return nil, err
+	}
+	genesisTime, err := strconv.ParseUint(genesis.Data.GenesisTime, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("genesis: bad genesis_time %q", genesis.Data.GenesisTime)
+	}
+
+	rawSpec, status, err := beaconGET("/eth/v1/config/spec")
+	if err != nil {
+		return nil, err
+	}
+	if status/100 != 2 {
+		return nil, fmt.Errorf("spec: HTTP %d", status)
+	}
+	// Most spec values are strings, but newer clients include a few arrays/objects - keep them raw
+	var spec struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(rawSpec, &spec); err != nil {
+		return nil, err
+	}
+	specString := func(key string) string {
+		var v string
+		_ = json.Unmarshal(spec.Data[key], &v)
+		return v
+	}
+	specUint := func(key string) (uint64, bool) {
+		n, err := strconv.ParseUint(specString(key), 10, 64)
+		return n, err == nil
+	}
+
+	c := &chainClock{
+		GenesisTime:           genesisTime,
+		GenesisValidatorsRoot: genesis.Data.GenesisValidatorsRoot,
+		GenesisForkVersion:    genesis.Data.GenesisForkVersion,
+		ConfigName:            specString("CONFIG_NAME"),
+	}
+	var ok bool
+	if c.SecondsPerSlot, ok = specUint("SECONDS_PER_SLOT"); !ok || c.SecondsPerSlot == 0 {
+		return nil, errors.New("spec: missing SECONDS_PER_SLOT")
+	}
+	if c.SlotsPerEpoch, ok = specUint("SLOTS_PER_EPOCH"); !ok || c.SlotsPerEpoch == 0 {
+		return nil, errors.New("spec: missing SLOTS_PER_EPOCH")
+	}
+
+	c.SyncCommitteePeriod, _ = specUint("EPOCHS_PER_SYNC_COMMITTEE_PERIOD")
+
+	// Every fork has a <NAME>_FORK_EPOCH key; unscheduled forks use the far-future epoch
+	c.Forks = []forkEpoch{{Name: "phase0", Epoch: 0}}
+	for key := range spec.Data {
+		if !strings.HasSuffix(key, "_FORK_EPOCH") {
+			continue
+		}
+		epoch, ok := specUint(key)
+		if !ok || epoch == farFutureEpoch {
+			continue
+		}
+		c.Forks = append(c.Forks, forkEpoch{Name: strings.ToLower(strings.TrimSuffix(key, "_FORK_EPOCH")), Epoch: epoch})
+	}
+	sort.Slice(c.Forks, func(i, j int) bool {
+		if c.Forks[i].Epoch == c.Forks[j].Epoch {
+			return c.Forks[i].Name < c.Forks[j].Name
+		}
+		return c.Forks[i].Epoch < c.Forks[j].Epoch
+	})
+
+	return c, nil
+}
+
+// SlotAt returns the slot containing unix timestamp ts (false if ts is before genesis)
+func (c *chainClock) SlotAt(ts uint64) (uint64, bool) {
+	if ts < c.GenesisTime {
+		return 0, false
+	}
+	return (ts - c.GenesisTime) / c.SecondsPerSlot, true
+}
+
+// SlotStart returns the unix timestamp at which slot begins
+func (c *chainClock) SlotStart(slot uint64) uint64 {
+	return c.GenesisTime + slot*c.SecondsPerSlot
+}
+
+// EpochOf returns the epoch a slot belongs to
+func (c *chainClock) EpochOf(slot uint64) uint64 {
+	return slot / c.SlotsPerEpoch
+}
+
+// EpochStartSlot returns the first slot of an epoch
+func (c *chainClock) EpochStartSlot(epoch uint64) uint64 {
+	return epoch * c.SlotsPerEpoch
+}
+
+// CurrentSlot returns the slot at wall-clock time now (0 before genesis)
+func (c *chainClock) CurrentSlot(now time.Time) uint64 {
+	slot, _ := c.SlotAt(uint64(now.Unix()))
+	return slot
+}
+
+// TimeIntoSlot returns how far into the current slot now is
+func (c *chainClock) TimeIntoSlot(now time.Time) time.Duration {
+	start := time.Unix(int64(c.SlotStart(c.CurrentSlot(now))), 0)
+	if now.Before(start) {
+		return 0
+	}
+	return now.Sub(start)
+}
+
+// ForkAt returns the name of the fork active at an epoch
+func (c *chainClock) ForkAt(epoch uint64) string {
+	name := "phase0"
+	for _, f := range c.Forks {
+		if f.Epoch <= epoch {
+			name = f.Name
+		}
+	}
+	return name
+}
+
+// handleClock serves GET /api/clock: the chain's timing config plus "where are we right now"
+func handleClock(w http.ResponseWriter, r *http.Request) {
+	c, err := getChainClock()
+	if err != nil {
+		writeErr(w, http.StatusTooManyRequests, "BEACON", "Could not load chain genesis/spec", "The beacon API must serve /eth/v1/beacon/genesis and /eth/v1/config/spec. Check BEACON_API_URL.")
+		return
+	}
+
+	now := time.Now()
+	slot := c.CurrentSlot(now)
+	epoch := c.EpochOf(slot)
+	into := c.TimeIntoSlot(now)
+	slotDur := time.Duration(c.SecondsPerSlot) * time.Second
+
+	writeOK(w, map[string]any{
+		"config":              c,
+		"now":                 now.Unix(),
+		"current_slot":        slot,
+		"current_epoch":       epoch,
+		"slot_in_epoch":       slot - c.EpochStartSlot(epoch),
+		"slot_start":          c.SlotStart(slot),
+		"ms_into_slot":        into.Milliseconds(),
+		"ms_until_next_slot":  (slotDur - into).Milliseconds(),
+		"epoch_start_slot":    c.EpochStartSlot(epoch),
+		"next_epoch_start_ts": c.SlotStart(c.EpochStartSlot(epoch + 1)),
+		"active_fork":         c.ForkAt(epoch),
+	})
+}
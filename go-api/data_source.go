@@ -3,6 +3,7 @@
 package main
 
 import (
+	"sync"
 	"time"
 )
 
@@ -27,9 +28,11 @@ type DataSource interface {
 	GetTTL() time.Duration
 }
 
-// BaseDataSource provides common functionality for data sources
+// BaseDataSource provides common functionality for data sources.
+// Fetches run concurrently (receipt workers, fan-outs), so the health fields are guarded by mu.
 type BaseDataSource struct {
 	name        string
+	mu          sync.RWMutex
 	lastError   error
 	lastSuccess time.Time
 	cacheKey    string
@@ -50,10 +53,14 @@ func (b *BaseDataSource) GetName() string {
 }
 
 func (b *BaseDataSource) GetLastError() error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
 	return b.lastError
 }
 
 func (b *BaseDataSource) GetLastSuccess() time.Time {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
 	return b.lastSuccess
 }
 
@@ -67,18 +74,24 @@ func (b *BaseDataSource) GetTTL() time.Duration {
 
 // SetError updates the last error and clears success timestamp
 func (b *BaseDataSource) SetError(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
 	b.lastError = err
 	b.lastSuccess = time.Time{} // Clear success timestamp on error
 }
 
 // SetSuccess updates the last success timestamp and clears error
 func (b *BaseDataSource) SetSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
 	b.lastSuccess = time.Now()
 	b.lastError = nil
 }
 
 // IsHealthy checks if the data source is healthy based on recent success
 func (b *BaseDataSource) IsHealthy() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
 	// Consider healthy if we've had success in the last 5 minutes
 	// or if we've never had an error
 	if b.lastSuccess.IsZero() && b.lastError == nil {
@@ -85,13 +85,85 @@ type rpcRequest struct {
 	Params  any    `json:"params"`
 }
 
+// rpcError is the error object a node puts inside a JSON-RPC response.
+// We keep the code around so callers can tell "method not found" apart from real failures.
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+// isExecutionRevert reports whether an eth_call failed because the contract reverted, which is an
+// answer about the contract (it will revert again), unlike transport errors or rate limiting
+func isExecutionRevert(err error) bool {
+	var re *rpcError
+	if !errors.As(err, &re) {
+		return false
+	}
+	return re.Code == 3 || strings.Contains(strings.ToLower(re.Message), "revert")
+}
+
 // rpcResponse is what comes back from the RPC endpoint
 type rpcResponse struct {
+	ID     int             `json:"id"`
 	Result json.RawMessage `json:"result"`
-	Error  *struct {
-		Code    int    `json:"code"`
-		Message string `json:"message"`
-	} `json:"error,omitempty"`
+	Error  *rpcError       `json:"error,omitempty"`
+}
+
+// rpcBatchMax caps how many calls we pack into one batch request.
+// Hosted providers reject oversized batches (Alchemy allows 1000, Infura ~100), so default small.
+var rpcBatchMax = func() int {
+	if s := os.Getenv("RPC_BATCH_MAX"); s != "" {
+		if n, err := strconv.Atoi(s); err == nil && n > 0 && n <= 1000 {
+			return n
+		}
+	}
+	return 50
+}()
+
+// rpcBatchRequest is one call inside a batch (the ID is assigned for us)
+type rpcBatchRequest struct {
+	Method string
+	Params any
+}
+
+// rpcBatchResult is the outcome of one call inside a batch.
+// Each item succeeds or fails on its own - one bad receipt shouldn't sink the others.
+type rpcBatchResult struct {
+	Result json.RawMessage
+	Err    error
+}
+
+// definitive reports whether an eth_call's answer is worth caching: a result, or a revert (it will
+// revert again). Transport failures and rate limits aren't - the next request asks again.
+func (r rpcBatchResult) definitive() bool {
+	return r.Err == nil || isExecutionRevert(r.Err)
+}
+
+// rpcPost sends a raw JSON-RPC payload (single or batch) and returns the body.
+// Transport failures are reported to the health monitor here so both call styles share it.
+func rpcPost(payload []byte) ([]byte, error) {
+	res, err := rpcHTTPClient.Post(rpcHTTP, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		// Let the health monitor know this failed
+		if rpcHealth != nil {
+			rpcHealth.SetError(err)
+		}
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		if rpcHealth != nil {
+			rpcHealth.SetError(err)
+		}
+		return nil, err
+	}
+	return body, nil
 }
 
 // rpcCall does the actual work of calling the Ethereum JSON-RPC endpoint.
@@ -104,17 +176,11 @@ func rpcCall(method string, params any) (json.RawMessage, error) {
 		Params:  params,
 	})
 
-	res, err := rpcHTTPClient.Post(rpcHTTP, "application/json", bytes.NewReader(payload))
+	body, err := rpcPost(payload)
 	if err != nil {
-		// Let the health monitor know this failed
-		if rpcHealth != nil {
-			rpcHealth.SetError(err)
-		}
 		return nil, err
 	}
-	defer res.Body.Close()
 
-	body, _ := io.ReadAll(res.Body)
 	var parsed rpcResponse
 	if err := json.Unmarshal(body, &parsed); err != nil {
 		if rpcHealth != nil {
@@ -125,11 +191,10 @@ func rpcCall(method string, params any) (json.RawMessage, error) {
 
 	// RPC can return errors inside a 200 OK response, so check for those
 	if parsed.Error != nil {
-		err := errors.New(parsed.Error.Message)
 		if rpcHealth != nil {
-			rpcHealth.SetError(err)
+			rpcHealth.SetError(parsed.Error)
 		}
-		return nil, err
+		return nil, parsed.Error
 	}
 
 	// Success! Update health check
@@ -139,3 +204,94 @@ func rpcCall(method string, params any) (json.RawMessage, error) {
 
 	return parsed.Result, nil
 }
+
+// rpcBatchCall sends many calls as JSON-RPC batch arrays (chunked by rpcBatchMax) and
+// returns one result per call, in the same order as calls. Responses are matched by ID
+// because nodes are allowed to answer a batch in any order.
+// The returned error is only set when a whole chunk failed (network error, garbage body);
+// per-call failures live in each rpcBatchResult.Err.
+func rpcBatchCall(calls []rpcBatchRequest) ([]rpcBatchResult, error) {
+	results := make([]rpcBatchResult, len(calls))
+	var firstErr error
+
+	for start := 0; start < len(calls); start += rpcBatchMax {
+		end := start + rpcBatchMax
+		if end > len(calls) {
+			end = len(calls)
+		}
+		if err := rpcBatchChunk(calls[start:end], results[start:end]); err != nil {
+			for i := start; i < end; i++ {
+				results[i].Err = err
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	return results, firstErr
+}
+
+// rpcBatchChunk sends a single batch array and fills in results for that slice
+func rpcBatchChunk(calls []rpcBatchRequest, results []rpcBatchResult) error {
+	reqs := make([]rpcRequest, len(calls))
+	for i, c := range calls {
+		// IDs are 1-based positions within this chunk so we can map responses back
+		reqs[i] = rpcRequest{JSONRPC: "2.0", ID: i + 1, Method: c.Method, Params: c.Params}
+	}
+	payload, _ := json.Marshal(reqs)
+
+	body, err := rpcPost(payload)
+	if err != nil {
+		return err
+	}
+
+	var parsed []rpcResponse
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		// Providers without batch support answer with a single error object instead of an array
+		var single rpcResponse
+		if json.Unmarshal(body, &single) == nil && single.Error != nil {
+			err = single.Error
+		}
+		if rpcHealth != nil {
+			rpcHealth.SetError(err)
+		}
+		return err
+	}
+
+	answered := make([]bool, len(calls))
+	for _, p := range parsed {
+		idx := p.ID - 1
+		if idx < 0 || idx >= len(calls) {
+			continue // Unknown ID - ignore rather than guess
+		}
+		answered[idx] = true
+		if p.Error != nil {
+			results[idx].Err = p.Error
+			continue
+		}
+		results[idx].Result = p.Result
+	}
+	for i, ok := range answered {
+		if !ok {
+			results[i].Err = fmt.Errorf("no response for %s in batch", calls[i].Method)
+		}
+	}
+
+	// A batch where every item failed (e.g. per-item rate limiting) isn't a working node
+	if rpcHealth != nil {
+		answeredOK := len(results) == 0
+		for _, res := range results {
+			if res.Err == nil {
+				answeredOK = true
+				break
+			}
+		}
+		if answeredOK {
+			rpcHealth.SetSuccess()
+		} else {
+			rpcHealth.SetError(results[0].Err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+// finality.go
+// A typed view of finality from both layers:
+//   - consensus: the current justified, previous justified and finalized Casper-FFG checkpoints
+//   - execution: the block numbers the EL reports for its "safe" and "finalized" tags
+//
+// "safe" is the head of the latest justified checkpoint and "finalized" the head of the finalized
+// one, so a tx moves pending -> included -> safe -> finalized as those tags pass its block.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// finalityCheckpoint is one Casper-FFG checkpoint
+type finalityCheckpoint struct {
+	Epoch uint64 `json:"epoch"`
+	Root  string `json:"root"`
+	Slot  uint64 `json:"slot"` // first slot of the epoch
+}
+
+// elBlockTag is what eth_getBlockByNumber returns for a named tag
+type elBlockTag struct {
+	Number    uint64 `json:"number"`
+	Hash      string `json:"hash"`
+	Timestamp uint64 `json:"timestamp"`
+}
+
+// finalityStatus is the combined consensus + execution finality picture
+type finalityStatus struct {
+	CurrentJustified  finalityCheckpoint `json:"current_justified"`
+	PreviousJustified finalityCheckpoint `json:"previous_justified"`
+	Finalized         finalityCheckpoint `json:"finalized"`
+
+	// Execution-layer tags; nil when the node doesn't support them (pre-Merge clients)
+	Latest         *elBlockTag `json:"latest"`
+	Safe           *elBlockTag `json:"safe"`
+	FinalizedBlock *elBlockTag `json:"finalized_block"`
+
+	CurrentEpoch        uint64 `json:"current_epoch"`
+	EpochsSinceFinality uint64 `json:"epochs_since_finality"`
+	Healthy             bool   `json:"healthy"` // finality is no more than 2 epochs behind
+	Source              string `json:"source"`  // "events" (beacon event stream) or "api"
+}
+
+// txConfirmation says how settled a transaction is
+type txConfirmation struct {
+	State string `json:"state"` // "pending", "included", "safe" or "finalized"
+
+	BlockNumber *uint64 `json:"block_number,omitempty"`
+	Slot        *uint64 `json:"slot,omitempty"`
+
+	// Expected finalization, assuming finality keeps up; nil once final or when we can't tell
+	ExpectedFinalizedAt *int64 `json:"expected_finalized_at,omitempty"`
+	EtaSeconds          *int64 `json:"eta_seconds,omitempty"`
+	Note                string `json:"note,omitempty"`
+}
+
+// loadFinality fetches checkpoints (from memory if the event stream is live) and the EL tags
+func loadFinality() (*finalityStatus, error) {
+	clk, err := getChainClock()
+	if err != nil {
+		return nil, err
+	}
+
+	fs := &finalityStatus{Source: "events"}
+	raw, ok := beaconState.finalizedCheckpoints()
+	if !ok {
+		fs.Source = "api"
+		var status int
+		raw, status, err = beaconGET("/eth/v1/beacon/states/head/finality_checkpoints")
+		if err != nil {
+			return nil, err
+		}
+		if status/100 != 2 {
+			return nil, fmt.Errorf("finality checkpoints: HTTP %d", status)
+		}
+	}
+
+	var cp struct {
+		Data struct {
+			PreviousJustified struct{ Epoch, Root string } `json:"previous_justified"`
+			CurrentJustified  struct{ Epoch, Root string } `json:"current_justified"`
+			Finalized         struct{ Epoch, Root string } `json:"finalized"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(raw, &cp); err != nil {
+		return nil, err
+	}
+	toCheckpoint := func(epoch, root string) finalityCheckpoint {
+		e, _ := strconv.ParseUint(epoch, 10, 64)
+		return finalityCheckpoint{Epoch: e, Root: root, Slot: clk.EpochStartSlot(e)}
+	}
+	fs.PreviousJustified = toCheckpoint(cp.Data.PreviousJustified.Epoch, cp.Data.PreviousJustified.Root)
+	fs.CurrentJustified = toCheckpoint(cp.Data.CurrentJustified.Epoch, cp.Data.CurrentJustified.Root)
+	fs.Finalized = toCheckpoint(cp.Data.Finalized.Epoch, cp.Data.Finalized.Root)
+	if cp.Data.Finalized.Epoch == "" {
+		return nil, errors.New("finality checkpoints: missing finalized epoch")
+	}
+
+	fs.CurrentEpoch = clk.EpochOf(clk.CurrentSlot(time.Now()))
+	if fs.CurrentEpoch > fs.Finalized.Epoch {
+		fs.EpochsSinceFinality = fs.CurrentEpoch - fs.Finalized.Epoch
+	}
+	fs.Healthy = fs.EpochsSinceFinality <= 2
+
+	// All three EL tags in one round-trip
+	results, _ := rpcBatchCall([]rpcBatchRequest{
+		{Method: "eth_getBlockByNumber", Params: []any{"latest", false}},
+		{Method: "eth_getBlockByNumber", Params: []any{"safe", false}},
+		{Method: "eth_getBlockByNumber", Params: []any{"finalized", false}},
+	})
+	tags := make([]*elBlockTag, len(results))
+	for i, res := range results {
+		if res.Err != nil || len(res.Result) == 0 || string(res.Result) == "null" {
+			continue
+		}
+		var b struct {
+			Number    string `json:"number"`
+			Hash      string `json:"hash"`
+			Timestamp string `json:"timestamp"`
+		}
+		if json.Unmarshal(res.Result, &b) != nil {
+			continue
+		}
+		n, err := parseHexUint64(b.Number)
+		if err != nil {
+			continue
+		}
+		ts, _ := parseHexUint64(b.Timestamp)
+		tags[i] = &elBlockTag{Number: n, Hash: b.Hash, Timestamp: ts}
+	}
+	fs.Latest, fs.Safe, fs.FinalizedBlock = tags[0], tags[1], tags[2]
+
+	return fs, nil
+}
+
+// confirmationFor works out where a tx in block blockNumber (timestamp blockTs) stands.
+// Pass blockNumber == nil for a pending tx.
+func (fs *finalityStatus) confirmationFor(blockNumber *uint64, blockTs uint64) txConfirmation {
+	c := txConfirmation{State: "pending"}
+	if blockNumber == nil {
+		return c
+	}
+	c.State = "included"
+	c.BlockNumber = blockNumber
+
+	if fs.FinalizedBlock != nil && *blockNumber <= fs.FinalizedBlock.Number {
+		c.State = "finalized"
+		return c
+	}
+	if fs.Safe != nil && *blockNumber <= fs.Safe.Number {
+		c.State = "safe"
+	}
+
+	clk, err := getChainClock()
+	if err != nil {
+		return c
+	}
+	slot, ok := clk.SlotAt(blockTs)
+	if !ok {
+		return c
+	}
+	c.Slot = &slot
+
+	// A block is final once the first checkpoint at or after it is finalized. Checkpoint epoch E
+	// is finalized when E+1 gets justified, which is processed at the start of E+2.
+	target := clk.EpochOf(slot)
+	if slot != clk.EpochStartSlot(target) {
+		target++
+	}
+	eta := int64(clk.SlotStart(clk.EpochStartSlot(target + 2)))
+	c.ExpectedFinalizedAt = &eta
+	left := eta - time.Now().Unix()
+	if left < 0 {
+		left = 0
+	}
+	c.EtaSeconds = &left
+	if !fs.Healthy {
+		c.Note = fmt.Sprintf("finality is %d epochs behind, so this estimate is optimistic", fs.EpochsSinceFinality)
+	}
+	return c
+}
+
+// handleFinality returns Casper-FFG checkpoints showing which epochs are justified/finalized,
+// plus the EL safe/finalized blocks. Once a block is finalized, it's basically impossible to reorg.
+func handleFinality(w http.ResponseWriter, r *http.Request) {
+	fs, err := loadFinality()
+	if err != nil {
+		writeErr(w, http.StatusTooManyRequests, "BEACON", "Finality checkpoints fetch failed", "Public beacon API may be rate limiting. Try again or configure BEACON_API_URL to a local consensus client.")
+		return
+	}
+	writeOK(w, fs)
+}
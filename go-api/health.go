@@ -18,9 +18,10 @@ type HealthStatus struct {
 
 // OverallHealth represents the health status of all data sources
 type OverallHealth struct {
-	Status      string         `json:"status"` // "healthy", "degraded", "unhealthy"
-	Timestamp   time.Time      `json:"timestamp"`
-	DataSources []HealthStatus `json:"dataSources"`
+	Status      string              `json:"status"` // "healthy", "degraded", "unhealthy"
+	Timestamp   time.Time           `json:"timestamp"`
+	DataSources []HealthStatus      `json:"dataSources"`
+	Relays      []relayHealthReport `json:"relays"` // per-relay detail behind the "relay" data source
 	Summary     struct {
 		Total     int `json:"total"`
 		Healthy   int `json:"healthy"`
@@ -159,6 +160,7 @@ func handleHealth(w http.ResponseWriter, r *http.Request) {
 		Status:      overallStatus,
 		Timestamp:   time.Now(),
 		DataSources: dataSources,
+		Relays:      relayHealthReports(),
 	}
 
 	health.Summary.Total = totalCount
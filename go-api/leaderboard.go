@@ -0,0 +1,467 @@
+// leaderboard.go
+// Who is winning the block-building market? A background aggregator collects every payload the
+// relays delivered (paging back with cursors until it covers the longest window, then polling
+// for new ones) and ranks builders and relays over rolling windows: blocks won, total and median
+// proposer payment, and share of slots.
+//
+// The backfill costs hundreds of relay requests, so the aggregator only starts the first time
+// someone asks for the leaderboard; LEADERBOARD_DISABLE=1 turns it off entirely.
+//
+// Windows come from LEADERBOARD_WINDOWS (default "1h,24h,7d"). Builder pubkeys can be given
+// readable names with BUILDER_NAMES_FILE, a JSON object of {"0xpubkey": "name"}.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// leaderboardWindow is one named rolling window
+type leaderboardWindow struct {
+	Name     string
+	Duration time.Duration
+}
+
+// leaderboardWindows are the windows we rank over, shortest first
+var leaderboardWindows = func() []leaderboardWindow {
+	raw := envOr("LEADERBOARD_WINDOWS", "1h,24h,7d")
+	var out []leaderboardWindow
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		d, err := parseWindowDuration(part)
+		if err != nil || d <= 0 {
+			if part != "" {
+				log.Printf("leaderboard: ignoring window %q: %v\n", part, err)
+			}
+			continue
+		}
+		out = append(out, leaderboardWindow{Name: part, Duration: d})
+	}
+	if len(out) == 0 {
+		out = []leaderboardWindow{{"1h", time.Hour}, {"24h", 24 * time.Hour}, {"7d", 7 * 24 * time.Hour}}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Duration < out[j].Duration })
+	return out
+}()
+
+// leaderboardBackfillPages caps how many cursor pages we fetch going back in time
+var leaderboardBackfillPages = func() int {
+	if s := os.Getenv("LEADERBOARD_BACKFILL_PAGES"); s != "" {
+		if n, err := strconv.Atoi(s); err == nil && n >= 0 && n <= 5000 {
+			return n
+		}
+	}
+	return 300
+}()
+
+// leaderboardPageSize is how many payloads we ask each relay for per request
+const leaderboardPageSize = 200
+
+// parseWindowDuration is time.ParseDuration plus a "d" (days) suffix
+func parseWindowDuration(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		n, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil {
+			return 0, fmt.Errorf("bad day count")
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// builderNames maps builder pubkey (lowercase) to a readable name, from BUILDER_NAMES_FILE
+var builderNames = func() map[string]string {
+	names := map[string]string{}
+	path := os.Getenv("BUILDER_NAMES_FILE")
+	if path == "" {
+		return names
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		log.Printf("leaderboard: can't read BUILDER_NAMES_FILE: %v\n", err)
+		return names
+	}
+	var m map[string]string
+	if err := json.Unmarshal(raw, &m); err != nil {
+		log.Printf("leaderboard: BUILDER_NAMES_FILE is not a JSON object of pubkey -> name: %v\n", err)
+		return names
+	}
+	for k, v := range m {
+		names[strings.ToLower(k)] = v
+	}
+	log.Printf("leaderboard: loaded %d builder names\n", len(names))
+	return names
+}()
+
+// builderName returns the configured name for a builder pubkey, or ""
+func builderName(pubkey string) string {
+	return builderNames[strings.ToLower(pubkey)]
+}
+
+// leaderboardEntry is one builder's or relay's row
+type leaderboardEntry struct {
+	Key              string  `json:"key"` // builder pubkey or relay host
+	Name             string  `json:"name,omitempty"`
+	Blocks           int     `json:"blocks"`
+	TotalPayment     string  `json:"total_payment"` // wei
+	TotalPaymentETH  string  `json:"total_payment_eth"`
+	MedianPayment    string  `json:"median_payment"` // wei
+	MedianPaymentETH string  `json:"median_payment_eth"`
+	ShareOfSlots     float64 `json:"share_of_slots"`      // % of all slots in the window
+	ShareOfMEVBlocks float64 `json:"share_of_mev_blocks"` // % of relay-delivered blocks in the window
+
+	payments []*big.Int
+}
+
+// leaderboardWindowStats is the leaderboard for one window
+type leaderboardWindowStats struct {
+	Window        string              `json:"window"`
+	FromSlot      uint64              `json:"from_slot"`
+	ToSlot        uint64              `json:"to_slot"`
+	Slots         uint64              `json:"slots"`
+	MEVBlocks     int                 `json:"mev_blocks"`
+	Complete      bool                `json:"complete"` // we have data back to the start of the window
+	Builders      []*leaderboardEntry `json:"builders"`
+	Relays        []*leaderboardEntry `json:"relays"` // a payload several relays delivered counts for each
+	OldestCovered uint64              `json:"oldest_covered_slot"`
+}
+
+// leaderboardStore holds delivered payloads by slot and the latest computed stats
+type leaderboardStore struct {
+	mu       sync.RWMutex
+	bySlot   map[uint64]*BidTrace
+	oldest   uint64 // oldest slot our history reaches (we've seen everything after it)
+	pages    int
+	backfill bool // still paging back
+	stats    []*leaderboardWindowStats
+	updated  time.Time
+}
+
+// leaderboard is the process-wide aggregator
+var leaderboard = &leaderboardStore{bySlot: map[uint64]*BidTrace{}, backfill: true}
+
+var (
+	leaderboardStart    sync.Once
+	leaderboardDisabled = func() bool {
+		d := strings.ToLower(envOr("LEADERBOARD_DISABLE", ""))
+		return d == "1" || d == "true" || d == "yes" || d == "on"
+	}()
+)
+
+// startLeaderboard runs the aggregator in the background (once; later calls do nothing)
+func startLeaderboard() {
+	leaderboardStart.Do(func() {
+		log.Println("leaderboard: starting aggregator")
+		go func() {
+			for {
+				clk, err := getChainClock()
+				if err == nil {
+					runLeaderboard(clk)
+					return
+				}
+				log.Printf("leaderboard: waiting for chain clock: %v\n", err)
+				time.Sleep(30 * time.Second)
+			}
+		}()
+	})
+}
+
+// runLeaderboard backfills history page by page, interleaved with polling for new payloads
+func runLeaderboard(clk *chainClock) {
+	longest := leaderboardWindows[len(leaderboardWindows)-1].Duration
+	var cursor *uint64
+	slot := time.Duration(clk.SecondsPerSlot) * time.Second
+
+	for {
+		// Newest payloads first
+		leaderboard.ingest(relayQuery{Limit: leaderboardPageSize})
+
+		// Then one page further back, until the longest window is covered or we hit the cap
+		if leaderboard.stillBackfilling() {
+			windowStart := windowStartSlot(clk, longest)
+			next, done := leaderboard.ingestPage(cursor, windowStart)
+			cursor = next
+			if done {
+				leaderboard.finishBackfill()
+			}
+		}
+
+		leaderboard.recompute(clk)
+
+		// Page back about once a slot; once caught up, a poll every few slots is plenty
+		if leaderboard.stillBackfilling() {
+			time.Sleep(slot)
+		} else {
+			time.Sleep(5 * slot)
+		}
+	}
+}
+
+// windowStartSlot returns the first slot inside a window ending now
+func windowStartSlot(clk *chainClock, d time.Duration) uint64 {
+	now := clk.CurrentSlot(time.Now())
+	n := uint64(d / (time.Duration(clk.SecondsPerSlot) * time.Second))
+	if n > now {
+		return 0
+	}
+	return now - n
+}
+
+// stillBackfilling reports whether we're still paging back through history
+func (s *leaderboardStore) stillBackfilling() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.backfill
+}
+
+// finishBackfill stops paging back
+func (s *leaderboardStore) finishBackfill() {
+	s.mu.Lock()
+	s.backfill = false
+	pages := s.pages
+	s.mu.Unlock()
+	log.Printf("leaderboard: backfill finished after %d pages\n", pages)
+}
+
+// ingest fetches one query's worth of delivered payloads from every relay into the store
+func (s *leaderboardStore) ingest(q relayQuery) *relayMerged {
+	merged, err := relayFanoutRecords(q.path(relayDeliveredEndpoint))
+	if err != nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range merged.traces() {
+		if existing, ok := s.bySlot[t.Slot]; ok {
+			existing.Relays = mergeRelayNames(existing.Relays, t.Relays)
+			continue
+		}
+		s.bySlot[t.Slot] = t
+	}
+	return merged
+}
+
+// ingestPage fetches the page at cursor and returns the cursor for the next one.
+// done is true once we've reached windowStart, run out of history, or hit the page cap.
+func (s *leaderboardStore) ingestPage(cursor *uint64, windowStart uint64) (next *uint64, done bool) {
+	merged := s.ingest(relayQuery{Limit: leaderboardPageSize, Cursor: cursor})
+	if merged == nil {
+		return cursor, false // try the same page again next time
+	}
+
+	// Each relay pages independently. Continue from the newest "oldest slot" among relays that
+	// returned a full page, so no relay's history has a gap (overlap is de-duplicated by slot).
+	var resume uint64
+	full := false
+	for _, res := range merged.Relays {
+		if res.OK && res.Count >= leaderboardPageSize && res.oldest > resume {
+			resume, full = res.oldest, true
+		}
+	}
+
+	s.mu.Lock()
+	s.pages++
+	pages := s.pages
+	oldest := ^uint64(0)
+	for _, res := range merged.Relays {
+		if res.OK && res.Count > 0 && res.oldest < oldest {
+			oldest = res.oldest
+		}
+	}
+	if !full {
+		// Every relay ran out of history - what we have is all there is
+		if oldest != ^uint64(0) && (s.oldest == 0 || oldest < s.oldest) {
+			s.oldest = oldest
+		}
+		s.mu.Unlock()
+		return nil, true
+	}
+	if s.oldest == 0 || resume < s.oldest {
+		s.oldest = resume
+	}
+	s.mu.Unlock()
+
+	if resume <= windowStart || resume == 0 || pages >= leaderboardBackfillPages {
+		return nil, true
+	}
+	n := resume - 1
+	return &n, false
+}
+
+// mergeRelayNames adds relays from b to a, skipping ones already there
+func mergeRelayNames(a, b []string) []string {
+	for _, r := range b {
+		found := false
+		for _, x := range a {
+			if x == r {
+				found = true
+				break
+			}
+		}
+		if !found {
+			a = append(a, r)
+		}
+	}
+	return a
+}
+
+// recompute rebuilds the per-window stats and prunes payloads older than the longest window
+func (s *leaderboardStore) recompute(clk *chainClock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := clk.CurrentSlot(time.Now())
+	longestStart := windowStartSlot(clk, leaderboardWindows[len(leaderboardWindows)-1].Duration)
+	for slot := range s.bySlot {
+		if slot < longestStart {
+			delete(s.bySlot, slot)
+		}
+	}
+
+	stats := make([]*leaderboardWindowStats, 0, len(leaderboardWindows))
+	for _, win := range leaderboardWindows {
+		from := windowStartSlot(clk, win.Duration)
+		ws := &leaderboardWindowStats{
+			Window:        win.Name,
+			FromSlot:      from,
+			ToSlot:        now,
+			Slots:         now - from,
+			Complete:      s.oldest != 0 && s.oldest <= from,
+			OldestCovered: s.oldest,
+		}
+		builders := map[string]*leaderboardEntry{}
+		relays := map[string]*leaderboardEntry{}
+		for slot, t := range s.bySlot {
+			if slot < from || slot > now {
+				continue
+			}
+			ws.MEVBlocks++
+			addLeaderboardPayment(builders, t.BuilderPubkey, t.Value)
+			for _, r := range t.Relays {
+				addLeaderboardPayment(relays, r, t.Value)
+			}
+		}
+		ws.Builders = finishLeaderboard(builders, ws, true)
+		ws.Relays = finishLeaderboard(relays, ws, false)
+		stats = append(stats, ws)
+	}
+	s.stats = stats
+	s.updated = time.Now()
+}
+
+// addLeaderboardPayment credits one block and its payment to key
+func addLeaderboardPayment(m map[string]*leaderboardEntry, key string, value *big.Int) {
+	e, ok := m[key]
+	if !ok {
+		e = &leaderboardEntry{Key: key}
+		m[key] = e
+	}
+	e.Blocks++
+	e.payments = append(e.payments, value)
+}
+
+// finishLeaderboard fills in totals, medians and shares, and sorts by blocks won
+func finishLeaderboard(m map[string]*leaderboardEntry, ws *leaderboardWindowStats, builders bool) []*leaderboardEntry {
+	out := make([]*leaderboardEntry, 0, len(m))
+	for _, e := range m {
+		total := new(big.Int)
+		for _, p := range e.payments {
+			total.Add(total, p)
+		}
+		sort.Slice(e.payments, func(i, j int) bool { return e.payments[i].Cmp(e.payments[j]) < 0 })
+		median := new(big.Int)
+		if n := len(e.payments); n > 0 {
+			if n%2 == 1 {
+				median.Set(e.payments[n/2])
+			} else {
+				median.Add(e.payments[n/2-1], e.payments[n/2])
+				median.Quo(median, big.NewInt(2))
+			}
+		}
+		e.TotalPayment, e.TotalPaymentETH = total.String(), weiToETHDecimal(total)
+		e.MedianPayment, e.MedianPaymentETH = median.String(), weiToETHDecimal(median)
+		if ws.Slots > 0 {
+			e.ShareOfSlots = roundTo(float64(e.Blocks)*100/float64(ws.Slots), 2)
+		}
+		if ws.MEVBlocks > 0 {
+			e.ShareOfMEVBlocks = roundTo(float64(e.Blocks)*100/float64(ws.MEVBlocks), 2)
+		}
+		if builders {
+			e.Name = builderName(e.Key)
+		}
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Blocks != out[j].Blocks {
+			return out[i].Blocks > out[j].Blocks
+		}
+		return out[i].Key < out[j].Key
+	})
+	return out
+}
+
+// handleLeaderboard serves GET /api/builders/leaderboard?window=24h&limit=20
+func handleLeaderboard(w http.ResponseWriter, r *http.Request) {
+	limit := 20
+	if s := r.URL.Query().Get("limit"); s != "" {
+		if n, err := strconv.Atoi(s); err == nil && n > 0 && n <= 500 {
+			limit = n
+		}
+	}
+	only := r.URL.Query().Get("window")
+
+	if leaderboardDisabled {
+		writeErr(w, http.StatusServiceUnavailable, "LEADERBOARD_DISABLED", "Leaderboard is disabled", "Unset LEADERBOARD_DISABLE to enable it")
+		return
+	}
+	startLeaderboard()
+
+	// Copy what we need under the lock and encode after releasing it
+	leaderboard.mu.RLock()
+	windows := []*leaderboardWindowStats{}
+	for _, ws := range leaderboard.stats {
+		if only != "" && ws.Window != only {
+			continue
+		}
+		cp := *ws
+		if len(cp.Builders) > limit {
+			cp.Builders = cp.Builders[:limit]
+		}
+		if len(cp.Relays) > limit {
+			cp.Relays = cp.Relays[:limit]
+		}
+		windows = append(windows, &cp)
+	}
+	known := len(leaderboard.stats) > 0
+	resp := map[string]any{
+		"windows":     windows,
+		"updated_at":  nil, // until the first refresh lands
+		"backfilling": leaderboard.backfill,
+		"pages":       leaderboard.pages,
+		"payloads":    len(leaderboard.bySlot),
+	}
+	if !leaderboard.updated.IsZero() {
+		resp["updated_at"] = leaderboard.updated.Unix()
+	}
+	leaderboard.mu.RUnlock()
+
+	if only != "" && len(windows) == 0 && known {
+		names := make([]string, len(leaderboardWindows))
+		for i, win := range leaderboardWindows {
+			names[i] = win.Name
+		}
+		writeErr(w, http.StatusBadRequest, "BAD_REQUEST", "Unknown window "+only, "Configured windows: "+strings.Join(names, ", "))
+		return
+	}
+
+	writeOK(w, resp)
+}
@@ -0,0 +1,446 @@
+// liquidations.go
+// Liquidations are MEV too: when a loan's collateral falls below the protocol's threshold, anyone
+// may repay part of the debt and take collateral at a discount (the liquidation bonus). Searchers
+// race for these the moment an oracle update makes a position unhealthy.
+//
+// Every lending protocol announces it differently, so like swap_decoders.go this is a registry of
+// event decoders keyed by topic0:
+//   - Aave V2/V3 (and forks like Spark) LiquidationCall: repays debtAsset, seizes collateralAsset
+//   - Compound V2 LiquidateBorrow: emitted by the borrowed cToken, collateral seized in cTokens
+//   - Compound V3 AbsorbDebt/AbsorbCollateral: the protocol absorbs the whole account (one event per
+//     collateral asset), and sells the collateral later through buyCollateral
+//   - Maker Dog Bark: the vault is seized and its collateral goes to a Dutch auction
+//
+// Amounts are raw token units, like the rest of the MEV endpoints.
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// liquidation is one liquidated position
+type liquidation struct {
+	Protocol   string `json:"protocol"` // aave_v2, aave_v3, spark, aave_fork, compound_v2, compound_v3 or maker
+	Market     string `json:"market"`   // contract that emitted it: Aave pool, borrowed cToken, Comet or Maker Dog
+	TxHash     string `json:"txHash"`
+	TxIndex    int    `json:"txIndex"`
+	LogIndex   int    `json:"logIndex"`
+	Liquidator string `json:"liquidator"`
+	Borrower   string `json:"borrower"`
+
+	DebtAsset  string                 `json:"debtAsset"`
+	DebtRepaid string                 `json:"debtRepaid"`
+	DebtUsd    string                 `json:"debtUsd,omitempty"` // Compound V3 only (8 decimals)
+	Collateral []liquidatedCollateral `json:"collateral"`
+
+	// Liquidation bonus in percent: what the liquidator gets on top of the debt it repaid
+	BonusPct    *float64 `json:"bonusPct,omitempty"`
+	BonusSource string   `json:"bonusSource,omitempty"` // "configured" (protocol parameter) or "usd_value" (from the event's USD amounts)
+	Note        string   `json:"note,omitempty"`
+}
+
+// liquidatedCollateral is one collateral asset seized from the borrower
+type liquidatedCollateral struct {
+	Asset  string `json:"asset"`
+	Amount string `json:"amount"`
+	Usd    string `json:"usd,omitempty"` // Compound V3 only (8 decimals)
+}
+
+// liquidationLog is the part of a receipt log the decoders need
+type liquidationLog struct {
+	Address string
+	Topics  []string
+	Data    []byte
+	TxFrom  string
+}
+
+// liquidationDecoder recognizes one liquidation event
+type liquidationDecoder struct {
+	Protocol  string
+	Signature string
+	Decode    func(lg liquidationLog) *liquidation // nil if the log is malformed
+}
+
+// liquidationDecoders maps topic0 to its decoder; see registerLiquidationDecoder
+var liquidationDecoders = map[string]*liquidationDecoder{}
+
+// registerLiquidationDecoder adds a decoder, keyed by the keccak of its event signature
+func registerLiquidationDecoder(d *liquidationDecoder) {
+	liquidationDecoders[strings.ToLower(keccakTopic(d.Signature))] = d
+}
+
+func init() {
+	registerLiquidationDecoder(&liquidationDecoder{
+		Protocol:  "aave",
+		Signature: "LiquidationCall(address,address,address,uint256,uint256,address,bool)",
+		Decode:    decodeAaveLiquidation,
+	})
+	registerLiquidationDecoder(&liquidationDecoder{
+		Protocol:  "compound_v2",
+		Signature: "LiquidateBorrow(address,address,uint256,address,uint256)",
+		Decode:    decodeCompoundV2Liquidation,
+	})
+	registerLiquidationDecoder(&liquidationDecoder{
+		Protocol:  "compound_v3",
+		Signature: "AbsorbDebt(address,address,uint256,uint256)",
+		Decode:    decodeCompoundV3AbsorbDebt,
+	})
+	registerLiquidationDecoder(&liquidationDecoder{
+		Protocol:  "compound_v3",
+		Signature: "AbsorbCollateral(address,address,address,uint256,uint256)",
+		Decode:    decodeCompoundV3AbsorbCollateral,
+	})
+	registerLiquidationDecoder(&liquidationDecoder{
+		Protocol:  "maker",
+		Signature: "Bark(bytes32,address,uint256,uint256,uint256,address,uint256)",
+		Decode:    decodeMakerBark,
+	})
+}
+
+// aavePools names the Aave deployments (forks emit the same LiquidationCall)
+var aavePools = map[string]string{
+	"0x7d2768de32b0b80b7a3454c06bdac94a69ddc7a9": "aave_v2",
+	"0x87870bca3f3fd6335c3f4ce8392d69350b4fa4e2": "aave_v3",
+	"0xc13e21b648a5ee794902342038ff3adab66be987": "spark",
+}
+
+// logAddress returns the i-th data word as an address, or ""
+func logAddress(data []byte, i int) string {
+	if len(data) < (i+1)*32 {
+		return ""
+	}
+	return "0x" + strings.ToLower(hex.EncodeToString(data[i*32+12:(i+1)*32]))
+}
+
+// decodeAaveLiquidation: LiquidationCall(address indexed collateralAsset, address indexed debtAsset,
+// address indexed user, uint256 debtToCover, uint256 liquidatedCollateralAmount, address liquidator,
+// bool receiveAToken)
+func decodeAaveLiquidation(lg liquidationLog) *liquidation {
+	debt, seized := logWord(lg.Data, 0), logWord(lg.Data, 1)
+	if len(lg.Topics) < 4 || seized == nil {
+		return nil
+	}
+	protocol, ok := aavePools[lg.Address]
+	if !ok {
+		protocol = "aave_fork"
+	}
+	return &liquidation{
+		Protocol:   protocol,
+		Liquidator: logAddress(lg.Data, 2),
+		Borrower:   topicAt(lg.Topics, 3),
+		DebtAsset:  topicAt(lg.Topics, 2),
+		DebtRepaid: debt.String(),
+		Collateral: []liquidatedCollateral{{Asset: topicAt(lg.Topics, 1), Amount: seized.String()}},
+	}
+}
+
+// decodeCompoundV2Liquidation: LiquidateBorrow(address liquidator, address borrower, uint repayAmount,
+// address cTokenCollateral, uint seizeTokens) - nothing indexed. The emitter is the borrowed cToken.
+func decodeCompoundV2Liquidation(lg liquidationLog) *liquidation {
+	repay, seized := logWord(lg.Data, 2), logWord(lg.Data, 4)
+	if seized == nil {
+		return nil
+	}
+	return &liquidation{
+		Liquidator: logAddress(lg.Data, 0),
+		Borrower:   logAddress(lg.Data, 1),
+		DebtAsset:  lg.Address,
+		DebtRepaid: repay.String(),
+		Collateral: []liquidatedCollateral{{Asset: logAddress(lg.Data, 3), Amount: seized.String()}},
+		Note:       "Debt is in the borrowed cToken's underlying; collateral is in cToken units",
+	}
+}
+
+// decodeCompoundV3AbsorbDebt: AbsorbDebt(address indexed absorber, address indexed borrower,
+// uint basePaidOut, uint usdValue). The debt asset is the Comet's base token.
+func decodeCompoundV3AbsorbDebt(lg liquidationLog) *liquidation {
+	paid, usd := logWord(lg.Data, 0), logWord(lg.Data, 1)
+	if len(lg.Topics) < 3 || usd == nil {
+		return nil
+	}
+	return &liquidation{
+		Liquidator: topicAt(lg.Topics, 1),
+		Borrower:   topicAt(lg.Topics, 2),
+		DebtAsset:  "base",
+		DebtRepaid: paid.String(),
+		DebtUsd:    usd.String(),
+		Collateral: []liquidatedCollateral{},
+	}
+}
+
+// decodeCompoundV3AbsorbCollateral: AbsorbCollateral(address indexed absorber, address indexed borrower,
+// address indexed asset, uint collateralAbsorbed, uint usdValue) - one per collateral asset
+func decodeCompoundV3AbsorbCollateral(lg liquidationLog) *liquidation {
+	amount, usd := logWord(lg.Data, 0), logWord(lg.Data, 1)
+	if len(lg.Topics) < 4 || usd == nil {
+		return nil
+	}
+	return &liquidation{
+		Liquidator: topicAt(lg.Topics, 1),
+		Borrower:   topicAt(lg.Topics, 2),
+		Collateral: []liquidatedCollateral{{Asset: topicAt(lg.Topics, 3), Amount: amount.String(), Usd: usd.String()}},
+	}
+}
+
+// decodeMakerBark: Bark(bytes32 indexed ilk, address indexed urn, uint256 ink, uint256 art, uint256 due,
+// address clip, uint256 indexed id). ink is the collateral seized (wad), due the debt (rad = 45 decimals,
+// scaled down to 18 here). The keeper who called bark() isn't in the event - it's the tx sender.
+func decodeMakerBark(lg liquidationLog) *liquidation {
+	ink, due := logWord(lg.Data, 0), logWord(lg.Data, 2)
+	if len(lg.Topics) < 3 || due == nil {
+		return nil
+	}
+	ilk := strings.TrimRight(string(decodeHex(lg.Topics[1])), "\x00")
+	return &liquidation{
+		Liquidator: lg.TxFrom,
+		Borrower:   topicAt(lg.Topics, 2),
+		DebtAsset:  "DAI",
+		DebtRepaid: new(big.Int).Quo(due, new(big.Int).Exp(big.NewInt(10), big.NewInt(27), nil)).String(),
+		Collateral: []liquidatedCollateral{{Asset: ilk, Amount: ink.String()}},
+		Note:       "The vault's debt (debtRepaid) and collateral go to a Dutch auction (" + logAddress(lg.Data, 3) + "); the keeper is paid the tip/chip incentive rather than a collateral bonus",
+	}
+}
+
+// liquidationsFromReceipts decodes every liquidation in already-fetched receipts (lined up with
+// b.Transactions). Compound V3 absorbs are merged into one entry per borrower and Comet.
+func liquidationsFromReceipts(b *block, receipts []*receipt) []*liquidation {
+	out := []*liquidation{}
+	for idx, rcpt := range receipts {
+		if rcpt == nil {
+			continue
+		}
+		tx := b.Transactions[idx]
+		absorbs := map[string]*liquidation{} // Comet + borrower -> merged entry
+		for logIdx, lg := range rcpt.Logs {
+			if len(lg.Topics) == 0 {
+				continue
+			}
+			d, ok := liquidationDecoders[strings.ToLower(lg.Topics[0])]
+			if !ok {
+				continue
+			}
+			liq := d.Decode(liquidationLog{
+				Address: strings.ToLower(lg.Address),
+				Topics:  lg.Topics,
+				Data:    decodeHex(lg.Data),
+				TxFrom:  strings.ToLower(tx.From),
+			})
+			if liq == nil {
+				continue
+			}
+			if liq.Protocol == "" {
+				liq.Protocol = d.Protocol
+			}
+			liq.Market = strings.ToLower(lg.Address)
+			liq.TxHash, liq.TxIndex, liq.LogIndex = strings.ToLower(tx.Hash), idx, logIdx
+
+			if liq.Protocol == "compound_v3" {
+				key := liq.Market + "|" + liq.Borrower
+				if prev, ok := absorbs[key]; ok {
+					prev.Collateral = append(prev.Collateral, liq.Collateral...)
+					if liq.DebtAsset != "" {
+						prev.DebtAsset, prev.DebtRepaid, prev.DebtUsd = liq.DebtAsset, liq.DebtRepaid, liq.DebtUsd
+					}
+					continue
+				}
+				absorbs[key] = liq
+			}
+			out = append(out, liq)
+		}
+	}
+	return out
+}
+
+// === Liquidation bonus ===
+// Aave keeps the bonus per collateral asset in the reserve configuration bitmap (bits 32-47, e.g.
+// 10500 = 5%). Compound V2 has one liquidationIncentiveMantissa on the comptroller (e.g. 1.08e18 = 8%).
+// Compound V3's events carry USD values, so the absorb discount is simply collateral USD / debt USD.
+// Parameters rarely change, so answers are cached.
+
+// Function selectors for the parameter lookups
+var (
+	selectorAaveGetConfiguration = keccakTopic("getConfiguration(address)")[:10]
+	selectorCTokenComptroller    = keccakTopic("comptroller()")[:10]
+	selectorLiquidationIncentive = keccakTopic("liquidationIncentiveMantissa()")[:10]
+	selectorCometBaseToken       = keccakTopic("baseToken()")[:10]
+)
+
+var (
+	liquidationParamMu    sync.Mutex
+	liquidationParamCache = map[string]string{} // "to|data" -> eth_call result ("" = the call reverted)
+)
+
+// liquidationParamCacheMax bounds the cache; it's cleared when full
+const liquidationParamCacheMax = 10000
+
+// liquidationParams answers eth_calls from the cache, asking the node (in one batch) for the rest.
+// Keys are "to|data". Results and reverts are cached; transport and rate-limit errors come back as
+// "" for this request only, so the next one asks again.
+func liquidationParams(calls []string) map[string]string {
+	out := make(map[string]string, len(calls))
+	var missing []string
+	liquidationParamMu.Lock()
+	for _, c := range calls {
+		if v, ok := liquidationParamCache[c]; ok {
+			out[c] = v
+		} else if _, dup := out[c]; !dup {
+			out[c] = ""
+			missing = append(missing, c)
+		}
+	}
+	liquidationParamMu.Unlock()
+	if len(missing) == 0 {
+		return out
+	}
+
+	reqs := make([]rpcBatchRequest, len(missing))
+	for i, c := range missing {
+		to, data, _ := strings.Cut(c, "|")
+		reqs[i] = rpcBatchRequest{Method: "eth_call", Params: []any{map[string]string{"to": to, "data": data}, "latest"}}
+	}
+	results, _ := rpcBatchCall(reqs) // failed chunks show up as per-call errors
+
+	liquidationParamMu.Lock()
+	defer liquidationParamMu.Unlock()
+	if len(liquidationParamCache)+len(missing) > liquidationParamCacheMax {
+		liquidationParamCache = map[string]string{}
+	}
+	for i, c := range missing {
+		if !results[i].definitive() {
+			continue
+		}
+		var h string
+		if results[i].Err == nil {
+			_ = json.Unmarshal(results[i].Result, &h)
+		}
+		liquidationParamCache[c] = h
+		out[c] = h
+	}
+	return out
+}
+
+// paramWord reads word i of an eth_call result, or nil
+func paramWord(h string, i int) *big.Int {
+	return logWord(decodeHex(h), i)
+}
+
+// addressArg ABI-encodes an address argument
+func addressArg(a string) string {
+	return strings.Repeat("0", 24) + strings.TrimPrefix(a, "0x")
+}
+
+// fillLiquidationBonuses works out the bonus for each liquidation (and Compound V3's base token)
+func fillLiquidationBonuses(liqs []*liquidation) {
+	// Round 1: Aave configurations, cToken comptrollers, Comet base tokens
+	var calls []string
+	for _, l := range liqs {
+		switch {
+		case strings.HasPrefix(l.Protocol, "aave") || l.Protocol == "spark":
+			calls = append(calls, l.Market+"|"+selectorAaveGetConfiguration+addressArg(l.Collateral[0].Asset))
+		case l.Protocol == "compound_v2":
+			calls = append(calls, l.Market+"|"+selectorCTokenComptroller)
+		case l.Protocol == "compound_v3":
+			calls = append(calls, l.Market+"|"+selectorCometBaseToken)
+		}
+	}
+	res := liquidationParams(calls)
+
+	// Round 2: comptroller incentives
+	calls = calls[:0]
+	comptrollers := map[string]string{}
+	for _, l := range liqs {
+		if l.Protocol != "compound_v2" {
+			continue
+		}
+		if w := paramWord(res[l.Market+"|"+selectorCTokenComptroller], 0); w != nil {
+			comptrollers[l.Market] = "0x" + hex.EncodeToString(w.FillBytes(make([]byte, 32))[12:])
+			calls = append(calls, comptrollers[l.Market]+"|"+selectorLiquidationIncentive)
+		}
+	}
+	incentives := liquidationParams(calls)
+
+	for _, l := range liqs {
+		switch {
+		case strings.HasPrefix(l.Protocol, "aave") || l.Protocol == "spark":
+			cfg := paramWord(res[l.Market+"|"+selectorAaveGetConfiguration+addressArg(l.Collateral[0].Asset)], 0)
+			if cfg == nil {
+				continue
+			}
+			bonus := new(big.Int).And(new(big.Int).Rsh(cfg, 32), big.NewInt(0xffff)).Int64()
+			if bonus > 10000 {
+				pct := roundTo(float64(bonus-10000)/100, 2)
+				l.BonusPct, l.BonusSource = &pct, "configured"
+				if l.Protocol == "aave_v3" {
+					l.Note = "Configured bonus for the collateral asset; E-Mode positions use their category's bonus instead"
+				}
+			}
+		case l.Protocol == "compound_v2":
+			m := paramWord(incentives[comptrollers[l.Market]+"|"+selectorLiquidationIncentive], 0)
+			if m == nil || m.Sign() == 0 {
+				continue
+			}
+			f, _ := new(big.Rat).SetFrac(m, new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)).Float64()
+			pct := roundTo((f-1)*100, 2)
+			l.BonusPct, l.BonusSource = &pct, "configured"
+		case l.Protocol == "compound_v3":
+			if w := paramWord(res[l.Market+"|"+selectorCometBaseToken], 0); w != nil && l.DebtAsset == "base" {
+				l.DebtAsset = "0x" + hex.EncodeToString(w.FillBytes(make([]byte, 32))[12:])
+			}
+			debtUsd, ok := new(big.Int).SetString(l.DebtUsd, 10)
+			if !ok || debtUsd.Sign() == 0 {
+				continue
+			}
+			collUsd := new(big.Int)
+			for _, c := range l.Collateral {
+				if v, ok := new(big.Int).SetString(c.Usd, 10); ok {
+					collUsd.Add(collUsd, v)
+				}
+			}
+			f, _ := new(big.Rat).SetFrac(collUsd, debtUsd).Float64()
+			pct := roundTo((f-1)*100, 2)
+			l.BonusPct, l.BonusSource = &pct, "usd_value"
+			l.Note = "The protocol absorbed the account; liquidators profit later by buying the collateral at a discount (buyCollateral)"
+		}
+	}
+}
+
+// handleLiquidations serves GET /api/mev/liquidations?block=<number|latest>
+func handleLiquidations(w http.ResponseWriter, r *http.Request) {
+	blockTag := r.URL.Query().Get("block")
+	if blockTag == "" {
+		blockTag = "latest"
+	}
+
+	b, err := fetchBlockFull(blockTag)
+	if err != nil {
+		writeErr(w, http.StatusInternalServerError, "EL_BLOCK_FETCH", "Failed to fetch block", "Check RPC_HTTP_URL and node sync state")
+		return
+	}
+	receipts, stats, err := scanBlockReceipts(b)
+	if err != nil {
+		writeErr(w, http.StatusInternalServerError, "EL_RECEIPTS", "Failed to scan receipts", "Node may still be syncing or pruning receipts")
+		return
+	}
+	liqs := liquidationsFromReceipts(b, receipts)
+	fillLiquidationBonuses(liqs)
+
+	byProtocol := map[string]int{}
+	for _, l := range liqs {
+		byProtocol[l.Protocol]++
+	}
+
+	writeOK(w, map[string]any{
+		"block":        b.Number,
+		"blockHash":    b.Hash,
+		"liquidations": liqs,
+		"byProtocol":   byProtocol,
+		"receipts":     stats,
+		"sources":      sourcesInfo(),
+		"note":         "Amounts are raw token units. Aave and Compound V2 bonuses are the configured protocol parameters; Compound V3's is collateral USD / debt USD from the events; Maker has none (collateral is auctioned).",
+	})
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// Liquidation logs laid out as mainnet emits them (real topic0s and contracts, round amounts)
+
+const (
+	aaveV3Pool = "0x87870bca3f3fd6335c3f4ce8392d69350b4fa4e2"
+	cUSDC      = "0x39aa39c021dfbae8fac545936693ac917d5e7563"
+	cETH       = "0x4ddc2d193948926d02f9b1fe9e1daa0718270ed5"
+	comptrol   = "0x3d9819210a31b4961b30ef54be2aed79b9c9cd3b"
+	cometUSDC  = "0xc3d688b66703497daa19211eedff47f25384cdc3"
+	makerDog   = "0x135954d155898d42c90d2a57824c690e0c7bef1b"
+	ethAClip   = "0xc67963a226eddd77b91ad8c421630a1b0adff270"
+	wstETH     = "0x7f39c581f595b53c5cb19bd0b3f8da6c935e2ca0"
+
+	liquidator = "0x00000000000000000000000000000000001f0001"
+	borrower   = "0x00000000000000000000000000000000000b0001"
+	keeper     = "0x00000000000000000000000000000000000ee001"
+)
+
+// testLog is a receipt log as the node returns it
+type testLog struct {
+	Address string   `json:"address"`
+	Topics  []string `json:"topics"`
+	Data    string   `json:"data"`
+}
+
+// addrWord ABI-encodes an address as a data word
+func addrWord(a string) string {
+	return strings.Repeat("0", 24) + strings.TrimPrefix(a, "0x")
+}
+
+// logsBlock builds a block with one transaction (sent by from) per log list, plus its receipts
+func logsBlock(t *testing.T, from string, txLogs ...[]testLog) (*block, []*receipt) {
+	t.Helper()
+	var txs []map[string]string
+	var receipts []*receipt
+	for i, logs := range txLogs {
+		hash := fmt.Sprintf("0x%064x", i+1)
+		txs = append(txs, map[string]string{"hash": hash, "from": from})
+		raw, _ := json.Marshal(map[string]any{"transactionHash": hash, "logs": logs})
+		var r receipt
+		if err := json.Unmarshal(raw, &r); err != nil {
+			t.Fatal(err)
+		}
+		receipts = append(receipts, &r)
+	}
+	raw, _ := json.Marshal(map[string]any{"number": "0x1", "hash": "0xb10c", "transactions": txs})
+	var b block
+	if err := json.Unmarshal(raw, &b); err != nil {
+		t.Fatal(err)
+	}
+	return &b, receipts
+}
+
+var (
+	aaveLiquidationLog = testLog{
+		Address: "0x87870Bca3F3fD6335C3F4ce8392D69350B4fA4E2",
+		Topics: []string{"0xe413a321e8681d831f4dbccbca790d2952b56f977908e45be37335533e005286",
+			topic(wethAddress), topic(usdc), topic(borrower)},
+		Data: logData(abiWord("1000000000"), abiWord("420000000000000000"), addrWord(liquidator), abiWord("0")),
+	}
+	compoundV2Log = testLog{
+		Address: "0x39AA39c021dfbaE8faC545936693aC917d5E7563",
+		Topics:  []string{"0x298637f684da70674f26509b10f07ec2fbc77a335ab1e7d6215a4b2484d8bb52"},
+		Data:    logData(addrWord(liquidator), addrWord(borrower), abiWord("500000000"), addrWord(cETH), abiWord("2700000000")),
+	}
+	// Comet emits one AbsorbCollateral per asset, then AbsorbDebt
+	cometLogs = []testLog{
+		{
+			Address: cometUSDC,
+			Topics:  []string{"0x9850ab1af75177e4a9201c65a2cf7976d5d28e40ef63494b44366f86b2f9412e", topic(liquidator), topic(borrower), topic(wethAddress)},
+			Data:    logData(abiWord("1000000000000000000"), abiWord("260000000000")),
+		},
+		{
+			Address: cometUSDC,
+			Topics:  []string{"0x9850ab1af75177e4a9201c65a2cf7976d5d28e40ef63494b44366f86b2f9412e", topic(liquidator), topic(borrower), topic(wstETH)},
+			Data:    logData(abiWord("500000000000000000"), abiWord("150000000000")),
+		},
+		{
+			Address: cometUSDC,
+			Topics:  []string{"0x1547a878dc89ad3c367b6338b4be6a65a5dd74fb77ae044da1e8747ef1f4f62f", topic(liquidator), topic(borrower)},
+			Data:    logData(abiWord("3800000000"), abiWord("380000000000")),
+		},
+	}
+	// ilk "ETH-A"; due is a rad (45 decimals): 13,200 DAI
+	makerBarkLog = testLog{
+		Address: makerDog,
+		Topics: []string{"0x85258d09e1e4ef299ff3fc11e74af99563f022d21f3f940db982229dc2a3358c",
+			"0x4554482d41000000000000000000000000000000000000000000000000000000", topic(borrower), "0x" + abiWord("6000")},
+		Data: logData(abiWord("10000000000000000000"), abiWord("12000000000000000000000"),
+			abiWord("13200000000000000000000"+strings.Repeat("0", 27)), addrWord(ethAClip)),
+	}
+)
+
+func TestLiquidationsFromReceipts(t *testing.T) {
+	b, receipts := logsBlock(t, keeper,
+		[]testLog{aaveLiquidationLog},
+		[]testLog{compoundV2Log},
+		cometLogs,
+		[]testLog{makerBarkLog},
+	)
+	liqs := liquidationsFromReceipts(b, receipts)
+	if len(liqs) != 4 {
+		t.Fatalf("got %d liquidations, want 4 (Comet absorbs merged): %+v", len(liqs), liqs)
+	}
+
+	type summary struct {
+		protocol, market, liquidator, borrower, debtAsset, debtRepaid, debtUsd, collateral string
+	}
+	sum := func(l *liquidation) summary {
+		return summary{l.Protocol, l.Market, l.Liquidator, l.Borrower, l.DebtAsset, l.DebtRepaid, l.DebtUsd, fmt.Sprint(l.Collateral)}
+	}
+	want := []summary{
+		{"aave_v3", aaveV3Pool, liquidator, borrower, usdc, "1000000000", "", fmt.Sprint([]liquidatedCollateral{{Asset: wethAddress, Amount: "420000000000000000"}})},
+		{"compound_v2", cUSDC, liquidator, borrower, cUSDC, "500000000", "", fmt.Sprint([]liquidatedCollateral{{Asset: cETH, Amount: "2700000000"}})},
+		{"compound_v3", cometUSDC, liquidator, borrower, "base", "3800000000", "380000000000", fmt.Sprint([]liquidatedCollateral{
+			{Asset: wethAddress, Amount: "1000000000000000000", Usd: "260000000000"},
+			{Asset: wstETH, Amount: "500000000000000000", Usd: "150000000000"},
+		})},
+		{"maker", makerDog, keeper, borrower, "DAI", "13200000000000000000000", "", fmt.Sprint([]liquidatedCollateral{{Asset: "ETH-A", Amount: "10000000000000000000"}})},
+	}
+	for i, l := range liqs {
+		if got := sum(l); got != want[i] {
+			t.Errorf("liquidation %d:\n got  %+v\n want %+v", i, got, want[i])
+		}
+	}
+	if liqs[2].TxIndex != 2 || liqs[2].LogIndex != 0 {
+		t.Errorf("merged absorb at tx %d log %d, want the first absorb (2, 0)", liqs[2].TxIndex, liqs[2].LogIndex)
+	}
+	if !strings.Contains(liqs[3].Note, ethAClip) {
+		t.Errorf("maker note doesn't name the clipper: %s", liqs[3].Note)
+	}
+
+	// Two borrowers absorbed in one tx stay apart
+	other := cometLogs[2]
+	other.Topics = []string{other.Topics[0], other.Topics[1], topic(keeper)}
+	b, receipts = logsBlock(t, keeper, append(append([]testLog{}, cometLogs...), other))
+	if liqs := liquidationsFromReceipts(b, receipts); len(liqs) != 2 {
+		t.Errorf("got %d Comet liquidations for two borrowers, want 2", len(liqs))
+	}
+
+	// Truncated data is skipped, not half-decoded
+	short := aaveLiquidationLog
+	short.Data = logData(abiWord("1000000000"))
+	b, receipts = logsBlock(t, keeper, []testLog{short})
+	if liqs := liquidationsFromReceipts(b, receipts); len(liqs) != 0 {
+		t.Errorf("truncated LiquidationCall decoded: %+v", liqs[0])
+	}
+}
+
+func TestFillLiquidationBonuses(t *testing.T) {
+	// Aave reserve configuration: LTV 80%, liquidation threshold 82.5%, bonus 10500 in bits 32-47
+	aaveConfig := fmt.Sprintf("0x%064x", uint64(10500)<<32|uint64(8250)<<16|8000)
+	stubRPC(t, func(method string, params []json.RawMessage) (any, *rpcError) {
+		to, data := ethCallTarget(params)
+		switch to + "|" + data {
+		case aaveV3Pool + "|" + selectorAaveGetConfiguration + addressArg(wethAddress):
+			return aaveConfig, nil
+		case cUSDC + "|" + selectorCTokenComptroller:
+			return "0x" + addrWord(comptrol), nil
+		case comptrol + "|" + selectorLiquidationIncentive:
+			return "0x" + abiWord("1080000000000000000"), nil // 1.08e18
+		case cometUSDC + "|" + selectorCometBaseToken:
+			return "0x" + addrWord(usdc), nil
+		}
+		return nil, &rpcError{Code: 3, Message: "execution reverted"}
+	})
+
+	b, receipts := logsBlock(t, keeper, []testLog{aaveLiquidationLog}, []testLog{compoundV2Log}, cometLogs, []testLog{makerBarkLog})
+	liqs := liquidationsFromReceipts(b, receipts)
+	fillLiquidationBonuses(liqs)
+
+	bonus := func(l *liquidation) string {
+		if l.BonusPct == nil {
+			return "none"
+		}
+		return fmt.Sprintf("%g %s", *l.BonusPct, l.BonusSource)
+	}
+	// Comet: (2,600 + 1,500) USD of collateral for 3,800 USD of debt
+	want := []string{"5 configured", "8 configured", "7.89 usd_value", "none"}
+	for i, l := range liqs {
+		if got := bonus(l); got != want[i] {
+			t.Errorf("%s bonus = %s, want %s", l.Protocol, got, want[i])
+		}
+	}
+	if liqs[2].DebtAsset != usdc {
+		t.Errorf("Comet debt asset = %s, want its base token %s", liqs[2].DebtAsset, usdc)
+	}
+}
+
+func TestLiquidationParamsCachesOnlyDefinitiveAnswers(t *testing.T) {
+	const (
+		ok       = "0x0000000000000000000000000000000000002001|0x01"
+		reverts  = "0x0000000000000000000000000000000000002002|0x01"
+		rateLim  = "0x0000000000000000000000000000000000002003|0x01"
+		okResult = "0x000000000000000000000000000000000000000000000000000000000000002a"
+	)
+	stubRPC(t, func(method string, params []json.RawMessage) (any, *rpcError) {
+		switch to, _ := ethCallTarget(params); to {
+		case "0x0000000000000000000000000000000000002001":
+			return okResult, nil
+		case "0x0000000000000000000000000000000000002002":
+			return nil, &rpcError{Code: -32000, Message: "execution reverted"}
+		}
+		return nil, &rpcError{Code: 429, Message: "Too Many Requests"}
+	})
+
+	got := liquidationParams([]string{ok, reverts, rateLim, ok})
+	if got[ok] != okResult || got[reverts] != "" || got[rateLim] != "" {
+		t.Errorf("results = %v", got)
+	}
+
+	liquidationParamMu.Lock()
+	_, okCached := liquidationParamCache[ok]
+	_, revertCached := liquidationParamCache[reverts]
+	_, limitedCached := liquidationParamCache[rateLim]
+	liquidationParamMu.Unlock()
+	if !okCached || !revertCached {
+		t.Errorf("definitive answers not cached: ok=%v revert=%v", okCached, revertCached)
+	}
+	if limitedCached {
+		t.Error("a rate-limited call was cached; it should be retried")
+	}
+}
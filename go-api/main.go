@@ -6,7 +6,6 @@ package main
 
 import (
 	"encoding/json"
-	"fmt"
 	"log"
 	"net/http"
 	"strconv"
@@ -46,36 +45,32 @@ func handleMempool(w http.ResponseWriter, r *http.Request) {
 // handleRelaysDelivered shows which blocks actually made it to proposers via MEV-Boost.
 // This is the "winning" block that gets proposed on-chain.
 func handleRelaysDelivered(w http.ResponseWriter, r *http.Request) {
-	// Parse limit from query string, default 10, clamp between 1-200
-	limit := 10
-	if s := r.URL.Query().Get("limit"); s != "" {
-		if n, err := strconv.Atoi(s); err == nil {
-			if n < 1 {
-				n = 1
-			}
-			if n > 200 {
-				n = 200
-			}
-			limit = n
-		}
+	// Filters: slot, block_number, block_hash, cursor; limit defaults to 10, clamped to 1-200
+	q, err := relayQueryFromRequest(r, 10)
+	if err != nil {
+		writeErr(w, http.StatusBadRequest, "BAD_REQUEST", err.Error(), "Try /api/relays/delivered?block_number=19000000 or ?slot=8500000")
+		return
 	}
 
-	// Hit the relay API for delivered payload data
-	raw, err := relayGET(fmt.Sprintf("/relay/v1/data/bidtraces/proposer_payload_delivered?limit=%d", limit))
+	// Ask every relay at once - each one only knows about the payloads it delivered itself
+	merged, err := relayFanoutRecords(q.path(relayDeliveredEndpoint))
 	if err != nil {
 		writeErr(w, http.StatusTooManyRequests, "RELAY", "Failed to fetch delivered payloads", "MEV relays may be rate limiting or unavailable")
 		return
 	}
 
-	var deliveredPayloads []map[string]any
-	if err := json.Unmarshal(raw, &deliveredPayloads); err != nil {
-		writeErr(w, http.StatusInternalServerError, "RELAY_PARSE", "Failed to parse delivered payloads", "")
-		return
-	}
+	// Never split a slot across pages, so the cursor below can skip past it
+	deliveredPayloads := relayPage(merged.traces(), q.Limit)
 
 	response := map[string]any{
 		"delivered_payloads": deliveredPayloads,
 		"count":              len(deliveredPayloads),
+		"relays":             merged.Relays,
+		"timed_out":          merged.TimedOut,
+	}
+	if !q.filtered() {
+		// Pass this back as ?cursor= to page further into the past
+		response["next_cursor"] = relayNextCursor(deliveredPayloads)
 	}
 	writeOK(w, response)
 }
@@ -83,42 +78,32 @@ func handleRelaysDelivered(w http.ResponseWriter, r *http.Request) {
 // handleRelaysReceived shows blocks submitted by builders to relays.
 // Most of these don't get selected - only the highest bid per slot wins.
 func handleRelaysReceived(w http.ResponseWriter, r *http.Request) {
-	// Same limit parsing logic as delivered endpoint
-	limit := 10
-	if s := r.URL.Query().Get("limit"); s != "" {
-		if n, err := strconv.Atoi(s); err == nil {
-			if n < 1 {
-				n = 1
-			}
-			if n > 200 {
-				n = 200
-			}
-			limit = n
-		}
-	}
-
-	raw, err := relayGET(fmt.Sprintf("/relay/v1/data/bidtraces/builder_blocks_received?limit=%d", limit))
+	// Same filters as the delivered endpoint (minus cursor, which relays only support there)
+	q, err := relayQueryFromRequest(r, 10)
 	if err != nil {
-		writeErr(w, http.StatusTooManyRequests, "RELAY", "Failed to fetch received blocks", "MEV relays may be rate limiting or unavailable")
+		writeErr(w, http.StatusBadRequest, "BAD_REQUEST", err.Error(), "Try /api/relays/received?slot=8500000")
 		return
 	}
+	q.Cursor = nil
 
-	var receivedBlocks []map[string]any
-	if err := json.Unmarshal(raw, &receivedBlocks); err != nil {
-		writeErr(w, http.StatusInternalServerError, "RELAY_PARSE", "Failed to parse received blocks", "")
+	receivedBlocks, rejected, err := relayQueryGET(relayReceivedEndpoint, q)
+	if err != nil {
+		writeErr(w, http.StatusTooManyRequests, "RELAY", "Failed to fetch received blocks", "MEV relays may be rate limiting or unavailable")
 		return
 	}
 
 	response := map[string]any{
 		"received_blocks": receivedBlocks,
 		"count":           len(receivedBlocks),
+		"rejected":        rejected,
 	}
 	writeOK(w, response)
 }
 
 // handleBeaconHeaders fetches recent proposed blocks from the consensus layer,
 // then enriches them with MEV payment data from relays. This shows validator
-// earnings and which builders are winning block auctions.
+// earnings and which builders are winning block auctions. Each head also gets a "source":
+// mev_boost, local, or unknown_relay_unavailable when we couldn't hear from every relay.
 func handleBeaconHeaders(w http.ResponseWriter, r *http.Request) {
 	// Grab beacon chain headers (these are proposed blocks)
 	headersRaw, status, err := beaconGET("/eth/v1/beacon/headers?limit=20")
@@ -127,10 +112,6 @@ func handleBeaconHeaders(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Also grab relay data so we can show builder payments
-	// We fetch more here (50) to increase chance of matching slots
-	relayRaw, relayErr := relayGET("/relay/v1/data/bidtraces/proposer_payload_delivered?limit=50")
-
 	// Parse beacon headers response
 	var headersObj struct {
 		Data []struct {
@@ -149,59 +130,87 @@ func handleBeaconHeaders(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Ask every relay which of these slots it delivered, and classify each block as MEV-Boost,
+	// local or unknown (see block_source.go)
+	slots := make([]uint64, 0, len(headersObj.Data))
+	for _, h := range headersObj.Data {
+		if n, err := strconv.ParseUint(h.Header.Message.Slot, 10, 64); err == nil {
+			slots = append(slots, n)
+		}
+	}
+	sources, merged := classifySlots(slots)
+
 	// Build a lookup map of relay bids by slot for fast matching
-	relayBids := make(map[string]map[string]any)
-	if relayErr == nil && relayRaw != nil {
-		var bids []map[string]any
-		if err := json.Unmarshal(relayRaw, &bids); err == nil {
-			for _, bid := range bids {
-				if slot, ok := bid["slot"].(string); ok {
-					relayBids[slot] = bid
-				}
+	relayBids := make(map[string]*BidTrace)
+	if merged != nil {
+		for _, bid := range merged.traces() {
+			if _, dup := relayBids[strconv.FormatUint(bid.Slot, 10)]; !dup {
+				relayBids[strconv.FormatUint(bid.Slot, 10)] = bid
 			}
 		}
 	}
 
 	// Merge beacon data with relay payment info
 	enriched := make([]map[string]any, 0, len(headersObj.Data))
+	var toVerify []*BidTrace
+	var verifyItems []map[string]any
 	for _, h := range headersObj.Data {
 		slot := h.Header.Message.Slot
 		item := map[string]any{
 			"slot":           slot,
 			"proposer_index": h.Header.Message.ProposerIndex,
 		}
+		if n, err := strconv.ParseUint(slot, 10, 64); err == nil && sources[n] != nil {
+			item["source"] = sources[n]
+		}
 
 		// If we have relay data for this slot, add it
 		if bid, found := relayBids[slot]; found {
-			item["builder_payment_eth"] = bid["value"]
-			item["block_number"] = bid["block_number"]
-			item["gas_used"] = bid["gas_used"]
-			item["gas_limit"] = bid["gas_limit"]
-			item["num_tx"] = bid["num_tx"]
-			item["builder_pubkey"] = bid["builder_pubkey"]
-			item["proposer_fee_recipient"] = bid["proposer_fee_recipient"]
+			item["builder_payment_wei"] = bid.Value.String()
+			item["builder_payment_eth"] = bid.ValueETH()
+			item["value_eth"] = bid.ValueETH()
+			item["block_number"] = strconv.FormatUint(bid.BlockNumber, 10)
+			item["gas_used"] = strconv.FormatUint(bid.GasUsed, 10)
+			item["gas_limit"] = strconv.FormatUint(bid.GasLimit, 10)
+			item["gas_utilization"] = roundTo(bid.GasUtilization(), 2)
+			item["num_tx"] = strconv.FormatUint(bid.NumTx, 10)
+			item["builder_pubkey"] = bid.BuilderPubkey
+			item["proposer_fee_recipient"] = bid.ProposerFeeRecipient
+			toVerify = append(toVerify, bid)
+			verifyItems = append(verifyItems, item)
 		}
 
 		enriched = append(enriched, item)
 	}
 
-	writeOK(w, map[string]any{
-		"headers": enriched,
-		"count":   len(enriched),
-	})
-}
+	resp := map[string]any{
+		"headers":        enriched,
+		"count":          len(enriched),
+		"source_summary": summarizeBlockSources(slots, sources),
+	}
 
-// handleFinality returns Casper-FFG checkpoints showing which epochs are finalized.
-// Once a block is finalized, it's basically impossible to reorg.
-func handleFinality(w http.ResponseWriter, r *http.Request) {
-	raw, status, err := beaconGET("/eth/v1/beacon/states/finalized/finality_checkpoints")
-	if err != nil || status/100 != 2 {
-		writeErr(w, http.StatusTooManyRequests, "BEACON", "Finality checkpoints fetch failed", "Public beacon API may be rate limiting. Try again or configure BEACON_API_URL to a local consensus client.")
-		return
+	// ?verify=1 checks each claimed payment against the execution block (see payment_verify.go)
+	if v := r.URL.Query().Get("verify"); v == "1" || v == "true" {
+		summary := map[string]int{}
+		for i, res := range verifyBidPayments(toVerify) {
+			if res == nil {
+				summary["error"]++
+				continue
+			}
+			verifyItems[i]["payment_verification"] = res
+			summary[res.Status]++
+		}
+		resp["payment_verification"] = summary
 	}
-	// Just pass through the beacon API response directly
-	w.Header().Set("content-type", "application/json")
-	_, _ = w.Write(raw)
+
+	writeOK(w, resp)
+}
+
+// handleBeaconState shows what the beacon event stream has told us (head, finality, last reorg)
+func handleBeaconState(w http.ResponseWriter, r *http.Request) {
+	state := beaconState.snapshot()
+	state["live"] = beaconState.live()
+	writeOK(w, state)
 }
 
 // handleBlock grabs a full block with all transactions from the execution layer.
@@ -249,6 +258,12 @@ func main() {
 	// Kick off mempool monitoring in background
 	startMempoolSubscription()
 
+	// Relay and finality feeds for the live event stream
+	startStreamPollers()
+
+	// Follow head/finality/reorgs from the beacon node's event stream
+	startBeaconEvents()
+
 	// Set up all our routes
 	mux := http.NewServeMux()
 
@@ -256,12 +271,25 @@ func main() {
 	mux.HandleFunc("/api/mempool", handleMempool)
 	mux.HandleFunc("/api/relays/delivered", handleRelaysDelivered)
 	mux.HandleFunc("/api/relays/received", handleRelaysReceived)
+	mux.HandleFunc("/api/relays/verify-payment/", handleVerifyPayment) // claimed vs on-chain proposer payment
+	mux.HandleFunc("/api/auction/", handleAuction)                     // every builder bid for one slot, on a timeline
+	mux.HandleFunc("/api/blocks/sources", handleBlockSources)          // MEV-Boost vs locally built, last N slots
+	mux.HandleFunc("/api/builders/leaderboard", handleLeaderboard)     // builder/relay market share over rolling windows
 	mux.HandleFunc("/api/validators/head", handleBeaconHeaders)
+	mux.HandleFunc("/api/validator/", handleValidatorProfile)       // status, balance, duties and rewards for one validator
+	mux.HandleFunc("/api/validators/", handleValidator)             // relay registrations for one validator
+	mux.HandleFunc("/api/relays/validators", handleRelayValidators) // proposers registered for this and next epoch
 	mux.HandleFunc("/api/finality", handleFinality)
-	mux.HandleFunc("/api/snapshot", handleSnapshot) // batch endpoint for efficiency
+	mux.HandleFunc("/api/beacon/state", handleBeaconState)                        // head/finality as seen by the event stream
+	mux.HandleFunc("/api/clock", handleClock)                                     // slot/epoch clock from genesis + spec
+	mux.HandleFunc("/api/beacon/execution-block/", handleBeaconBlockForExecution) // which beacon block carries an EL block
+	mux.HandleFunc("/api/snapshot", handleSnapshot)                               // batch endpoint for efficiency
 	mux.HandleFunc("/api/block/", handleBlock)
 	mux.HandleFunc("/api/mev/sandwich", handleSandwich)
-	mux.HandleFunc("/api/track/tx/", handleTrackTx) // follow a tx through its lifecycle
+	mux.HandleFunc("/api/mev/arbitrage", handleArbitrage)       // single-tx cyclic arbitrage
+	mux.HandleFunc("/api/mev/liquidations", handleLiquidations) // Aave, Compound and Maker liquidations
+	mux.HandleFunc("/api/track/tx/", handleTrackTx)             // follow a tx through its lifecycle
+	mux.HandleFunc("/api/stream", handleStream)                 // live Server-Sent Events feed
 
 	// Health check endpoints
 	mux.HandleFunc("/api/health", handleHealth)                // Detailed health status
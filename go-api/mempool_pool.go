@@ -0,0 +1,207 @@
+// mempool_pool.go
+// An in-memory store of the pending transactions we've seen, keyed by hash.
+// Every transaction remembers when we first and last saw it, so we can show how long it has
+// been waiting. Transactions leave the pool when they show up in a mined block, when we haven't
+// seen them for MEMPOOL_TTL_SECONDS, or when the pool grows past MEMPOOL_MAX_TXS (least recently
+// seen go first). Eviction runs on the summary refresh tick, not on every sighting.
+package main
+
+import (
+	"math/big"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// poolEntry is one pending transaction plus our bookkeeping about it
+type poolEntry struct {
+	tx        PendingTx
+	firstSeen time.Time
+	lastSeen  time.Time
+}
+
+// mempoolPool is the bounded pending-tx store
+type mempoolPool struct {
+	mu      sync.RWMutex
+	entries map[string]*poolEntry
+	ttl     time.Duration
+	maxSize int
+}
+
+// txPool is the process-wide mempool store fed by the WebSocket/HTTP monitors
+var txPool = newMempoolPool(
+	func() time.Duration {
+		if s := os.Getenv("MEMPOOL_TTL_SECONDS"); s != "" {
+			if n, err := strconv.Atoi(s); err == nil && n > 0 && n <= 86400 {
+				return time.Duration(n) * time.Second
+			}
+		}
+		return 10 * time.Minute
+	}(),
+	func() int {
+		if s := os.Getenv("MEMPOOL_MAX_TXS"); s != "" {
+			if n, err := strconv.Atoi(s); err == nil && n > 0 && n <= 200000 {
+				return n
+			}
+		}
+		return 5000
+	}(),
+)
+
+// newMempoolPool creates an empty pool with the given eviction limits
+func newMempoolPool(ttl time.Duration, maxSize int) *mempoolPool {
+	return &mempoolPool{
+		entries: map[string]*poolEntry{},
+		ttl:     ttl,
+		maxSize: maxSize,
+	}
+}
+
+// upsert records sightings of txs at time now and returns the ones that are new to the pool
+func (p *mempoolPool) upsert(txs []PendingTx, now time.Time) []PendingTx {
+	var added []PendingTx
+	p.mu.Lock()
+	for _, tx := range txs {
+		key := strings.ToLower(tx.Hash)
+		if key == "" {
+			continue
+		}
+		if e, ok := p.entries[key]; ok {
+			e.lastSeen = now
+			continue
+		}
+		p.entries[key] = &poolEntry{tx: tx, firstSeen: now, lastSeen: now}
+		added = append(added, tx)
+	}
+	p.mu.Unlock()
+	return added
+}
+
+// removeIncluded drops txs that made it into a block and returns the ones we were tracking
+func (p *mempoolPool) removeIncluded(hashes []string) []PendingTx {
+	var removed []PendingTx
+	p.mu.Lock()
+	for _, h := range hashes {
+		key := strings.ToLower(h)
+		if e, ok := p.entries[key]; ok {
+			removed = append(removed, e.view(time.Now()))
+			delete(p.entries, key)
+		}
+	}
+	p.mu.Unlock()
+	return removed
+}
+
+// evict removes stale entries (not seen within the TTL) and trims the pool to maxSize,
+// dropping the least recently seen first. Returns how many entries were removed.
+func (p *mempoolPool) evict(now time.Time) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	removed := 0
+	for k, e := range p.entries {
+		if now.Sub(e.lastSeen) > p.ttl {
+			delete(p.entries, k)
+			removed++
+		}
+	}
+
+	if over := len(p.entries) - p.maxSize; over > 0 {
+		keys := make([]string, 0, len(p.entries))
+		for k := range p.entries {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return p.entries[keys[i]].lastSeen.Before(p.entries[keys[j]].lastSeen)
+		})
+		for _, k := range keys[:over] {
+			delete(p.entries, k)
+			removed++
+		}
+	}
+	return removed
+}
+
+// size returns how many txs are currently in the pool
+func (p *mempoolPool) size() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(p.entries)
+}
+
+// view copies the tx and fills in the first-seen / time-in-pool fields
+func (e *poolEntry) view(now time.Time) PendingTx {
+	tx := e.tx
+	tx.Timestamp = e.firstSeen.Unix()
+	tx.FirstSeen = e.firstSeen.Unix()
+	tx.LastSeen = e.lastSeen.Unix()
+	tx.TimeInPool = int64(now.Sub(e.firstSeen).Seconds())
+	return tx
+}
+
+// list returns a sorted page of the pool plus the total number of txs.
+// sortBy is "newest" (default), "oldest" (longest waiting first) or "fee" (highest fee first).
+func (p *mempoolPool) list(sortBy string, offset, limit int) ([]PendingTx, int) {
+	now := time.Now()
+	p.mu.RLock()
+	all := make([]PendingTx, 0, len(p.entries))
+	for _, e := range p.entries {
+		all = append(all, e.view(now))
+	}
+	p.mu.RUnlock()
+
+	switch sortBy {
+	case "oldest":
+		sort.Slice(all, func(i, j int) bool {
+			if all[i].FirstSeen == all[j].FirstSeen {
+				return all[i].Hash < all[j].Hash
+			}
+			return all[i].FirstSeen < all[j].FirstSeen
+		})
+	case "fee":
+		// Rank by what the proposer would earn right now, like a block builder would
+		baseFee := currentBaseFee()
+		fees := make(map[string]*big.Int, len(all))
+		for _, tx := range all {
+			fees[tx.Hash] = effectiveTip(tx, baseFee)
+		}
+		sort.Slice(all, func(i, j int) bool {
+			if c := fees[all[i].Hash].Cmp(fees[all[j].Hash]); c != 0 {
+				return c > 0
+			}
+			return all[i].Hash < all[j].Hash
+		})
+	default:
+		sort.Slice(all, func(i, j int) bool {
+			if all[i].FirstSeen == all[j].FirstSeen {
+				return all[i].Hash < all[j].Hash
+			}
+			return all[i].FirstSeen > all[j].FirstSeen
+		})
+	}
+
+	total := len(all)
+	if offset >= total {
+		return []PendingTx{}, total
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+	return all[offset:end], total
+}
+
+// all returns every tx in the pool (unsorted) for metrics
+func (p *mempoolPool) all() []PendingTx {
+	now := time.Now()
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	out := make([]PendingTx, 0, len(p.entries))
+	for _, e := range p.entries {
+		out = append(out, e.view(now))
+	}
+	return out
+}
@@ -1,15 +1,19 @@
 // mempool_ws.go
-// Monitors pending transactions using HTTP polling.
-// We use eth_getBlockByNumber("pending") which works with all RPC providers,
-// unlike WebSocket subscriptions which are not supported by most public providers.
+// Monitors pending transactions.
+// When RPC_WS_URL is set we subscribe to newPendingTransactions and newHeads over WebSocket,
+// which shows transactions the moment our node hears about them. If the node rejects the
+// subscription (many public providers do), or no WebSocket URL is configured, we fall back to
+// polling eth_getBlockByNumber("pending") over HTTP, which works with all RPC providers.
 package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"math/big"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -26,23 +30,58 @@ type PendingTx struct {
 	Gas       *string `json:"gas"`       // gas limit
 	Nonce     string  `json:"nonce"`     // sender's transaction count
 	Input     string  `json:"input"`     // calldata
-	Timestamp int64   `json:"timestamp"` // when we saw it
+	Timestamp int64   `json:"timestamp"` // when we first saw it
+
+	// Typed transaction fields (see tx_types.go) - only present for the types that use them
+	Type                 *string                `json:"type,omitempty"`                 // 0x0 legacy .. 0x4 set-code
+	MaxFeePerGas         *string                `json:"maxFeePerGas,omitempty"`         // EIP-1559 fee cap
+	MaxPriorityFeePerGas *string                `json:"maxPriorityFeePerGas,omitempty"` // EIP-1559 tip cap
+	MaxFeePerBlobGas     *string                `json:"maxFeePerBlobGas,omitempty"`     // EIP-4844 blob fee cap
+	BlobVersionedHashes  []string               `json:"blobVersionedHashes,omitempty"`  // EIP-4844 blob commitments
+	AccessList           []accessListEntry      `json:"accessList,omitempty"`           // EIP-2930
+	AuthorizationList    []setCodeAuthorization `json:"authorizationList,omitempty"`    // EIP-7702
+
+	// Filled in by the mempool pool, not the node
+	FirstSeen  int64 `json:"firstSeen,omitempty"`  // unix time we first saw this tx
+	LastSeen   int64 `json:"lastSeen,omitempty"`   // unix time we most recently saw it pending
+	TimeInPool int64 `json:"timeInPool,omitempty"` // seconds since first seen
 }
 
-// MempoolMetrics provides aggregated stats about pending transactions
+// MempoolMetrics provides aggregated stats about pending transactions.
+// Fee figures are measured against the latest base fee, so type-2+ txs count what they'd
+// actually pay rather than their fee cap.
 type MempoolMetrics struct {
-	TotalGasRequested uint64  `json:"totalGasRequested"` // sum of gas limits
-	TotalValueWei     string  `json:"totalValueWei"`     // sum of transaction values (hex)
-	AvgGasPrice       float64 `json:"avgGasPrice"`       // average gas price in gwei
-	HighPriorityCount int     `json:"highPriorityCount"` // txs paying >50 gwei
+	TotalGasRequested      uint64             `json:"totalGasRequested"`      // sum of gas limits
+	TotalValueWei          string             `json:"totalValueWei"`          // sum of transaction values (hex)
+	AvgGasPrice            float64            `json:"avgGasPrice"`            // average effective gas price in gwei
+	HighPriorityCount      int                `json:"highPriorityCount"`      // txs tipping at least highPriorityTipGwei
+	HighPriorityTipGwei    float64            `json:"highPriorityTipGwei"`    // the tip threshold behind highPriorityCount
+	BaseFeeGwei            float64            `json:"baseFeeGwei"`            // base fee the figures were computed against
+	AvgEffectiveTip        float64            `json:"avgEffectiveTip"`        // average tip the proposer would earn, gwei
+	PriorityFeePercentiles map[string]float64 `json:"priorityFeePercentiles"` // p10..p90 of effective tips, gwei
+	UnderpricedCount       int                `json:"underpricedCount"`       // txs whose fee cap is below the base fee
+	TypeBreakdown          map[string]int     `json:"typeBreakdown"`          // legacy / accessList / dynamicFee / blob / setCode
+	BlobCount              int                `json:"blobCount"`              // total blobs carried by type-3 txs
 }
 
+// highPriorityTipGwei is the effective tip (not gas price) above which we call a tx high priority
+var highPriorityTipGwei = func() float64 {
+	if s := envOr("MEMPOOL_HIGH_TIP_GWEI", ""); s != "" {
+		if f, err := strconv.ParseFloat(s, 64); err == nil && f > 0 {
+			return f
+		}
+	}
+	return 2
+}()
+
 // MempoolData holds our current snapshot of pending transactions
 type MempoolData struct {
 	PendingTxs []PendingTx     `json:"pendingTxs"`
-	Count      int             `json:"count"`
+	Count      int             `json:"count"` // txs in PendingTxs
+	Total      int             `json:"total"` // txs in the whole pool
 	LastUpdate int64           `json:"lastUpdate"`
-	Source     string          `json:"source"` // "ws", "http-polling", etc
+	Source     string          `json:"source"`                  // "ws", "http-polling", etc
+	BaseFee    *string         `json:"baseFeePerGas,omitempty"` // latest block's base fee (hex wei)
 	Metrics    *MempoolMetrics `json:"metrics,omitempty"`
 }
 
@@ -51,12 +90,52 @@ var (
 	mempoolMutex sync.RWMutex // protects mempoolData from concurrent access
 )
 
-// handleMempoolWS returns current mempool snapshot to the HTTP client
-func handleMempoolWS(w http.ResponseWriter, _ *http.Request) {
-	mempoolMutex.RLock()
-	data := mempoolData
-	mempoolMutex.RUnlock()
-	writeOK(w, data)
+// handleMempoolWS returns a page of the mempool pool to the HTTP client.
+// Query params: limit (1-500, default 10), offset (default 0) and
+// sort = newest (default) | oldest (longest waiting first) | fee (highest fee first).
+func handleMempoolWS(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit := 10
+	if s := q.Get("limit"); s != "" {
+		if n, err := strconv.Atoi(s); err == nil {
+			if n < 1 {
+				n = 1
+			}
+			if n > 500 {
+				n = 500
+			}
+			limit = n
+		}
+	}
+	offset := 0
+	if s := q.Get("offset"); s != "" {
+		if n, err := strconv.Atoi(s); err == nil && n > 0 {
+			offset = n
+		}
+	}
+	sortBy := q.Get("sort")
+	switch sortBy {
+	case "", "newest", "oldest", "fee":
+	default:
+		writeErr(w, http.StatusBadRequest, "BAD_REQUEST", "Unknown sort order", "Use sort=newest, sort=oldest or sort=fee")
+		return
+	}
+
+	data := GetMempoolData()
+	data.PendingTxs, data.Total = txPool.list(sortBy, offset, limit)
+	data.Count = len(data.PendingTxs)
+	writeOK(w, map[string]any{
+		"pendingTxs":    data.PendingTxs,
+		"count":         data.Count,
+		"total":         data.Total,
+		"offset":        offset,
+		"limit":         limit,
+		"sort":          firstNonEmpty(sortBy, "newest"),
+		"lastUpdate":    data.LastUpdate,
+		"source":        data.Source,
+		"baseFeePerGas": data.BaseFee,
+		"metrics":       data.Metrics,
+	})
 }
 
 // GetMempoolData lets other parts of the code grab mempool state safely
@@ -68,49 +147,431 @@ func GetMempoolData() MempoolData {
 }
 
 // startMempoolSubscription kicks off our mempool monitoring.
-// We use HTTP polling instead of WebSocket because most public RPC providers
-// don't support the eth_subscribe("newPendingTransactions") method.
+// With RPC_WS_URL set we subscribe over WebSocket; without it, or if the node rejects
+// eth_subscribe("newPendingTransactions") as many public providers do, we poll over HTTP.
 func startMempoolSubscription() {
 	// Check if user explicitly disabled mempool monitoring
 	if d := strings.ToLower(envOr("MEMPOOL_DISABLE", "")); d == "1" || d == "true" || d == "yes" || d == "on" {
 		log.Println("mempool WS: disabled via MEMPOOL_DISABLE env")
-		mempoolMutex.Lock()
-		mempoolData.Source = "ws-disabled"
 		// Generate some fake data for demo purposes
-		mempoolData.Count = 10
-		mempoolData.LastUpdate = time.Now().Unix()
-		mockTxs := make([]PendingTx, 10)
+		now := time.Now()
 		for i := range 10 {
-			mockTxs[i] = PendingTx{
-				Hash:      fmt.Sprintf("0x%064x", i+1),
-				From:      fmt.Sprintf("0x%040x", i*1000),
-				Value:     fmt.Sprintf("0x%x", (i+1)*1e18),
-				Timestamp: time.Now().Unix() - int64(i*10),
+			mock := PendingTx{
+				Hash:  fmt.Sprintf("0x%064x", i+1),
+				From:  fmt.Sprintf("0x%040x", i*1000),
+				Value: fmt.Sprintf("0x%x", (i+1)*1e18),
 			}
 			to := fmt.Sprintf("0x%040x", i*2000)
-			mockTxs[i].To = &to
+			mock.To = &to
+			txPool.upsert([]PendingTx{mock}, now.Add(-time.Duration(i*10)*time.Second))
 		}
-		mempoolData.PendingTxs = mockTxs
-		mempoolMutex.Unlock()
+		refreshMempoolData("ws-disabled")
+		return
+	}
+
+	// Expire stale txs even when no new ones are arriving, and keep the summary current
+	go runMempoolJanitor()
+	go runMempoolRefresher()
+
+	// Prefer a real subscription when we have a WebSocket endpoint
+	if rpcWS != "" {
+		log.Println("mempool: subscribing to pending transactions over WebSocket")
+		go startWSSubscription()
 		return
 	}
 
-	// Use HTTP polling as our primary approach
-	// WebSocket would be nicer but doesn't work reliably with Infura/Alchemy
+	// No WebSocket endpoint - HTTP polling works everywhere
 	log.Println("mempool: starting HTTP polling for pending transactions")
 	go startHTTPPolling()
 }
 
-// calculateMempoolMetrics computes aggregate stats from pending transactions
-func calculateMempoolMetrics(txs []PendingTx) *MempoolMetrics {
+// mempoolDisplayMax is how many of the newest pending txs GetMempoolData carries for display
+const mempoolDisplayMax = 10
+
+// wsLookupMax caps how many hash-only notifications we resolve per second
+const wsLookupMax = 200
+
+// errSubscriptionRejected means the node doesn't let us eth_subscribe to pending txs at all
+var errSubscriptionRejected = errors.New("pending transaction subscription rejected")
+
+// startWSSubscription keeps a WebSocket subscription alive, reconnecting with exponential backoff.
+// If the node flat out rejects the subscription we give up on WebSocket and poll over HTTP instead.
+func startWSSubscription() {
+	backoff := time.Second
+	for {
+		started := time.Now()
+		err := runWSSubscription()
+		if errors.Is(err, errSubscriptionRejected) {
+			log.Printf("mempool WS: %v - falling back to HTTP polling\n", err)
+			startHTTPPolling()
+			return
+		}
+
+		log.Printf("mempool WS: connection lost: %v (reconnecting in %s)\n", err, backoff)
+		if mempoolHealth != nil && err != nil {
+			mempoolHealth.SetError(err)
+		}
+
+		// A connection that stayed up for a while earns a fresh backoff
+		if time.Since(started) > time.Minute {
+			backoff = time.Second
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > time.Minute {
+			backoff = time.Minute
+		}
+	}
+}
+
+// runWSSubscription runs one WebSocket session until the connection drops.
+// We first ask for full transaction objects (geth-style "newPendingTransactions", true); nodes
+// that don't support that get the hash-only variant and we look the txs up over HTTP in batches.
+func runWSSubscription() error {
+	client, err := dialWSRPC(rpcWS)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	fullTxs := true
+	pendingCh, err := client.subscribe("newPendingTransactions", true)
+	if err != nil {
+		var re *rpcError
+		if !errors.As(err, &re) {
+			return err // Transport problem, not a rejection - reconnect
+		}
+		fullTxs = false
+		pendingCh, err = client.subscribe("newPendingTransactions")
+		if err != nil {
+			if errors.As(err, &re) {
+				return fmt.Errorf("%w: %v", errSubscriptionRejected, err)
+			}
+			return err
+		}
+	}
+
+	// newHeads is a nice-to-have (fresh base fee); carry on without it if it's rejected
+	headsCh, err := client.subscribe("newHeads")
+	if err != nil {
+		log.Printf("mempool WS: newHeads subscription failed: %v\n", err)
+	}
+
+	source := "ws"
+	if !fullTxs {
+		source = "ws-hashes"
+	}
+	log.Printf("mempool WS: subscribed (%s)\n", source)
+
+	// Hash-only notifications are collected and resolved once a second in a single batch
+	var hashes []string
+	flush := time.NewTicker(time.Second)
+	defer flush.Stop()
+
+	// Mined blocks are fetched off the select loop so a slow node doesn't stall the notifications
+	done := make(chan struct{})
+	defer close(done)
+	heads := newHeadFetcher(source, done)
+
+	for {
+		select {
+		case msg, ok := <-pendingCh:
+			if !ok {
+				return fmt.Errorf("subscription closed: %w", wsClientErr(client))
+			}
+			if fullTxs {
+				var tx PendingTx
+				if json.Unmarshal(msg, &tx) == nil && tx.Hash != "" {
+					recordPendingTxs([]PendingTx{tx}, source)
+				}
+				continue
+			}
+			var h string
+			if json.Unmarshal(msg, &h) == nil && h != "" {
+				hashes = append(hashes, h)
+			}
+
+		case msg, ok := <-headsCh:
+			if !ok {
+				headsCh = nil // stop selecting on it; pendingCh will report the disconnect
+				continue
+			}
+			var head struct {
+				Hash          string  `json:"hash"`
+				BaseFeePerGas *string `json:"baseFeePerGas"`
+			}
+			if json.Unmarshal(msg, &head) != nil {
+				continue
+			}
+			if head.BaseFeePerGas != nil {
+				mempoolMutex.Lock()
+				mempoolData.BaseFee = head.BaseFeePerGas
+				mempoolMutex.Unlock()
+			}
+			// Anything in the new block is no longer pending
+			heads.push(head.Hash)
+
+		case <-flush.C:
+			if len(hashes) == 0 {
+				continue
+			}
+			// Busy mempools announce thousands of hashes; only resolve the newest batch
+			if len(hashes) > wsLookupMax {
+				hashes = hashes[len(hashes)-wsLookupMax:]
+			}
+			recordPendingTxs(lookupPendingTxs(hashes), source)
+			hashes = hashes[:0]
+
+		case <-client.Done():
+			return wsClientErr(client)
+		}
+	}
+}
+
+// headFetcher fetches mined blocks in order on its own goroutine. Heads that arrive while a fetch
+// is in flight are queued, never dropped: a skipped block would leave its txs in the pool until
+// they expire.
+type headFetcher struct {
+	mu    sync.Mutex
+	queue []string
+	wake  chan struct{}
+}
+
+// newHeadFetcher starts a fetcher that runs until done is closed
+func newHeadFetcher(source string, done <-chan struct{}) *headFetcher {
+	f := &headFetcher{wake: make(chan struct{}, 1)}
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-f.wake:
+			}
+			f.mu.Lock()
+			batch := f.queue
+			f.queue = nil
+			f.mu.Unlock()
+			for _, hash := range batch {
+				if raw, err := rpcCall("eth_getBlockByHash", []any{hash, false}); err == nil {
+					processMinedBlock(raw, source)
+				}
+			}
+		}
+	}()
+	return f
+}
+
+// push queues a head for fetching
+func (f *headFetcher) push(hash string) {
+	f.mu.Lock()
+	f.queue = append(f.queue, hash)
+	f.mu.Unlock()
+	select {
+	case f.wake <- struct{}{}:
+	default: // already woken; the fetcher picks this up with the rest of the queue
+	}
+}
+
+// wsClientErr reports why a WebSocket client's read loop stopped
+func wsClientErr(c *wsRPCClient) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	return errors.New("websocket closed")
+}
+
+// lookupPendingTxs resolves tx hashes to full transactions with one batched HTTP call
+func lookupPendingTxs(hashes []string) []PendingTx {
+	calls := make([]rpcBatchRequest, len(hashes))
+	for i, h := range hashes {
+		calls[i] = rpcBatchRequest{Method: "eth_getTransactionByHash", Params: []any{h}}
+	}
+	results, _ := rpcBatchCall(calls)
+
+	txs := make([]PendingTx, 0, len(hashes))
+	for _, res := range results {
+		if res.Err != nil || string(res.Result) == "null" {
+			continue // Already mined or dropped - nothing to show
+		}
+		var tx PendingTx
+		if json.Unmarshal(res.Result, &tx) == nil && tx.Hash != "" {
+			txs = append(txs, tx)
+		}
+	}
+	return txs
+}
+
+// recordPendingTxs adds sightings of pending txs to the pool. The summary is rebuilt by
+// runMempoolRefresher rather than on every notification, which would sort the whole pool each time.
+func recordPendingTxs(txs []PendingTx, source string) {
+	if len(txs) == 0 {
+		return
+	}
+	added := txPool.upsert(txs, time.Now())
+	markMempoolDirty(source)
+	// Published even with nobody listening, so a client that reconnects can replay what it missed
+	for _, tx := range added {
+		streamHub.publish("pending_tx", tx)
+	}
+
+	if mempoolHealth != nil {
+		mempoolHealth.SetSuccess()
+	}
+}
+
+// lastHeadHash remembers the newest block we've announced so each head is streamed once
+var (
+	lastHeadMu   sync.Mutex
+	lastHeadHash string
+)
+
+// processMinedBlock takes a block (eth_getBlockBy*, with or without full txs), announces it as a
+// new head and drops every tx in it from the pool
+func processMinedBlock(rawBlock json.RawMessage, source string) {
+	var b struct {
+		Number        string            `json:"number"`
+		Hash          string            `json:"hash"`
+		Timestamp     string            `json:"timestamp"`
+		BaseFeePerGas *string           `json:"baseFeePerGas"`
+		GasUsed       string            `json:"gasUsed"`
+		GasLimit      string            `json:"gasLimit"`
+		Transactions  []json.RawMessage `json:"transactions"`
+	}
+	if json.Unmarshal(rawBlock, &b) != nil || b.Hash == "" {
+		return
+	}
+
+	lastHeadMu.Lock()
+	isNew := b.Hash != lastHeadHash
+	lastHeadHash = b.Hash
+	lastHeadMu.Unlock()
+	if !isNew {
+		return // Already processed this block on an earlier tick
+	}
+	streamHub.publish("new_head", map[string]any{
+		"number":        b.Number,
+		"hash":          b.Hash,
+		"timestamp":     b.Timestamp,
+		"baseFeePerGas": b.BaseFeePerGas,
+		"gasUsed":       b.GasUsed,
+		"gasLimit":      b.GasLimit,
+		"txCount":       len(b.Transactions),
+	})
+
+	hashes := make([]string, 0, len(b.Transactions))
+	for _, raw := range b.Transactions {
+		// Hash-only blocks list strings, full blocks list objects
+		var h string
+		if json.Unmarshal(raw, &h) == nil {
+			hashes = append(hashes, h)
+			continue
+		}
+		var obj struct {
+			Hash string `json:"hash"`
+		}
+		if json.Unmarshal(raw, &obj) == nil {
+			hashes = append(hashes, obj.Hash)
+		}
+	}
+
+	if removed := txPool.removeIncluded(hashes); len(removed) > 0 {
+		for _, tx := range removed {
+			streamHub.publish("tx_included", map[string]any{
+				"hash":        tx.Hash,
+				"blockNumber": b.Number,
+				"blockHash":   b.Hash,
+				"firstSeen":   tx.FirstSeen,
+				"timeInPool":  tx.TimeInPool,
+			})
+		}
+		markMempoolDirty(source)
+	}
+}
+
+// The pool changed since the summary was last rebuilt (and by which source)
+var (
+	mempoolDirtyMu     sync.Mutex
+	mempoolDirty       bool
+	mempoolDirtySource string
+)
+
+// markMempoolDirty schedules a summary rebuild for the next refresher tick
+func markMempoolDirty(source string) {
+	mempoolDirtyMu.Lock()
+	mempoolDirty = true
+	mempoolDirtySource = source
+	mempoolDirtyMu.Unlock()
+}
+
+// flushMempoolSummary enforces the pool's size limit and rebuilds the summary if the pool changed
+// since the last rebuild (quiet pools are still aged out by runMempoolJanitor)
+func flushMempoolSummary() {
+	mempoolDirtyMu.Lock()
+	dirty, source := mempoolDirty, mempoolDirtySource
+	mempoolDirty = false
+	mempoolDirtyMu.Unlock()
+	if dirty {
+		txPool.evict(time.Now())
+		refreshMempoolData(source)
+	}
+}
+
+// runMempoolRefresher trims and summarizes the pool at most once a second
+func runMempoolRefresher() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		flushMempoolSummary()
+	}
+}
+
+// refreshMempoolData rebuilds the shared summary (newest txs, totals, metrics) from the pool
+func refreshMempoolData(source string) {
+	newest, total := txPool.list("newest", 0, mempoolDisplayMax)
+	metrics := calculateMempoolMetrics(txPool.all(), currentBaseFee())
+
+	mempoolMutex.Lock()
+	mempoolData.PendingTxs = newest
+	mempoolData.Count = len(newest)
+	mempoolData.Total = total
+	mempoolData.LastUpdate = time.Now().Unix()
+	mempoolData.Source = source
+	mempoolData.Metrics = metrics
+	mempoolMutex.Unlock()
+}
+
+// runMempoolJanitor periodically evicts txs we haven't seen within the TTL
+func runMempoolJanitor() {
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		if n := txPool.evict(time.Now()); n > 0 {
+			mempoolMutex.RLock()
+			source := mempoolData.Source
+			mempoolMutex.RUnlock()
+			refreshMempoolData(source)
+		}
+	}
+}
+
+// calculateMempoolMetrics computes aggregate stats from pending transactions.
+// baseFee may be nil if we haven't seen a block header yet; tips then fall back to what was bid.
+func calculateMempoolMetrics(txs []PendingTx, baseFee *big.Int) *MempoolMetrics {
 	if len(txs) == 0 {
 		return nil
 	}
 
-	metrics := &MempoolMetrics{}
-	var totalGasPrice uint64
-	var gasPriceCount int
+	metrics := &MempoolMetrics{
+		BaseFeeGwei:         weiToGwei(baseFee),
+		HighPriorityTipGwei: highPriorityTipGwei,
+		TypeBreakdown:       map[string]int{},
+	}
 	totalValue := big.NewInt(0)
+	totalGasPrice := big.NewInt(0)
+	totalTip := big.NewInt(0)
+	tips := make([]float64, 0, len(txs))
 
 	for _, tx := range txs {
 		// Sum gas requested
@@ -127,37 +588,61 @@ func calculateMempoolMetrics(txs []PendingTx) *MempoolMetrics {
 			}
 		}
 
-		// Calculate average gas price and count high priority
-		var gasPrice uint64
-		if tx.GasPrice != nil && *tx.GasPrice != "" {
-			if gp, err := strconv.ParseUint(strings.TrimPrefix(*tx.GasPrice, "0x"), 16, 64); err == nil {
-				gasPrice = gp
-			}
+		metrics.TypeBreakdown[txTypeName(tx)]++
+		metrics.BlobCount += len(tx.BlobVersionedHashes)
+
+		// Txs with no price info at all (e.g. mock data) don't tell us anything about fees
+		if tx.GasPrice == nil && tx.MaxFeePerGas == nil {
+			continue
 		}
 
-		if gasPrice > 0 {
-			totalGasPrice += gasPrice
-			gasPriceCount++
+		tip := effectiveTip(tx, baseFee)
+		if tip.Sign() < 0 {
+			// Can't be included at the current base fee - don't let it drag the averages down
+			metrics.UnderpricedCount++
+			continue
+		}
+		totalTip.Add(totalTip, tip)
+		totalGasPrice.Add(totalGasPrice, effectiveGasPrice(tx, baseFee))
+		tipGwei := weiToGwei(tip)
+		tips = append(tips, tipGwei)
 
-			// Count high priority (>50 gwei = 50 * 1e9 wei)
-			if gasPrice > 50_000_000_000 {
-				metrics.HighPriorityCount++
-			}
+		if tipGwei >= highPriorityTipGwei {
+			metrics.HighPriorityCount++
 		}
 	}
 
 	// Store total value as hex
 	metrics.TotalValueWei = "0x" + totalValue.Text(16)
 
-	// Calculate average gas price in gwei
-	if gasPriceCount > 0 {
-		avgWei := totalGasPrice / uint64(gasPriceCount)
-		metrics.AvgGasPrice = float64(avgWei) / 1e9 // convert to gwei
+	// Averages and percentiles over txs that can actually be included
+	if n := len(tips); n > 0 {
+		count := big.NewInt(int64(n))
+		metrics.AvgGasPrice = weiToGwei(new(big.Int).Quo(totalGasPrice, count))
+		metrics.AvgEffectiveTip = weiToGwei(new(big.Int).Quo(totalTip, count))
+
+		sort.Float64s(tips)
+		metrics.PriorityFeePercentiles = map[string]float64{}
+		for _, p := range []int{10, 25, 50, 75, 90} {
+			// Nearest-rank percentile
+			idx := (p*n+99)/100 - 1
+			if idx < 0 {
+				idx = 0
+			}
+			metrics.PriorityFeePercentiles[fmt.Sprintf("p%d", p)] = tips[idx]
+		}
 	}
 
 	return metrics
 }
 
+// currentBaseFee returns the latest base fee we've seen, or nil before the first header arrives
+func currentBaseFee() *big.Int {
+	mempoolMutex.RLock()
+	defer mempoolMutex.RUnlock()
+	return hexBig(mempoolData.BaseFee)
+}
+
 // startHTTPPolling fetches the "pending" block every few seconds.
 // The pending block contains transactions that are waiting to be mined.
 // This works with all RPC providers, unlike WebSocket subscriptions.
@@ -167,8 +652,15 @@ func startHTTPPolling() {
 	defer ticker.Stop()
 
 	for range ticker.C {
-		// Ask for the "pending" pseudo-block with full tx objects
-		raw, err := rpcCall("eth_getBlockByNumber", []any{"pending", true})
+		// Ask for the "pending" pseudo-block with full tx objects, plus the latest header
+		// for its base fee - batched so it's still one round-trip per tick
+		results, err := rpcBatchCall([]rpcBatchRequest{
+			{Method: "eth_getBlockByNumber", Params: []any{"pending", true}},
+			{Method: "eth_getBlockByNumber", Params: []any{"latest", false}},
+		})
+		if err == nil {
+			err = results[0].Err
+		}
 		if err != nil {
 			log.Printf("mempool HTTP: failed to fetch pending block: %v\n", err)
 			// Update health status on error
@@ -178,20 +670,33 @@ func startHTTPPolling() {
 			continue
 		}
 
-		// Parse the block response
-		var block struct {
-			Transactions []struct {
-				Hash     string  `json:"hash"`
-				From     string  `json:"from"`
-				To       *string `json:"to"`
-				Value    string  `json:"value"`
-				GasPrice *string `json:"gasPrice"`
-				Gas      *string `json:"gas"`
-				Nonce    string  `json:"nonce"`
-				Input    string  `json:"input"`
-			} `json:"transactions"`
+		raw := results[0].Result
+
+		var baseFee *string
+		if results[1].Err == nil {
+			var head struct {
+				BaseFeePerGas *string `json:"baseFeePerGas"`
+			}
+			if json.Unmarshal(results[1].Result, &head) == nil {
+				baseFee = head.BaseFeePerGas
+			}
+		}
+
+		if baseFee != nil {
+			mempoolMutex.Lock()
+			mempoolData.BaseFee = baseFee
+			mempoolMutex.Unlock()
+		}
+
+		// Txs in the latest block are mined - take them out before adding new sightings
+		if results[1].Err == nil {
+			processMinedBlock(results[1].Result, "http-polling")
 		}
 
+		// Parse the pending block - its tx objects have the same fields as PendingTx
+		var block struct {
+			Transactions []PendingTx `json:"transactions"`
+		}
 		if err := json.Unmarshal(raw, &block); err != nil {
 			log.Printf("mempool HTTP: failed to parse pending block: %v\n", err)
 			continue
@@ -202,47 +707,14 @@ func startHTTPPolling() {
 			continue
 		}
 
-		// Grab the first 10 transactions for display
-		limit := 10
-		if len(block.Transactions) < limit {
-			limit = len(block.Transactions)
-		}
-
-		now := time.Now().Unix()
-		pendingTxs := make([]PendingTx, limit)
-		for i := 0; i < limit; i++ {
-			tx := block.Transactions[i]
-			pendingTxs[i] = PendingTx{
-				Hash:      tx.Hash,
-				From:      tx.From,
-				To:        tx.To,
-				Value:     tx.Value,
-				GasPrice:  tx.GasPrice,
-				Gas:       tx.Gas,
-				Nonce:     tx.Nonce,
-				Input:     tx.Input,
-				Timestamp: now,
-			}
-		}
-
-		// Calculate metrics
-		metrics := calculateMempoolMetrics(pendingTxs)
-
-		// Update our shared state
-		mempoolMutex.Lock()
-		mempoolData.PendingTxs = pendingTxs
-		mempoolData.Count = len(pendingTxs)
-		mempoolData.LastUpdate = now
-		mempoolData.Source = "http-polling"
-		mempoolData.Metrics = metrics
-		mempoolMutex.Unlock()
-
-		// Update health status on success
-		if mempoolHealth != nil {
-			mempoolHealth.SetSuccess()
+		recordPendingTxs(block.Transactions, "http-polling")
+		flushMempoolSummary()
+		metrics := GetMempoolData().Metrics
+		if metrics == nil {
+			metrics = &MempoolMetrics{}
 		}
 
-		log.Printf("mempool HTTP: fetched %d pending transactions (avg gas: %.2f gwei)\n",
-			len(pendingTxs), metrics.AvgGasPrice)
+		log.Printf("mempool HTTP: saw %d pending transactions, pool holds %d (avg gas: %.2f gwei)\n",
+			len(block.Transactions), txPool.size(), metrics.AvgGasPrice)
 	}
 }
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// Heads that arrive while the node is slow to serve a block must all be fetched, or the txs
+// they mined would sit in the pool until the TTL
+func TestHeadFetcherKeepsEveryHead(t *testing.T) {
+	stubRPC(t, func(method string, params []json.RawMessage) (any, *rpcError) {
+		var hash string
+		json.Unmarshal(params[0], &hash)
+		time.Sleep(5 * time.Millisecond)
+		// Block N mines tx N
+		return map[string]any{"hash": hash, "number": "0x1", "transactions": []string{"0xt" + hash[2:]}}, nil
+	})
+	saved := txPool
+	txPool = newMempoolPool(time.Hour, 100)
+	t.Cleanup(func() { txPool = saved })
+
+	heads := make([]string, 20)
+	for i := range heads {
+		heads[i] = fmt.Sprintf("0x%064x", i)
+		txPool.upsert([]PendingTx{{Hash: "0xt" + heads[i][2:]}}, time.Now())
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	fetcher := newHeadFetcher("test", done)
+	for _, h := range heads {
+		fetcher.push(h)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for txPool.size() > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := txPool.size(); n != 0 {
+		t.Errorf("%d of %d mined txs still pending", n, len(heads))
+	}
+}
@@ -0,0 +1,255 @@
+// payment_verify.go
+// Trust, but verify. A relay's delivered bid trace says "the builder paid `value` wei to
+// proposer_fee_recipient", and until now we simply displayed that number. Here we check it
+// against the execution block itself:
+//   - Most builders pay with an ordinary transfer at the end of the block, sent from the block's
+//     fee recipient (the builder's coinbase) to the proposer's fee recipient. We sum those.
+//   - Some builders set the proposer as the block's fee recipient and let the priority fees flow
+//     there directly. Then there is no payment tx, so we compare the proposer's balance at the
+//     parent block and at this block (minus any withdrawals credited to it in this block).
+//
+// The balance method needs state at the parent block, which pruned nodes only keep for ~128
+// blocks, so older blocks may come back "unverified".
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// paymentVerification is the result of checking one delivered payload's claimed payment
+type paymentVerification struct {
+	BlockNumber  uint64   `json:"block_number"`
+	BlockHash    string   `json:"block_hash"`
+	FeeRecipient string   `json:"proposer_fee_recipient"`
+	Builder      string   `json:"builder_coinbase,omitempty"` // the execution block's fee recipient
+	Status       string   `json:"status"`                     // matching, underpaid, overpaid, unverified or block_mismatch
+	Method       string   `json:"method"`                     // payment_tx, balance_delta or none
+	ClaimedWei   string   `json:"claimed_wei"`
+	ClaimedETH   string   `json:"claimed_eth"`
+	PaidWei      string   `json:"paid_wei,omitempty"`
+	PaidETH      string   `json:"paid_eth,omitempty"`
+	DiffWei      string   `json:"diff_wei,omitempty"` // paid - claimed
+	DiffETH      string   `json:"diff_eth,omitempty"`
+	PaymentTxs   []string `json:"payment_txs,omitempty"`
+	Note         string   `json:"note,omitempty"`
+}
+
+// paymentBlock is the slice of eth_getBlockByNumber we need
+type paymentBlock struct {
+	Number       string `json:"number"`
+	Hash         string `json:"hash"`
+	ParentHash   string `json:"parentHash"`
+	Miner        string `json:"miner"`
+	Transactions []struct {
+		Hash  string  `json:"hash"`
+		From  string  `json:"from"`
+		To    *string `json:"to"`
+		Value string  `json:"value"`
+	} `json:"transactions"`
+	Withdrawals []struct {
+		Address string `json:"address"`
+		Amount  string `json:"amount"` // gwei
+	} `json:"withdrawals"`
+}
+
+// Verified results never change once the block is final, and even before that they're keyed by
+// block hash, so a reorg simply means a different key
+var (
+	paymentVerifyMu    sync.Mutex
+	paymentVerifyCache = map[string]*paymentVerification{}
+)
+
+// paymentVerifyCacheMax bounds the cache; it's cleared when full (a verification is a few RPC calls)
+const paymentVerifyCacheMax = 20000
+
+// verifyBidPayment checks a delivered bid trace's claimed payment against the chain
+func verifyBidPayment(bid *BidTrace) (*paymentVerification, error) {
+	paymentVerifyMu.Lock()
+	cached, ok := paymentVerifyCache[bid.BlockHash]
+	paymentVerifyMu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	v := &paymentVerification{
+		BlockNumber:  bid.BlockNumber,
+		BlockHash:    bid.BlockHash,
+		FeeRecipient: bid.ProposerFeeRecipient,
+		ClaimedWei:   bid.Value.String(),
+		ClaimedETH:   bid.ValueETH(),
+		Method:       "none",
+		Status:       "unverified",
+	}
+
+	raw, err := rpcCall("eth_getBlockByNumber", []any{fmt.Sprintf("0x%x", bid.BlockNumber), true})
+	if err != nil {
+		return nil, err
+	}
+	if string(raw) == "null" {
+		v.Note = "Execution block not found on our node yet"
+		return v, nil
+	}
+	var b paymentBlock
+	if err := json.Unmarshal(raw, &b); err != nil {
+		return nil, fmt.Errorf("decode block: %w", err)
+	}
+	v.Builder = strings.ToLower(b.Miner)
+
+	if !strings.EqualFold(b.Hash, bid.BlockHash) {
+		v.Status = "block_mismatch"
+		v.Note = "The canonical block at this height is " + strings.ToLower(b.Hash) + ", not the one the relay delivered (reorged, or the proposer published a different block)"
+		return v, nil
+	}
+	if v.FeeRecipient == "" {
+		v.Note = "The relay didn't report a proposer fee recipient"
+		return v, nil
+	}
+
+	paid := new(big.Int)
+	if v.Builder != v.FeeRecipient {
+		// Builder paid from its own coinbase: look for transfers to the proposer
+		for i := len(b.Transactions) - 1; i >= 0; i-- {
+			tx := b.Transactions[i]
+			if tx.To == nil || !strings.EqualFold(*tx.To, v.FeeRecipient) || !strings.EqualFold(tx.From, v.Builder) {
+				continue
+			}
+			if val := hexBig(&tx.Value); val != nil {
+				paid.Add(paid, val)
+			}
+			v.PaymentTxs = append(v.PaymentTxs, tx.Hash)
+		}
+		if len(v.PaymentTxs) > 0 {
+			v.Method = "payment_tx"
+		}
+	}
+
+	if v.Method == "none" {
+		// No payment tx - the proposer was paid directly through priority fees (or not at all)
+		delta, err := feeRecipientBalanceDelta(&b, v.FeeRecipient)
+		if err != nil {
+			v.Note = "No payment transaction found and the balance check failed (the node may not keep state this far back): " + err.Error()
+			return v, nil
+		}
+		paid = delta
+		v.Method = "balance_delta"
+		v.Note = "Balance change of the fee recipient between the parent block and this one, excluding withdrawals. Transactions the fee recipient itself sent in this block would also show up here."
+	}
+
+	diff := new(big.Int).Sub(paid, bid.Value)
+	v.PaidWei, v.PaidETH = paid.String(), weiToETHDecimal(paid)
+	v.DiffWei = diff.String()
+	if diff.Sign() < 0 {
+		v.DiffETH = "-" + weiToETHDecimal(new(big.Int).Neg(diff))
+	} else {
+		v.DiffETH = weiToETHDecimal(diff)
+	}
+	switch diff.Sign() {
+	case 0:
+		v.Status = "matching"
+	case -1:
+		v.Status = "underpaid"
+	default:
+		v.Status = "overpaid"
+	}
+
+	paymentVerifyMu.Lock()
+	if len(paymentVerifyCache) >= paymentVerifyCacheMax {
+		paymentVerifyCache = map[string]*paymentVerification{}
+	}
+	paymentVerifyCache[bid.BlockHash] = v
+	paymentVerifyMu.Unlock()
+	return v, nil
+}
+
+// feeRecipientBalanceDelta returns addr's balance change over block b, minus withdrawals to it
+func feeRecipientBalanceDelta(b *paymentBlock, addr string) (*big.Int, error) {
+	results, err := rpcBatchCall([]rpcBatchRequest{
+		{Method: "eth_getBalance", Params: []any{addr, map[string]any{"blockHash": b.ParentHash}}},
+		{Method: "eth_getBalance", Params: []any{addr, map[string]any{"blockHash": b.Hash}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	balances := make([]*big.Int, 2)
+	for i, res := range results {
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		var h string
+		if err := json.Unmarshal(res.Result, &h); err != nil {
+			return nil, fmt.Errorf("decode balance: %w", err)
+		}
+		if balances[i] = hexBig(&h); balances[i] == nil {
+			return nil, fmt.Errorf("bad balance %q", h)
+		}
+	}
+
+	delta := new(big.Int).Sub(balances[1], balances[0])
+	gwei := big.NewInt(1e9)
+	for _, wd := range b.Withdrawals {
+		if !strings.EqualFold(wd.Address, addr) {
+			continue
+		}
+		if amt := hexBig(&wd.Amount); amt != nil {
+			delta.Sub(delta, new(big.Int).Mul(amt, gwei))
+		}
+	}
+	return delta, nil
+}
+
+// verifyBidPayments checks several bids at once, a few at a time. Results line up with bids;
+// a bid whose check failed outright gets nil.
+func verifyBidPayments(bids []*BidTrace) []*paymentVerification {
+	out := make([]*paymentVerification, len(bids))
+	sem := make(chan struct{}, 4)
+	var wg sync.WaitGroup
+	for i, bid := range bids {
+		wg.Add(1)
+		go func(i int, bid *BidTrace) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if v, err := verifyBidPayment(bid); err == nil {
+				out[i] = v
+			}
+		}(i, bid)
+	}
+	wg.Wait()
+	return out
+}
+
+// handleVerifyPayment serves GET /api/relays/verify-payment/{block_number}
+func handleVerifyPayment(w http.ResponseWriter, r *http.Request) {
+	n, err := strconv.ParseUint(strings.TrimPrefix(r.URL.Path, "/api/relays/verify-payment/"), 10, 64)
+	if err != nil {
+		writeErr(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid block number", "Use /api/relays/verify-payment/{block_number} with a decimal block number")
+		return
+	}
+
+	bid, merged, err := relayDeliveredFor(n)
+	if err != nil {
+		writeErr(w, http.StatusTooManyRequests, "RELAY", "Failed to fetch delivered payloads", "MEV relays may be rate limiting or unavailable")
+		return
+	}
+	if bid == nil {
+		writeErr(w, http.StatusNotFound, "NOT_FOUND", "No relay delivered this block", "The proposer may have built the block locally, or the relays that delivered it are not in RELAY_URLS")
+		return
+	}
+
+	v, err := verifyBidPayment(bid)
+	if err != nil {
+		writeErr(w, http.StatusBadGateway, "RPC", "Failed to fetch the execution block", err.Error())
+		return
+	}
+	writeOK(w, map[string]any{
+		"verification": v,
+		"bid":          bid,
+		"relays":       merged.Relays,
+	})
+}
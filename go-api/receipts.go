@@ -0,0 +1,170 @@
+// receipts.go
+// Fetches every receipt in a block as cheaply as the node allows.
+// The fastest path is eth_getBlockReceipts (one call for the whole block). Not every provider
+// supports it, so when the node says "method not found" we remember that and fall back to
+// batched eth_getTransactionReceipt calls, then to concurrent single calls for anything left over.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// receiptStats tells callers how a block's receipts were gathered and how many are missing.
+// We surface this in API responses instead of silently skipping failed receipts.
+type receiptStats struct {
+	Source  string `json:"source"`  // "eth_getBlockReceipts", "batch", "batch+concurrent" or "concurrent"
+	Total   int    `json:"total"`   // transactions in the block
+	Scanned int    `json:"scanned"` // transactions we asked receipts for
+	Missing int    `json:"missing"` // receipts we couldn't get
+}
+
+// blockReceiptsUnsupported flips to true the first time the node rejects eth_getBlockReceipts,
+// so we don't waste a round-trip on every scan afterwards
+var blockReceiptsUnsupported atomic.Bool
+
+// receiptConcurrency bounds how many single receipt calls run at once in the last-resort path
+var receiptConcurrency = func() int {
+	if s := os.Getenv("RECEIPT_CONCURRENCY"); s != "" {
+		if n, err := strconv.Atoi(s); err == nil && n > 0 && n <= 64 {
+			return n
+		}
+	}
+	return 8
+}()
+
+// isMethodNotFound reports whether the node doesn't implement the RPC method we called.
+// The spec code is -32601 but providers word it in many creative ways, so check the message too.
+func isMethodNotFound(err error) bool {
+	var re *rpcError
+	if !errors.As(err, &re) {
+		return false
+	}
+	if re.Code == -32601 {
+		return true
+	}
+	msg := strings.ToLower(re.Message)
+	for _, hint := range []string{"method not found", "does not exist", "not supported", "unsupported", "not available"} {
+		if strings.Contains(msg, hint) {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchBlockReceipts returns receipts for the first maxN transactions of b (index-aligned with
+// b.Transactions). A nil entry means that receipt couldn't be fetched; stats.Missing counts them.
+func fetchBlockReceipts(b *block, maxN int) ([]*receipt, receiptStats) {
+	if maxN <= 0 || maxN > len(b.Transactions) {
+		maxN = len(b.Transactions)
+	}
+	stats := receiptStats{Total: len(b.Transactions), Scanned: maxN}
+	hashes := make([]string, maxN)
+	for i := 0; i < maxN; i++ {
+		hashes[i] = b.Transactions[i].Hash
+	}
+	if maxN == 0 {
+		stats.Source = "none"
+		return nil, stats
+	}
+
+	// Fast path: the whole block in one call
+	if !blockReceiptsUnsupported.Load() {
+		all, err := fetchReceiptsByBlock(b.Hash)
+		if err == nil {
+			out := alignReceipts(hashes, all)
+			stats.Source = "eth_getBlockReceipts"
+			stats.Missing = countMissing(out)
+			if stats.Missing == 0 {
+				return out, stats
+			}
+			// Partial answer (shouldn't happen, but some proxies truncate) - top up below
+			fillReceiptsConcurrently(hashes, out)
+			stats.Missing = countMissing(out)
+			return out, stats
+		}
+		if isMethodNotFound(err) {
+			blockReceiptsUnsupported.Store(true)
+		}
+	}
+
+	// Fallback: batched per-tx receipts
+	out, err := fetchReceiptsBatch(hashes)
+	stats.Source = "batch"
+	if err != nil && countMissing(out) == len(out) {
+		// Batches rejected outright - go straight to concurrent single calls
+		stats.Source = "concurrent"
+	}
+	if countMissing(out) > 0 {
+		if stats.Source == "batch" {
+			stats.Source = "batch+concurrent"
+		}
+		fillReceiptsConcurrently(hashes, out)
+	}
+	stats.Missing = countMissing(out)
+	return out, stats
+}
+
+// fetchReceiptsByBlock calls eth_getBlockReceipts for the given block hash
+func fetchReceiptsByBlock(blockHash string) ([]receipt, error) {
+	raw, err := rpcCall("eth_getBlockReceipts", []any{blockHash})
+	if err != nil {
+		return nil, err
+	}
+	var all []receipt
+	if err := json.Unmarshal(raw, &all); err != nil {
+		return nil, err
+	}
+	return all, nil
+}
+
+// alignReceipts lines receipts up with tx hashes (matching by hash, never by position)
+func alignReceipts(hashes []string, all []receipt) []*receipt {
+	byHash := make(map[string]*receipt, len(all))
+	for i := range all {
+		byHash[strings.ToLower(all[i].TransactionHash)] = &all[i]
+	}
+	out := make([]*receipt, len(hashes))
+	for i, h := range hashes {
+		out[i] = byHash[strings.ToLower(h)]
+	}
+	return out
+}
+
+// fillReceiptsConcurrently fetches the nil entries of out one by one, at most
+// receiptConcurrency at a time
+func fillReceiptsConcurrently(hashes []string, out []*receipt) {
+	sem := make(chan struct{}, receiptConcurrency)
+	var wg sync.WaitGroup
+	for i := range out {
+		if out[i] != nil {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if r, err := fetchReceipt(hashes[i]); err == nil {
+				out[i] = r
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+// countMissing counts nil receipts
+func countMissing(rs []*receipt) int {
+	n := 0
+	for _, r := range rs {
+		if r == nil {
+			n++
+		}
+	}
+	return n
+}
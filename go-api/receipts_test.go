@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+// The receipt workers all report to rpcHealth at once; run with -race to catch unguarded state
+func TestFillReceiptsConcurrentlyHealth(t *testing.T) {
+	stubRPC(t, func(method string, params []json.RawMessage) (any, *rpcError) {
+		var hash string
+		if method != "eth_getTransactionReceipt" || json.Unmarshal(params[0], &hash) != nil {
+			return nil, &rpcError{Code: -32601, Message: "unexpected call"}
+		}
+		if hash == fmt.Sprintf("0x%064x", 7) {
+			return nil, nil // not mined
+		}
+		return map[string]any{"transactionHash": hash, "status": "0x1", "logs": []any{}}, nil
+	})
+
+	hashes := make([]string, 40)
+	for i := range hashes {
+		hashes[i] = fmt.Sprintf("0x%064x", i)
+	}
+	out := make([]*receipt, len(hashes))
+	fillReceiptsConcurrently(hashes, out)
+
+	if n := countMissing(out); n != 1 || out[7] != nil {
+		t.Errorf("missing %d receipts, want only #7", n)
+	}
+	for i, r := range out {
+		if r != nil && r.TransactionHash != hashes[i] {
+			t.Errorf("receipt %d is for %s", i, r.TransactionHash)
+		}
+	}
+	if !rpcHealth.IsHealthy() {
+		t.Errorf("rpc unhealthy after successful fetches: %v", rpcHealth.GetLastError())
+	}
+}
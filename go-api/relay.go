@@ -5,10 +5,10 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"strconv"
 	"strings"
@@ -56,18 +56,10 @@ var relayBudget = func() time.Duration {
 }()
 
 // relayGET tries to fetch data from multiple MEV relays until one succeeds.
-// It checks the cache first, then tries relays in order, respecting the time budget.
-// If a path recently failed, we skip it entirely (negative caching).
+// It checks the cache first, then tries relays fastest-healthy-first (see relay_stats.go),
+// respecting the time budget. Relays whose circuit is open, or that recently failed this
+// exact path, are skipped (negative caching per relay, so one dead relay doesn't block the rest).
 func relayGET(path string) (json.RawMessage, error) {
-	// Don't hammer relays that just failed - back off for a bit
-	if relayFailRecently(path) {
-		err := errors.New("relay recently failed; backing off")
-		if relayHealth != nil {
-			relayHealth.SetError(err)
-		}
-		return nil, err
-	}
-
 	// Check if we already have this cached
 	if body, ok := relayCacheGet(path); ok {
 		return body, nil
@@ -75,71 +67,54 @@ func relayGET(path string) (json.RawMessage, error) {
 
 	started := time.Now()
 	var lastErr error
-	successCount := 0
+	tried := 0
 
-	// Try each relay in our list until one works
-	for _, base := range relayBases {
+	// Try each relay until one works
+	for _, base := range relayOrder() {
 		// Stop if we've exceeded our time budget
 		if time.Since(started) > relayBudget {
-			fmt.Printf("relay: budget exceeded after trying %d relays\n", successCount)
+			fmt.Printf("relay: budget exceeded after trying %d relays\n", tried)
 			break
 		}
 
-		url := strings.TrimRight(base, "/") + path
-		req, err := http.NewRequest("GET", url, nil)
-		if err != nil {
-			lastErr = fmt.Errorf("request creation failed: %w", err)
+		// Don't hammer relays that just failed - back off for a bit
+		if relayFailRecently(base + path) {
+			continue
+		}
+		if !relayStatFor(base).allow(time.Now()) {
 			continue
 		}
 
-		req.Header.Set("Accept", "application/json")
-		resp, err := relayHTTPClient.Do(req)
+		tried++
+		body, err := relayFetchOne(context.Background(), base, path)
 		if err != nil {
-			lastErr = fmt.Errorf("request failed for %s: %w", base, err)
+			lastErr = fmt.Errorf("%s: %w", relayName(base), err)
+			relayCacheMarkFail(base + path)
 			continue
 		}
 
-		// Process the response in a closure so we can defer the body close
-		func() {
-			defer resp.Body.Close()
-
-			// Relays sometimes return non-200 status codes when rate limiting
-			if resp.StatusCode/100 != 2 {
-				lastErr = fmt.Errorf("non-2xx status %d from %s", resp.StatusCode, base)
-				return
-			}
-
-			body, _ := io.ReadAll(resp.Body)
-			// Some relays send empty responses even on 200 - skip those
-			if len(strings.TrimSpace(string(body))) == 0 {
-				lastErr = fmt.Errorf("empty response from %s", base)
-				return
-			}
-
-			relayCacheSet(path, json.RawMessage(body))
-			successCount++
-		}()
-
-		// If the cache now has it, we succeeded
-		if body, ok := relayCacheGet(path); ok {
-			fmt.Printf("relay: success from %s after %s\n", base, time.Since(started))
-			if relayHealth != nil {
-				relayHealth.SetSuccess()
-			}
-			return body, nil
+		relayCacheSet(path, body)
+		fmt.Printf("relay: success from %s after %s\n", relayName(base), time.Since(started))
+		if relayHealth != nil {
+			relayHealth.SetSuccess()
 		}
+		return body, nil
 	}
 
-	// All relays failed - mark this path as failing and return error
-	relayCacheMarkFail(path)
-	if lastErr != nil {
-		err := fmt.Errorf("all %d relays failed, last error: %w", len(relayBases), lastErr)
-		if relayHealth != nil {
-			relayHealth.SetError(err)
-		}
-		return nil, err
+	// Every relay failed, was backing off, or had an open circuit
+	var err error
+	switch {
+	case lastErr != nil:
+		err = fmt.Errorf("all %d relays failed, last error: %w", len(relayBases), lastErr)
+	case tried == 0:
+		err = errors.New("all relays backing off or circuit open")
+	default:
+		err = fmt.Errorf("all %d relays failed or timed out", len(relayBases))
+	}
+	if relayHealth != nil {
+		relayHealth.SetError(err)
 	}
-	return nil, fmt.Errorf("all %d relays failed or timed out", len(relayBases))
+	return nil, err
 }
 
 // === Caching layer ===
@@ -193,7 +168,8 @@ func relayCacheSet(key string, body json.RawMessage) {
 }
 
 // === Negative cache ===
-// Track recent failures so we don't keep trying the same broken path over and over
+// Track recent failures so we don't keep trying the same broken relay over and over.
+// Keys are relay base URL + path.
 
 type relayFailEntry struct{ expires time.Time }
 
@@ -209,14 +185,14 @@ var (
 	}()
 )
 
-// relayCacheMarkFail records that this path failed, so we back off for a bit
+// relayCacheMarkFail records that this relay+path failed, so we back off for a bit
 func relayCacheMarkFail(key string) {
 	relayFailMu.Lock()
 	relayFailMemo[key] = relayFailEntry{expires: time.Now().Add(relayErrTTL)}
 	relayFailMu.Unlock()
 }
 
-// relayFailRecently checks if this path failed recently (within the error cache window)
+// relayFailRecently checks if this relay+path failed recently (within the error cache window)
 func relayFailRecently(key string) bool {
 	now := time.Now()
 	relayFailMu.RLock()
@@ -0,0 +1,262 @@
+// relay_fanout.go
+// relayGET stops at the first relay that answers, which is fine for "show me some bids" but
+// gives a single relay's view of the market. The fan-out here asks every relay at once (still
+// within relayBudget), tags each record with the relay(s) it came from, and merges records that
+// several relays report for the same payload (block_hash).
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// relayResult is what one relay answered during a fan-out
+type relayResult struct {
+	Relay     string `json:"relay"`
+	OK        bool   `json:"ok"`
+	TimedOut  bool   `json:"timed_out,omitempty"`
+	Count     int    `json:"count"`
+	LatencyMs int64  `json:"latency_ms"`
+	Cached    bool   `json:"cached,omitempty"`
+	Rejected  int    `json:"rejected,omitempty"` // malformed entries we dropped
+	Status    int    `json:"status,omitempty"`   // HTTP status when the relay refused the request
+	Error     string `json:"error,omitempty"`
+
+	body     json.RawMessage
+	oldest   uint64 // lowest slot in this relay's answer, for cursor paging
+	answered bool   // refused with a 4xx - the relay is up, it just said no
+}
+
+// relayMerged is the de-duplicated union of every relay's records.
+// Both bidtraces endpoints decode as ReceivedBlock; delivered payloads just have no timestamps.
+type relayMerged struct {
+	Records  []*ReceivedBlock `json:"records"`
+	Relays   []relayResult    `json:"relays"`
+	TimedOut []string         `json:"timed_out"`
+}
+
+// traces returns the merged records as plain bid traces (for proposer_payload_delivered)
+func (m *relayMerged) traces() []*BidTrace {
+	out := make([]*BidTrace, len(m.Records))
+	for i, rec := range m.Records {
+		out[i] = &rec.BidTrace
+	}
+	return out
+}
+
+// relayName turns a relay URL into a display name (host only - the URL's user part is the relay pubkey)
+func relayName(base string) string {
+	u, err := url.Parse(base)
+	if err != nil || u.Host == "" {
+		return base
+	}
+	return u.Host
+}
+
+// relayFanout queries every relay in parallel and waits at most relayBudget for them.
+// Successful bodies are cached per relay+path, so a repeat call within the TTL is free.
+func relayFanout(path string) []relayResult {
+	ctx, cancel := context.WithTimeout(context.Background(), relayBudget)
+	defer cancel()
+
+	results := make([]relayResult, len(relayBases))
+	var wg sync.WaitGroup
+	for i, base := range relayBases {
+		results[i].Relay = relayName(base)
+		key := base + path
+		if body, ok := relayCacheGet(key); ok {
+			results[i].OK, results[i].Cached, results[i].body = true, true, body
+			continue
+		}
+		if relayFailRecently(key) {
+			results[i].Error = "recently failed; backing off"
+			continue
+		}
+		if !relayStatFor(base).allow(time.Now()) {
+			results[i].Error = "circuit open"
+			continue
+		}
+
+		wg.Add(1)
+		go func(res *relayResult, base, key string) {
+			defer wg.Done()
+			started := time.Now()
+			body, err := relayFetchOne(ctx, base, path)
+			res.LatencyMs = time.Since(started).Milliseconds()
+			if err != nil {
+				var ne net.Error
+				var se *relayStatusError
+				res.TimedOut = errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &ne) && ne.Timeout())
+				res.Error = err.Error()
+				if ctx.Err() != nil {
+					return // our budget ran out, not the relay's fault
+				}
+				if errors.As(err, &se) {
+					res.Status = se.Status
+					if se.answered() {
+						res.answered = true
+						return // a real answer ("not found", "bad request"), not a relay problem
+					}
+				}
+				relayCacheMarkFail(key)
+				return
+			}
+			relayCacheSet(key, body)
+			res.OK, res.body = true, body
+		}(&results[i], base, key)
+	}
+	wg.Wait()
+
+	// Relays that refused the request (e.g. validator not registered) are still up
+	if relayHealth != nil {
+		up := 0
+		for _, res := range results {
+			if res.OK || res.answered {
+				up++
+			}
+		}
+		if up > 0 {
+			relayHealth.SetSuccess()
+		} else {
+			relayHealth.SetError(fmt.Errorf("fan-out: all %d relays failed for %s", len(results), path))
+		}
+	}
+	return results
+}
+
+// relayStatusError is a non-2xx answer from a relay, with its error message if it sent one
+type relayStatusError struct {
+	Status  int
+	Message string
+}
+
+func (e *relayStatusError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("non-2xx status %d: %s", e.Status, e.Message)
+	}
+	return fmt.Sprintf("non-2xx status %d", e.Status)
+}
+
+// answered reports whether the relay is fine and just said no (4xx other than rate limiting),
+// e.g. "no registration found for validator". That shouldn't count against its health.
+func (e *relayStatusError) answered() bool {
+	return e.Status/100 == 4 && e.Status != http.StatusTooManyRequests
+}
+
+// relayFetchOne does a single GET against one relay and records the outcome in its stats.
+// Requests the caller cancelled (or whose shared budget ran out) aren't counted either way; a
+// relay that is slow on its own still hits relayHTTPClient's timeout and counts as a failure.
+func relayFetchOne(ctx context.Context, base, path string) (body json.RawMessage, err error) {
+	started := time.Now()
+	status := 0
+	defer func() {
+		if err != nil && ctx.Err() != nil {
+			relayStatFor(base).skip()
+			return
+		}
+		healthErr := err
+		var se *relayStatusError
+		if errors.As(err, &se) && se.answered() {
+			healthErr = nil
+		}
+		relayStatFor(base).record(status, healthErr, time.Since(started))
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", strings.TrimRight(base, "/")+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := relayHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	status = resp.StatusCode
+
+	// Relays sometimes return non-200 status codes when rate limiting
+	if resp.StatusCode/100 != 2 {
+		var msg struct {
+			Message string `json:"message"`
+		}
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		_ = json.Unmarshal(raw, &msg)
+		return nil, &relayStatusError{Status: resp.StatusCode, Message: msg.Message}
+	}
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	// Some relays send empty responses even on 200 - skip those
+	if len(strings.TrimSpace(string(raw))) == 0 {
+		return nil, errors.New("empty response")
+	}
+	return json.RawMessage(raw), nil
+}
+
+// relayFanoutRecords fans out a bidtraces query and merges the answers.
+// Each record gets Relay (first relay that reported it) and Relays (all of them); when relays
+// disagree on when they received the same block, the earliest timestamp wins.
+// Records are ordered newest slot first. Fails only if no relay answered.
+func relayFanoutRecords(path string) (*relayMerged, error) {
+	results := relayFanout(path)
+
+	merged := &relayMerged{Records: []*ReceivedBlock{}, TimedOut: []string{}}
+	byHash := map[string]*ReceivedBlock{}
+	okCount := 0
+
+	for i := range results {
+		res := &results[i]
+		if res.TimedOut {
+			merged.TimedOut = append(merged.TimedOut, res.Relay)
+		}
+		if !res.OK {
+			continue
+		}
+		records, rejected, err := decodeReceivedBlocks(res.body)
+		if err != nil {
+			res.OK, res.Error = false, "unexpected response: "+err.Error()
+			continue
+		}
+		okCount++
+		res.Count = len(records)
+		res.Rejected = rejected
+
+		for _, rec := range records {
+			if res.oldest == 0 || rec.Slot < res.oldest {
+				res.oldest = rec.Slot
+			}
+			if existing, ok := byHash[rec.BlockHash]; ok {
+				existing.Relays = append(existing.Relays, res.Relay)
+				if rec.TimestampMs != 0 && (existing.TimestampMs == 0 || rec.TimestampMs < existing.TimestampMs) {
+					existing.Timestamp, existing.TimestampMs = rec.Timestamp, rec.TimestampMs
+				}
+				continue
+			}
+			rec.Relay = res.Relay
+			rec.Relays = []string{res.Relay}
+			byHash[rec.BlockHash] = rec
+			merged.Records = append(merged.Records, rec)
+		}
+	}
+	merged.Relays = results
+
+	if okCount == 0 {
+		return merged, fmt.Errorf("all %d relays failed (%d timed out)", len(results), len(merged.TimedOut))
+	}
+
+	sort.SliceStable(merged.Records, func(i, j int) bool {
+		return merged.Records[i].Slot > merged.Records[j].Slot
+	})
+	return merged, nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRelayFetchOneBudgetIsNotAFailure(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow":
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		case "/gone":
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	// Our own budget running out (and a caller giving up) says nothing about the relay
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := relayFetchOne(ctx, srv.URL, "/slow"); err == nil {
+		t.Fatal("expected the budget to cut the request short")
+	}
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	cancel2()
+	if _, err := relayFetchOne(ctx2, srv.URL, "/slow"); err == nil {
+		t.Fatal("expected a cancelled request to fail")
+	}
+
+	st := relayStatFor(srv.URL)
+	st.mu.Lock()
+	requests, fails := st.requests, st.consecutiveFails
+	st.mu.Unlock()
+	if requests != 0 || fails != 0 {
+		t.Errorf("budget expiry recorded: requests=%d consecutiveFails=%d", requests, fails)
+	}
+
+	// A real relay failure still counts
+	if _, err := relayFetchOne(context.Background(), srv.URL, "/gone"); err == nil {
+		t.Fatal("expected a 502")
+	}
+	st.mu.Lock()
+	requests, fails = st.requests, st.consecutiveFails
+	st.mu.Unlock()
+	if requests != 1 || fails != 1 {
+		t.Errorf("502 recorded as requests=%d consecutiveFails=%d", requests, fails)
+	}
+}
+
+// A relay that refuses a query (validator not registered there) is up; one that 502s isn't
+func TestRelayFanoutHealthCountsRefusalsAsUp(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"code":400,"message":"no registration found for validator"}`))
+	}))
+	defer srv.Close()
+	saved := relayBases
+	relayBases = []string{srv.URL}
+	defer func() { relayBases = saved }()
+	if relayHealth == nil {
+		initHealthSources()
+	}
+
+	relayFanout("/down")
+	if relayHealth.IsHealthy() {
+		t.Error("relay healthy after every relay failed with 502")
+	}
+	relayFanout("/refused")
+	if !relayHealth.IsHealthy() {
+		t.Errorf("relay unhealthy after a 400 answer: %v", relayHealth.GetLastError())
+	}
+}
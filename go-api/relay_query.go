@@ -0,0 +1,169 @@
+// relay_query.go
+// Typed filters for the relay data API instead of hand-built query strings.
+// Both bidtraces endpoints accept slot, block_number and block_hash filters, so we can ask for
+// the one payload we care about rather than downloading the latest N and scanning. The delivered
+// endpoint also takes a cursor (a slot: "give me entries at or before this slot") for paging back.
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Relay data API endpoints
+const (
+	relayDeliveredEndpoint = "/relay/v1/data/bidtraces/proposer_payload_delivered"
+	relayReceivedEndpoint  = "/relay/v1/data/bidtraces/builder_blocks_received"
+)
+
+// relayQuery is the set of filters the bidtraces endpoints understand. Zero values are omitted.
+type relayQuery struct {
+	Slot           *uint64
+	BlockNumber    *uint64
+	BlockHash      string
+	ProposerPubkey string
+	BuilderPubkey  string
+	Cursor         *uint64 // delivered only: newest slot to return
+	Limit          int
+}
+
+// path renders the query onto an endpoint, e.g. relayDeliveredEndpoint?block_number=123
+func (q relayQuery) path(endpoint string) string {
+	v := url.Values{}
+	if q.Slot != nil {
+		v.Set("slot", strconv.FormatUint(*q.Slot, 10))
+	}
+	if q.BlockNumber != nil {
+		v.Set("block_number", strconv.FormatUint(*q.BlockNumber, 10))
+	}
+	if q.BlockHash != "" {
+		v.Set("block_hash", q.BlockHash)
+	}
+	if q.ProposerPubkey != "" {
+		v.Set("proposer_pubkey", q.ProposerPubkey)
+	}
+	if q.BuilderPubkey != "" {
+		v.Set("builder_pubkey", q.BuilderPubkey)
+	}
+	if q.Cursor != nil {
+		v.Set("cursor", strconv.FormatUint(*q.Cursor, 10))
+	}
+	if q.Limit > 0 {
+		v.Set("limit", strconv.Itoa(q.Limit))
+	}
+	if len(v) == 0 {
+		return endpoint
+	}
+	return endpoint + "?" + v.Encode()
+}
+
+// filtered reports whether the query pins down a specific slot/block
+func (q relayQuery) filtered() bool {
+	return q.Slot != nil || q.BlockNumber != nil || q.BlockHash != ""
+}
+
+// relayQueryFromRequest reads slot, block_number, block_hash, cursor and limit from the URL.
+// defLimit applies when limit is missing; limit is clamped to 1-200.
+func relayQueryFromRequest(r *http.Request, defLimit int) (relayQuery, error) {
+	qs := r.URL.Query()
+	q := relayQuery{Limit: defLimit}
+
+	parseUint := func(name string) (*uint64, error) {
+		s := qs.Get(name)
+		if s == "" {
+			return nil, nil
+		}
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a decimal number", name)
+		}
+		return &n, nil
+	}
+	var err error
+	if q.Slot, err = parseUint("slot"); err != nil {
+		return q, err
+	}
+	if q.BlockNumber, err = parseUint("block_number"); err != nil {
+		return q, err
+	}
+	if q.Cursor, err = parseUint("cursor"); err != nil {
+		return q, err
+	}
+	if h := qs.Get("block_hash"); h != "" {
+		if !strings.HasPrefix(h, "0x") || len(h) != 66 {
+			return q, fmt.Errorf("block_hash must be a 0x-prefixed 32-byte hex string")
+		}
+		q.BlockHash = strings.ToLower(h)
+	}
+	if s := qs.Get("limit"); s != "" {
+		if n, err := strconv.Atoi(s); err == nil {
+			if n < 1 {
+				n = 1
+			}
+			if n > 200 {
+				n = 200
+			}
+			q.Limit = n
+		}
+	}
+	return q, nil
+}
+
+// relayQueryGET runs a query through relayGET (first relay that answers) and decodes the records
+func relayQueryGET(endpoint string, q relayQuery) ([]*ReceivedBlock, int, error) {
+	raw, err := relayGET(q.path(endpoint))
+	if err != nil {
+		return nil, 0, err
+	}
+	return decodeReceivedBlocks(raw)
+}
+
+// relayDeliveredFor finds the delivered payload for one execution block, asking every relay
+func relayDeliveredFor(blockNumber uint64) (*BidTrace, *relayMerged, error) {
+	merged, err := relayFanoutRecords(relayQuery{BlockNumber: &blockNumber}.path(relayDeliveredEndpoint))
+	if err != nil {
+		return nil, merged, err
+	}
+	for _, t := range merged.traces() {
+		if t.BlockNumber == blockNumber {
+			return t, merged, nil
+		}
+	}
+	return nil, merged, nil
+}
+
+// relayPage cuts newest-first traces down to a page of limit, extended to the end of the last
+// slot: several relays can report different payloads for one slot, and a page that split them
+// would lose the rest once relayNextCursor steps below that slot
+func relayPage(traces []*BidTrace, limit int) []*BidTrace {
+	if limit <= 0 || len(traces) <= limit {
+		return traces
+	}
+	n := limit
+	for n < len(traces) && traces[n].Slot == traces[limit-1].Slot {
+		n++
+	}
+	return traces[:n]
+}
+
+// relayNextCursor returns the cursor for the page after traces (one below the oldest slot seen),
+// or nil if there is nothing older to fetch. traces must end on a slot boundary (see relayPage).
+func relayNextCursor(traces []*BidTrace) *uint64 {
+	if len(traces) == 0 {
+		return nil
+	}
+	oldest := traces[0].Slot
+	for _, t := range traces[1:] {
+		if t.Slot < oldest {
+			oldest = t.Slot
+		}
+	}
+	if oldest == 0 {
+		return nil
+	}
+	next := oldest - 1
+	return &next
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+)
+
+// pageDelivered plays one merged delivered-payloads page: every relay answers with up to limit of its
+// own records at or before the cursor, and the union is cut the way handleRelaysDelivered does it
+func pageDelivered(relays [][]*BidTrace, cursor *uint64, limit int) []*BidTrace {
+	seen := map[string]bool{}
+	var merged []*BidTrace
+	for _, records := range relays {
+		n := 0
+		for _, t := range records {
+			if cursor != nil && t.Slot > *cursor {
+				continue
+			}
+			if n++; n > limit {
+				break
+			}
+			if !seen[t.BlockHash] {
+				seen[t.BlockHash] = true
+				merged = append(merged, t)
+			}
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool { return merged[i].Slot > merged[j].Slot })
+	return relayPage(merged, limit)
+}
+
+func TestRelayCursorPagingSeesEverySlotOnce(t *testing.T) {
+	trace := func(slot uint64, tag string) *BidTrace {
+		return &BidTrace{Slot: slot, BlockHash: fmt.Sprintf("0x%d%s", slot, tag)}
+	}
+	// Relay B delivered a different payload for slot 108, right where the first page ends
+	relayA := []*BidTrace{trace(110, "a"), trace(109, "a"), trace(108, "a"), trace(107, "a"), trace(105, "a"), trace(104, "a")}
+	relayB := []*BidTrace{trace(110, "a"), trace(108, "b"), trace(106, "b"), trace(104, "a"), trace(103, "b")}
+	want := 9 // distinct payloads across both relays
+
+	got := map[string]int{}
+	var cursor *uint64
+	for page := 0; page < 10; page++ {
+		traces := pageDelivered([][]*BidTrace{relayA, relayB}, cursor, 3)
+		if len(traces) == 0 {
+			break
+		}
+		for _, tr := range traces {
+			got[tr.BlockHash]++
+		}
+		next := relayNextCursor(traces)
+		if next == nil {
+			break
+		}
+		if cursor != nil && *next >= *cursor {
+			t.Fatalf("cursor went from %d to %d", *cursor, *next)
+		}
+		cursor = next
+	}
+
+	if len(got) != want {
+		t.Errorf("saw %d payloads, want %d: %v", len(got), want, got)
+	}
+	for h, n := range got {
+		if n != 1 {
+			t.Errorf("%s returned %d times", h, n)
+		}
+	}
+}
+
+func TestRelayPageKeepsBoundarySlotTogether(t *testing.T) {
+	traces := []*BidTrace{{Slot: 10}, {Slot: 9}, {Slot: 9}, {Slot: 9}, {Slot: 8}}
+	if n := len(relayPage(traces, 2)); n != 4 {
+		t.Errorf("limit 2 gave %d traces, want the whole of slot 9 (4)", n)
+	}
+	if n := len(relayPage(traces, 4)); n != 4 {
+		t.Errorf("limit 4 gave %d traces, want 4", n)
+	}
+	if n := len(relayPage(traces, 10)); n != 5 {
+		t.Errorf("limit 10 gave %d traces, want all 5", n)
+	}
+	if c := relayNextCursor(relayPage(traces, 2)); c == nil || *c != 8 {
+		t.Errorf("next cursor = %v, want 8", c)
+	}
+}
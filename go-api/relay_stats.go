@@ -0,0 +1,257 @@
+// relay_stats.go
+// Per-relay bookkeeping. Every request we make to a relay records its latency and outcome, which
+// gives us three things:
+//   - health reporting per relay (success rate, p50/p95 latency, last status, 429s) in /api/health
+//   - a circuit breaker: after RELAY_BREAKER_FAILURES failures in a row a relay is skipped for
+//     RELAY_BREAKER_COOLDOWN_SECONDS, then gets a single trial request before it's trusted again
+//   - adaptive ordering: relayGET tries the fastest healthy relays first
+package main
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// relayLatencyWindow is how many recent requests we keep per relay for percentiles/success rate
+const relayLatencyWindow = 100
+
+// relayBreakerFailures is how many consecutive failures open a relay's circuit
+var relayBreakerFailures = func() int {
+	if s := os.Getenv("RELAY_BREAKER_FAILURES"); s != "" {
+		if n, err := strconv.Atoi(s); err == nil && n > 0 && n <= 100 {
+			return n
+		}
+	}
+	return 3
+}()
+
+// relayBreakerCooldown is how long an open circuit stays open before a trial request
+var relayBreakerCooldown = func() time.Duration {
+	if s := os.Getenv("RELAY_BREAKER_COOLDOWN_SECONDS"); s != "" {
+		if n, err := strconv.Atoi(s); err == nil && n > 0 && n <= 3600 {
+			return time.Duration(n) * time.Second
+		}
+	}
+	return 30 * time.Second
+}()
+
+// relaySample is one finished request
+type relaySample struct {
+	ok      bool
+	latency time.Duration
+}
+
+// relayStat tracks one relay
+type relayStat struct {
+	mu   sync.Mutex
+	base string
+
+	samples []relaySample // ring of the last relayLatencyWindow requests, oldest first
+
+	requests    int
+	rateLimited int
+	lastStatus  int
+	lastError   string
+	lastSuccess time.Time
+	lastFailure time.Time
+
+	consecutiveFails int
+	openUntil        time.Time // circuit is open until this time
+	probing          bool      // a half-open trial request is in flight
+}
+
+// relayHealthReport is what /api/health shows per relay
+type relayHealthReport struct {
+	Relay            string    `json:"relay"`
+	Healthy          bool      `json:"healthy"`
+	Circuit          string    `json:"circuit"` // "closed", "open" or "half_open"
+	Requests         int       `json:"requests"`
+	SuccessRate      float64   `json:"successRate"` // over the recent window, 0-1
+	P50Ms            int64     `json:"p50Ms"`
+	P95Ms            int64     `json:"p95Ms"`
+	LastStatus       int       `json:"lastStatus,omitempty"`
+	RateLimited      int       `json:"rateLimited"`
+	ConsecutiveFails int       `json:"consecutiveFails"`
+	LastSuccess      time.Time `json:"lastSuccess,omitempty"`
+	LastError        string    `json:"lastError,omitempty"`
+}
+
+var (
+	relayStatsMu sync.Mutex
+	relayStats   = map[string]*relayStat{}
+)
+
+// relayStatFor returns the stats for a relay base URL, creating them on first use
+func relayStatFor(base string) *relayStat {
+	relayStatsMu.Lock()
+	defer relayStatsMu.Unlock()
+	s, ok := relayStats[base]
+	if !ok {
+		s = &relayStat{base: base}
+		relayStats[base] = s
+	}
+	return s
+}
+
+// allow reports whether we may send a request to this relay right now.
+// Once the cooldown has passed, exactly one caller gets through as a trial (half-open).
+func (s *relayStat) allow(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.consecutiveFails < relayBreakerFailures {
+		return true
+	}
+	if now.Before(s.openUntil) || s.probing {
+		return false
+	}
+	s.probing = true
+	return true
+}
+
+// record stores the outcome of one request. status is 0 when there was no HTTP response.
+func (s *relayStat) record(status int, err error, latency time.Duration) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests++
+	s.probing = false
+	if status != 0 {
+		s.lastStatus = status
+	}
+	if status == 429 {
+		s.rateLimited++
+	}
+
+	ok := err == nil
+	s.samples = append(s.samples, relaySample{ok: ok, latency: latency})
+	if len(s.samples) > relayLatencyWindow {
+		s.samples = s.samples[len(s.samples)-relayLatencyWindow:]
+	}
+
+	if ok {
+		s.lastSuccess = now
+		s.consecutiveFails = 0
+		s.openUntil = time.Time{}
+		return
+	}
+	s.lastError = err.Error()
+	s.lastFailure = now
+	s.consecutiveFails++
+	if s.consecutiveFails >= relayBreakerFailures {
+		s.openUntil = now.Add(relayBreakerCooldown)
+	}
+}
+
+// skip releases a request that was cut short by its caller (budget spent, client gone) without
+// recording it: it says nothing about the relay, so it must neither trip nor close the breaker
+func (s *relayStat) skip() {
+	s.mu.Lock()
+	s.probing = false
+	s.mu.Unlock()
+}
+
+// circuitLocked returns the breaker state; caller holds s.mu
+func (s *relayStat) circuitLocked(now time.Time) string {
+	switch {
+	case s.consecutiveFails < relayBreakerFailures:
+		return "closed"
+	case now.Before(s.openUntil):
+		return "open"
+	default:
+		return "half_open"
+	}
+}
+
+// percentileLocked returns the p-th percentile (0-100) of successful request latency; caller holds s.mu
+func (s *relayStat) percentileLocked(p int) time.Duration {
+	var lat []time.Duration
+	for _, smp := range s.samples {
+		if smp.ok {
+			lat = append(lat, smp.latency)
+		}
+	}
+	if len(lat) == 0 {
+		return 0
+	}
+	sort.Slice(lat, func(i, j int) bool { return lat[i] < lat[j] })
+	return lat[(len(lat)-1)*p/100]
+}
+
+// report summarizes the relay for /api/health
+func (s *relayStat) report() relayHealthReport {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := relayHealthReport{
+		Relay:            relayName(s.base),
+		Circuit:          s.circuitLocked(now),
+		Requests:         s.requests,
+		P50Ms:            s.percentileLocked(50).Milliseconds(),
+		P95Ms:            s.percentileLocked(95).Milliseconds(),
+		LastStatus:       s.lastStatus,
+		RateLimited:      s.rateLimited,
+		ConsecutiveFails: s.consecutiveFails,
+		LastSuccess:      s.lastSuccess,
+		LastError:        s.lastError,
+	}
+	if len(s.samples) > 0 {
+		ok := 0
+		for _, smp := range s.samples {
+			if smp.ok {
+				ok++
+			}
+		}
+		r.SuccessRate = float64(ok) / float64(len(s.samples))
+	}
+	// Never tried counts as healthy; otherwise the circuit must be closed and the last try good
+	r.Healthy = r.Circuit == "closed" && (s.requests == 0 || !s.lastSuccess.Before(s.lastFailure))
+	return r
+}
+
+// relayOrder returns relayBases sorted so the best bets come first: relays with a closed circuit
+// before the rest, then by median latency. Relays we haven't tried yet keep their configured
+// position among relays with the same latency (0), so every relay gets measured early on.
+func relayOrder() []string {
+	type ranked struct {
+		base   string
+		closed bool
+		p50    time.Duration
+	}
+	now := time.Now()
+	list := make([]ranked, len(relayBases))
+	for i, base := range relayBases {
+		s := relayStatFor(base)
+		s.mu.Lock()
+		list[i] = ranked{base: base, closed: s.circuitLocked(now) == "closed", p50: s.percentileLocked(50)}
+		if list[i].p50 == 0 && len(s.samples) > 0 {
+			// Tried but never succeeded recently - rank it as slow as it gets
+			list[i].p50 = relayBudget
+		}
+		s.mu.Unlock()
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].closed != list[j].closed {
+			return list[i].closed
+		}
+		return list[i].p50 < list[j].p50
+	})
+	out := make([]string, len(list))
+	for i, r := range list {
+		out[i] = r.base
+	}
+	return out
+}
+
+// relayHealthReports returns the per-relay health in configured order
+func relayHealthReports() []relayHealthReport {
+	out := make([]relayHealthReport, 0, len(relayBases))
+	for _, base := range relayBases {
+		out = append(out, relayStatFor(base).report())
+	}
+	return out
+}
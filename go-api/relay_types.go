@@ -0,0 +1,297 @@
+// relay_types.go
+// Typed models for the relay data API (bidtraces). Relays send every number as a decimal string
+// and nothing stops an entry from missing fields, so we parse each entry into a BidTrace or
+// ReceivedBlock, reject the ones that don't make sense, and render them back out in one
+// consistent shape: the relay's original fields (same names, decimal strings) plus derived
+// value_eth and gas_utilization.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// BidTrace is one proposer_payload_delivered entry, and the common part of a builder submission
+type BidTrace struct {
+	Slot                 uint64
+	ParentHash           string
+	BlockHash            string
+	BuilderPubkey        string
+	ProposerPubkey       string
+	ProposerFeeRecipient string
+	GasLimit             uint64
+	GasUsed              uint64
+	Value                *big.Int // wei paid to the proposer
+	BlockNumber          uint64
+	NumTx                uint64
+
+	// Which relay(s) reported it; filled in by the fan-out
+	Relay  string
+	Relays []string
+}
+
+// ReceivedBlock is one builder_blocks_received entry: a bid, plus when the relay got it
+type ReceivedBlock struct {
+	BidTrace
+	Timestamp            int64 // unix seconds
+	TimestampMs          int64 // unix milliseconds
+	OptimisticSubmission bool
+}
+
+// bidTraceWire is the relay's JSON encoding
+type bidTraceWire struct {
+	Slot                 string `json:"slot"`
+	ParentHash           string `json:"parent_hash"`
+	BlockHash            string `json:"block_hash"`
+	BuilderPubkey        string `json:"builder_pubkey"`
+	ProposerPubkey       string `json:"proposer_pubkey"`
+	ProposerFeeRecipient string `json:"proposer_fee_recipient"`
+	GasLimit             string `json:"gas_limit"`
+	GasUsed              string `json:"gas_used"`
+	Value                string `json:"value"`
+	BlockNumber          string `json:"block_number"`
+	NumTx                string `json:"num_tx"`
+
+	// builder_blocks_received only
+	Timestamp            string `json:"timestamp,omitempty"`
+	TimestampMs          string `json:"timestamp_ms,omitempty"`
+	OptimisticSubmission bool   `json:"optimistic_submission,omitempty"`
+}
+
+var (
+	reHash32  = regexp.MustCompile(`^0x[0-9a-fA-F]{64}$`)
+	reBLSKey  = regexp.MustCompile(`^0x[0-9a-fA-F]{96}$`)
+	reAddress = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+)
+
+// parse validates the wire fields and converts them into a BidTrace
+func (w *bidTraceWire) parse() (*BidTrace, error) {
+	t := &BidTrace{
+		ParentHash:           strings.ToLower(w.ParentHash),
+		BlockHash:            strings.ToLower(w.BlockHash),
+		BuilderPubkey:        strings.ToLower(w.BuilderPubkey),
+		ProposerPubkey:       strings.ToLower(w.ProposerPubkey),
+		ProposerFeeRecipient: strings.ToLower(w.ProposerFeeRecipient),
+	}
+
+	var err error
+	if t.Slot, err = parseDecimalField("slot", w.Slot, true); err != nil {
+		return nil, err
+	}
+	if t.BlockNumber, err = parseDecimalField("block_number", w.BlockNumber, true); err != nil {
+		return nil, err
+	}
+	if t.GasLimit, err = parseDecimalField("gas_limit", w.GasLimit, false); err != nil {
+		return nil, err
+	}
+	if t.GasUsed, err = parseDecimalField("gas_used", w.GasUsed, false); err != nil {
+		return nil, err
+	}
+	if t.NumTx, err = parseDecimalField("num_tx", w.NumTx, false); err != nil {
+		return nil, err
+	}
+
+	v, ok := new(big.Int).SetString(w.Value, 10)
+	if !ok || v.Sign() < 0 {
+		return nil, fmt.Errorf("value %q is not a non-negative decimal wei amount", w.Value)
+	}
+	t.Value = v
+
+	if !reHash32.MatchString(t.BlockHash) {
+		return nil, fmt.Errorf("block_hash %q is not a 32-byte hex hash", w.BlockHash)
+	}
+	if t.ParentHash != "" && !reHash32.MatchString(t.ParentHash) {
+		return nil, fmt.Errorf("parent_hash %q is not a 32-byte hex hash", w.ParentHash)
+	}
+	if !reBLSKey.MatchString(t.BuilderPubkey) {
+		return nil, fmt.Errorf("builder_pubkey %q is not a 48-byte BLS key", w.BuilderPubkey)
+	}
+	if t.ProposerPubkey != "" && !reBLSKey.MatchString(t.ProposerPubkey) {
+		return nil, fmt.Errorf("proposer_pubkey %q is not a 48-byte BLS key", w.ProposerPubkey)
+	}
+	if t.ProposerFeeRecipient != "" && !reAddress.MatchString(t.ProposerFeeRecipient) {
+		return nil, fmt.Errorf("proposer_fee_recipient %q is not an address", w.ProposerFeeRecipient)
+	}
+	if t.GasLimit > 0 && t.GasUsed > t.GasLimit {
+		return nil, fmt.Errorf("gas_used %d exceeds gas_limit %d", t.GasUsed, t.GasLimit)
+	}
+	return t, nil
+}
+
+// parseDecimalField parses a relay decimal-string number; empty is an error only when required
+func parseDecimalField(name, s string, required bool) (uint64, error) {
+	if s == "" {
+		if required {
+			return 0, fmt.Errorf("missing %s", name)
+		}
+		return 0, nil
+	}
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s %q is not a decimal number", name, s)
+	}
+	return n, nil
+}
+
+// UnmarshalJSON parses and validates a relay bid trace
+func (t *BidTrace) UnmarshalJSON(data []byte) error {
+	var w bidTraceWire
+	if err := json.Unmarshal(data, &w); err != nil {
+		return err
+	}
+	parsed, err := w.parse()
+	if err != nil {
+		return err
+	}
+	*t = *parsed
+	return nil
+}
+
+// UnmarshalJSON parses and validates a builder submission
+func (b *ReceivedBlock) UnmarshalJSON(data []byte) error {
+	var w bidTraceWire
+	if err := json.Unmarshal(data, &w); err != nil {
+		return err
+	}
+	parsed, err := w.parse()
+	if err != nil {
+		return err
+	}
+	b.BidTrace = *parsed
+	if w.Timestamp != "" {
+		ts, err := strconv.ParseInt(w.Timestamp, 10, 64)
+		if err != nil {
+			return fmt.Errorf("timestamp %q is not a decimal number", w.Timestamp)
+		}
+		b.Timestamp = ts
+	}
+	if w.TimestampMs != "" {
+		ms, err := strconv.ParseInt(w.TimestampMs, 10, 64)
+		if err != nil {
+			return fmt.Errorf("timestamp_ms %q is not a decimal number", w.TimestampMs)
+		}
+		b.TimestampMs = ms
+	}
+	// Older relays only send seconds
+	if b.TimestampMs == 0 && b.Timestamp != 0 {
+		b.TimestampMs = b.Timestamp * 1000
+	}
+	if b.Timestamp == 0 && b.TimestampMs != 0 {
+		b.Timestamp = b.TimestampMs / 1000
+	}
+	b.OptimisticSubmission = w.OptimisticSubmission
+	return nil
+}
+
+// ValueETH returns the proposer payment in ETH as a decimal string (18 places, trailing zeros trimmed)
+func (t *BidTrace) ValueETH() string {
+	return weiToETHDecimal(t.Value)
+}
+
+// GasUtilization returns gas_used / gas_limit as a percentage (0 when the limit is unknown)
+func (t *BidTrace) GasUtilization() float64 {
+	if t.GasLimit == 0 {
+		return 0
+	}
+	return float64(t.GasUsed) * 100 / float64(t.GasLimit)
+}
+
+// fields renders the trace in the relay's field names, plus derived values
+func (t *BidTrace) fields() map[string]any {
+	m := map[string]any{
+		"slot":                   strconv.FormatUint(t.Slot, 10),
+		"parent_hash":            t.ParentHash,
+		"block_hash":             t.BlockHash,
+		"builder_pubkey":         t.BuilderPubkey,
+		"proposer_pubkey":        t.ProposerPubkey,
+		"proposer_fee_recipient": t.ProposerFeeRecipient,
+		"gas_limit":              strconv.FormatUint(t.GasLimit, 10),
+		"gas_used":               strconv.FormatUint(t.GasUsed, 10),
+		"value":                  t.Value.String(),
+		"block_number":           strconv.FormatUint(t.BlockNumber, 10),
+		"num_tx":                 strconv.FormatUint(t.NumTx, 10),
+		"value_eth":              t.ValueETH(),
+		"gas_utilization":        roundTo(t.GasUtilization(), 2),
+	}
+	if t.Relay != "" {
+		m["relay"] = t.Relay
+		m["relays"] = t.Relays
+	}
+	return m
+}
+
+// MarshalJSON writes the consistent output shape
+func (t BidTrace) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.fields())
+}
+
+// MarshalJSON writes the bid trace fields plus the submission timestamps
+func (b ReceivedBlock) MarshalJSON() ([]byte, error) {
+	m := b.BidTrace.fields()
+	m["timestamp"] = strconv.FormatInt(b.Timestamp, 10)
+	m["timestamp_ms"] = strconv.FormatInt(b.TimestampMs, 10)
+	m["optimistic_submission"] = b.OptimisticSubmission
+	return json.Marshal(m)
+}
+
+// decodeBidTraces parses a relay response array, skipping (and counting) malformed entries.
+// It only fails if the body isn't a JSON array at all.
+func decodeBidTraces(raw json.RawMessage) ([]*BidTrace, int, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, 0, errors.New("relay response is not an array")
+	}
+	out := make([]*BidTrace, 0, len(items))
+	rejected := 0
+	for _, item := range items {
+		var t BidTrace
+		if err := json.Unmarshal(item, &t); err != nil {
+			rejected++
+			continue
+		}
+		out = append(out, &t)
+	}
+	return out, rejected, nil
+}
+
+// decodeReceivedBlocks is decodeBidTraces for builder submissions
+func decodeReceivedBlocks(raw json.RawMessage) ([]*ReceivedBlock, int, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, 0, errors.New("relay response is not an array")
+	}
+	out := make([]*ReceivedBlock, 0, len(items))
+	rejected := 0
+	for _, item := range items {
+		var b ReceivedBlock
+		if err := json.Unmarshal(item, &b); err != nil {
+			rejected++
+			continue
+		}
+		out = append(out, &b)
+	}
+	return out, rejected, nil
+}
+
+// weiToETHDecimal formats wei as ETH with up to 18 decimals, trailing zeros trimmed
+func weiToETHDecimal(wei *big.Int) string {
+	if wei == nil {
+		return "0"
+	}
+	s := new(big.Rat).SetFrac(wei, big.NewInt(1e18)).FloatString(18)
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	return s
+}
+
+// roundTo rounds f to n decimal places
+func roundTo(f float64, n int) float64 {
+	r, _ := strconv.ParseFloat(strconv.FormatFloat(f, 'f', n, 64), 64)
+	return r
+}
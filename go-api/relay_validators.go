@@ -0,0 +1,286 @@
+// relay_validators.go
+// Validator registrations: before a validator can use MEV-Boost it signs a registration
+// (fee recipient, gas limit, timestamp) that mev-boost POSTs to every relay it's configured
+// with via /eth/v1/builder/validators. That endpoint is write-only, but relays publish what
+// they received:
+//   - /relay/v1/data/validator_registration?pubkey=  the latest registration for one validator
+//   - /relay/v1/builder/validators                   registered proposers for this and next epoch
+//
+// /api/validators/{pubkey_or_index} asks every relay, so you can see which relays a validator
+// uses and whether its registrations agree with each other.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Relay endpoints for registrations
+const (
+	relayRegistrationEndpoint     = "/relay/v1/data/validator_registration"
+	relayBuilderValidatorEndpoint = "/relay/v1/builder/validators"
+)
+
+// validatorRegistration is one signed registration as a relay stored it
+type validatorRegistration struct {
+	Pubkey       string `json:"pubkey"`
+	FeeRecipient string `json:"fee_recipient"`
+	GasLimit     uint64 `json:"gas_limit"`
+	Timestamp    uint64 `json:"timestamp"`
+	Time         string `json:"time"` // timestamp as RFC 3339
+}
+
+// registrationWire is the relay encoding: {"message": {...}, "signature": "0x..."}
+type registrationWire struct {
+	Message struct {
+		FeeRecipient string `json:"fee_recipient"`
+		GasLimit     string `json:"gas_limit"`
+		Timestamp    string `json:"timestamp"`
+		Pubkey       string `json:"pubkey"`
+	} `json:"message"`
+}
+
+// parse validates the wire fields
+func (w *registrationWire) parse() (*validatorRegistration, error) {
+	reg := &validatorRegistration{
+		Pubkey:       strings.ToLower(w.Message.Pubkey),
+		FeeRecipient: strings.ToLower(w.Message.FeeRecipient),
+	}
+	var err error
+	if reg.GasLimit, err = parseDecimalField("gas_limit", w.Message.GasLimit, true); err != nil {
+		return nil, err
+	}
+	if reg.Timestamp, err = parseDecimalField("timestamp", w.Message.Timestamp, true); err != nil {
+		return nil, err
+	}
+	if !reBLSKey.MatchString(reg.Pubkey) {
+		return nil, fmt.Errorf("pubkey %q is not a 48-byte BLS key", w.Message.Pubkey)
+	}
+	if !reAddress.MatchString(reg.FeeRecipient) {
+		return nil, fmt.Errorf("fee_recipient %q is not an address", w.Message.FeeRecipient)
+	}
+	reg.Time = time.Unix(int64(reg.Timestamp), 0).UTC().Format(time.RFC3339)
+	return reg, nil
+}
+
+// relayRegistration is one relay's answer about a validator
+type relayRegistration struct {
+	Relay        string                 `json:"relay"`
+	Status       string                 `json:"status"` // registered, not_registered or error
+	Registration *validatorRegistration `json:"registration,omitempty"`
+	LatencyMs    int64                  `json:"latency_ms"`
+	Error        string                 `json:"error,omitempty"`
+}
+
+// upcomingProposer is one entry of /relay/v1/builder/validators, merged across relays
+type upcomingProposer struct {
+	Slot           uint64                 `json:"slot"`
+	ValidatorIndex uint64                 `json:"validator_index"`
+	Registration   *validatorRegistration `json:"registration"`
+	Relays         []string               `json:"relays"`
+}
+
+// beaconValidator is the part of /eth/v1/beacon/states/head/validators/{id} we show
+type beaconValidator struct {
+	Index     string `json:"index"`
+	Balance   string `json:"balance"`
+	Status    string `json:"status"`
+	Validator struct {
+		Pubkey                string `json:"pubkey"`
+		WithdrawalCredentials string `json:"withdrawal_credentials"`
+		EffectiveBalance      string `json:"effective_balance"`
+		Slashed               bool   `json:"slashed"`
+		ActivationEpoch       string `json:"activation_epoch"`
+		ExitEpoch             string `json:"exit_epoch"`
+	} `json:"validator"`
+}
+
+// fetchBeaconValidator resolves an index or pubkey at head. found is false on a 404.
+func fetchBeaconValidator(id string) (v *beaconValidator, found bool, err error) {
+	raw, status, err := beaconGET("/eth/v1/beacon/states/head/validators/" + id)
+	if err != nil {
+		return nil, false, err
+	}
+	if status == http.StatusNotFound {
+		return nil, false, nil
+	}
+	if status/100 != 2 {
+		return nil, false, fmt.Errorf("beacon node returned HTTP %d", status)
+	}
+	var resp struct {
+		Data beaconValidator `json:"data"`
+	}
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return nil, false, fmt.Errorf("decode validator: %w", err)
+	}
+	return &resp.Data, true, nil
+}
+
+// relayRegistrationsFor asks every relay for a validator's registration.
+// A relay that says "no registration" is different from one that failed to answer.
+func relayRegistrationsFor(pubkey string) []relayRegistration {
+	results := relayFanout(relayRegistrationEndpoint + "?pubkey=" + url.QueryEscape(pubkey))
+	out := make([]relayRegistration, len(results))
+	for i, res := range results {
+		rr := relayRegistration{Relay: res.Relay, LatencyMs: res.LatencyMs}
+		switch {
+		case res.OK:
+			var w registrationWire
+			if err := json.Unmarshal(res.body, &w); err != nil {
+				rr.Status, rr.Error = "error", "unexpected response: "+err.Error()
+				break
+			}
+			reg, err := w.parse()
+			if err != nil {
+				rr.Status, rr.Error = "error", "invalid registration: "+err.Error()
+				break
+			}
+			rr.Status, rr.Registration = "registered", reg
+		case res.Status == http.StatusNotFound || (res.Status == http.StatusBadRequest && strings.Contains(strings.ToLower(res.Error), "no registration")):
+			rr.Status = "not_registered"
+		default:
+			rr.Status, rr.Error = "error", res.Error
+		}
+		out[i] = rr
+	}
+	return out
+}
+
+// relayUpcomingProposers merges /relay/v1/builder/validators across relays, by slot
+func relayUpcomingProposers() ([]*upcomingProposer, []relayResult) {
+	results := relayFanout(relayBuilderValidatorEndpoint)
+	bySlot := map[uint64]*upcomingProposer{}
+	for i := range results {
+		res := &results[i]
+		if !res.OK {
+			continue
+		}
+		var items []struct {
+			Slot           string           `json:"slot"`
+			ValidatorIndex string           `json:"validator_index"`
+			Entry          registrationWire `json:"entry"`
+		}
+		if err := json.Unmarshal(res.body, &items); err != nil {
+			res.OK, res.Error = false, "unexpected response: "+err.Error()
+			continue
+		}
+		for _, it := range items {
+			slot, err1 := strconv.ParseUint(it.Slot, 10, 64)
+			idx, err2 := strconv.ParseUint(it.ValidatorIndex, 10, 64)
+			reg, err3 := it.Entry.parse()
+			if err1 != nil || err2 != nil || err3 != nil {
+				res.Rejected++
+				continue
+			}
+			res.Count++
+			if p, ok := bySlot[slot]; ok {
+				p.Relays = append(p.Relays, res.Relay)
+				continue
+			}
+			bySlot[slot] = &upcomingProposer{Slot: slot, ValidatorIndex: idx, Registration: reg, Relays: []string{res.Relay}}
+		}
+	}
+	out := make([]*upcomingProposer, 0, len(bySlot))
+	for _, p := range bySlot {
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Slot < out[j].Slot })
+	return out, results
+}
+
+// handleValidator serves GET /api/validators/{pubkey_or_index}
+func handleValidator(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/api/validators/")
+	if id == "" {
+		writeErr(w, http.StatusBadRequest, "BAD_REQUEST", "Missing validator", "Use /api/validators/{index} or /api/validators/{0x pubkey}")
+		return
+	}
+	if strings.HasPrefix(id, "0x") {
+		if !reBLSKey.MatchString(id) {
+			writeErr(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid validator pubkey", "A validator pubkey is 0x followed by 96 hex characters")
+			return
+		}
+		id = strings.ToLower(id)
+	} else if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+		writeErr(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid validator", "Use a decimal validator index or a 0x pubkey")
+		return
+	}
+
+	val, found, err := fetchBeaconValidator(id)
+	if err != nil {
+		writeErr(w, http.StatusTooManyRequests, "BEACON", "Validator lookup failed", "Public beacon API may be rate limiting. Try again or point BEACON_API_URL to your own consensus client.")
+		return
+	}
+	if !found {
+		writeErr(w, http.StatusNotFound, "NOT_FOUND", "Unknown validator", "The beacon node has no validator with this index or pubkey (deposits take a while to be processed)")
+		return
+	}
+	pubkey := strings.ToLower(val.Validator.Pubkey)
+
+	regs := relayRegistrationsFor(pubkey)
+	registeredWith := []string{}
+	unavailable := []string{}
+	feeRecipients := map[string]bool{}
+	gasLimits := map[uint64]bool{}
+	var latest *validatorRegistration
+	for _, rr := range regs {
+		switch rr.Status {
+		case "registered":
+			registeredWith = append(registeredWith, rr.Relay)
+			feeRecipients[rr.Registration.FeeRecipient] = true
+			gasLimits[rr.Registration.GasLimit] = true
+			if latest == nil || rr.Registration.Timestamp > latest.Timestamp {
+				latest = rr.Registration
+			}
+		case "error":
+			unavailable = append(unavailable, rr.Relay)
+		}
+	}
+
+	// Is it proposing soon through any relay?
+	upcoming := []*upcomingProposer{}
+	all, _ := relayUpcomingProposers()
+	for _, p := range all {
+		if p.Registration.Pubkey == pubkey {
+			upcoming = append(upcoming, p)
+		}
+	}
+
+	writeOK(w, map[string]any{
+		"validator":           val,
+		"pubkey":              pubkey,
+		"registered":          len(registeredWith) > 0,
+		"registered_with":     registeredWith,
+		"relays_unavailable":  unavailable,
+		"latest_registration": latest,
+		"consistent":          len(feeRecipients) <= 1 && len(gasLimits) <= 1, // same fee recipient and gas limit everywhere
+		"relays":              regs,
+		"upcoming_proposals":  upcoming,
+	})
+}
+
+// handleRelayValidators serves GET /api/relays/validators - proposers registered for this and next epoch
+func handleRelayValidators(w http.ResponseWriter, r *http.Request) {
+	proposers, results := relayUpcomingProposers()
+	ok := 0
+	for _, res := range results {
+		if res.OK {
+			ok++
+		}
+	}
+	if ok == 0 {
+		writeErr(w, http.StatusTooManyRequests, "RELAY", "Failed to fetch registered proposers", "MEV relays may be rate limiting or unavailable")
+		return
+	}
+	writeOK(w, map[string]any{
+		"proposers": proposers,
+		"count":     len(proposers),
+		"relays":    results,
+	})
+}
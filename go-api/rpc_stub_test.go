@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// rpcAnswer answers one JSON-RPC call for a stub node: a result (nil is JSON null) or an error
+type rpcAnswer func(method string, params []json.RawMessage) (any, *rpcError)
+
+// stubRPC points rpcHTTP at a local node that answers single calls and batches with answer,
+// until the test ends
+func stubRPC(t *testing.T, answer rpcAnswer) {
+	t.Helper()
+	if rpcHealth == nil {
+		initHealthSources()
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		type call struct {
+			ID     int               `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		respond := func(c call) map[string]any {
+			out := map[string]any{"jsonrpc": "2.0", "id": c.ID}
+			if res, err := answer(c.Method, c.Params); err != nil {
+				out["error"] = err
+			} else {
+				out["result"] = res
+			}
+			return out
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		if bytes.HasPrefix(bytes.TrimSpace(body), []byte("[")) {
+			var calls []call
+			if err := json.Unmarshal(body, &calls); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			out := make([]map[string]any, len(calls))
+			for i, c := range calls {
+				out[i] = respond(c)
+			}
+			json.NewEncoder(w).Encode(out)
+			return
+		}
+		var c call
+		if err := json.Unmarshal(body, &c); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(respond(c))
+	}))
+
+	saved := rpcHTTP
+	rpcHTTP = srv.URL
+	t.Cleanup(func() {
+		rpcHTTP = saved
+		srv.Close()
+	})
+}
+
+// ethCallTarget reads the "to" and "data" of an eth_call's params
+func ethCallTarget(params []json.RawMessage) (to, data string) {
+	var tx struct{ To, Data string }
+	if len(params) > 0 {
+		_ = json.Unmarshal(params[0], &tx)
+	}
+	return tx.To, tx.Data
+}
@@ -6,9 +6,10 @@
 //   2. Victim's swap executes at worse price (they get sandwiched)
 //   3. Sell tokens AFTER the victim's swap (backrun) - attacker profits
 //
-// We detect this by scanning transaction receipts for Uniswap V2/V3 Swap events and looking
-// for the pattern: same address swaps in the same pool immediately before AND after a different
-// address. This is a heuristic - not all detected "sandwiches" are malicious (could be legit MEV
+// We detect this by scanning transaction receipts for DEX swap events (swap_decoders.go) and looking
+// for the pattern: the same attacker (same bot contract, sending account, or swap recipient)
+// swaps in a pool before AND after one or more other traders, and the second swap reverses the
+// first. This is a heuristic - not all detected "sandwiches" are malicious (could be legit MEV
 // or arbitrage), but it gives you a sense of how prevalent this behavior is.
 //
 // Educational note: Sandwich attacks are controversial. They extract value from regular users
@@ -20,6 +21,8 @@ package main
 import (
     "encoding/hex"
     "encoding/json"
+    "errors"
+    "math/big"
     "net/http"
     "sort"
     "strconv"
@@ -36,8 +39,9 @@ type block struct {
     Hash         string `json:"hash"`
     Timestamp    string `json:"timestamp"`
     Transactions []struct {
-        Hash string `json:"hash"`
-        From string `json:"from"`
+        Hash string  `json:"hash"`
+        From string  `json:"from"`
+        To   *string `json:"to"` // Contract the tx called (nil for contract creation)
     } `json:"transactions"`
 }
 
@@ -45,10 +49,13 @@ type block struct {
 // Receipts contain event logs which tell us what actually happened during the transaction.
 // For sandwich detection, we're hunting for Swap events in the logs.
 type receipt struct {
-    TransactionHash string `json:"transactionHash"`
-    Logs            []struct {
+    TransactionHash   string `json:"transactionHash"`
+    GasUsed           string `json:"gasUsed"`           // Gas the tx actually burned (hex)
+    EffectiveGasPrice string `json:"effectiveGasPrice"` // What the sender paid per gas, base fee + tip (hex)
+    Logs              []struct {
         Address string   `json:"address"` // Contract that emitted the event (the liquidity pool)
         Topics  []string `json:"topics"`  // First topic is the event signature hash
+        Data    string   `json:"data"`    // Non-indexed event fields, ABI-encoded (the swap amounts live here)
     } `json:"logs"`
 }
 
@@ -58,9 +65,29 @@ type receipt struct {
 type swapEvent struct {
     TxHash   string // Transaction hash that contains this swap
     TxFrom   string // Address that sent the transaction (potential attacker or victim)
-    Pool     string // Liquidity pool contract address (e.g., WETH/USDC pair)
+    TxTo     string // Contract the transaction called (a router, or an MEV bot's own contract)
+    Pool     string // Liquidity pool (e.g., WETH/USDC pair) - a pool id for Uniswap V4
+    Contract string // Contract that emitted the log and holds the tokens: the pool, or the V4 PoolManager / Balancer Vault
     TxIndex  int    // Position of the transaction in the block (critical for ordering)
     LogIndex int    // Position of the log within the transaction (for tie-breaking)
+
+    // From the event: who called the pool and who got the output tokens.
+    // For bots these are usually the bot contract, even when the EOA sending the tx changes.
+    Sender    string
+    Recipient string
+
+    // What the trader did, normalized by the decoder for this DEX (see swap_decoders.go).
+    // Tokens are addresses when we know them, otherwise pool-relative names like "token0" or "coin2".
+    Dex          string   // "uniswap_v2", "uniswap_v3", "curve", "balancer_v2", ...
+    TokenIn      string   // Token the trader paid
+    TokenOut     string   // Token the trader received
+    AmountIn     *big.Int // Raw units (nil if the log data couldn't be decoded)
+    AmountOut    *big.Int
+    SqrtPriceX96 *big.Int // Concentrated-liquidity pools: price after the swap, as sqrt(token1/token0) * 2^96
+
+    // The transaction's gas bill (from its receipt), so we can charge it to the attacker
+    TxGasUsed  uint64
+    TxGasPrice *big.Int
 }
 
 // sandwich represents a detected sandwich attack with all the juicy details.
@@ -69,11 +96,58 @@ type swapEvent struct {
 type sandwich struct {
     Pool     string `json:"pool"`     // Which liquidity pool was targeted
     Attacker string `json:"attacker"` // Address that executed the sandwich
-    Victim   string `json:"victim"`   // Address that got sandwiched (poor soul)
+    Victim   string `json:"victim"`   // Address that got sandwiched (poor soul) - the first one, if several
     PreTx    string `json:"preTx"`    // Frontrun transaction hash
-    VictimTx string `json:"victimTx"` // The sandwiched transaction
+    VictimTx string `json:"victimTx"` // The sandwiched transaction (first victim)
     PostTx   string `json:"postTx"`   // Backrun transaction hash
     Block    string `json:"block"`    // Block number where this happened
+    Dex      string `json:"dex"`      // Protocol the swaps were decoded as (see swap_decoders.go)
+
+    MatchedOn    string           `json:"matchedOn"`    // What tied frontrun and backrun together: "contract" (tx.to), "eoa" (tx.from) or "swap" (Swap sender/recipient)
+    AttackerEOAs []string         `json:"attackerEOAs"` // Accounts that sent the frontrun/backrun (bots rotate these)
+    Victims      []sandwichVictim `json:"victims"`      // Every swap caught between frontrun and backrun
+    Pools        []string         `json:"pools"`        // Every pool the same frontrun/backrun pair sandwiched (multi-hop routes)
+
+    Estimate *sandwichEstimate `json:"estimate,omitempty"` // Profit/impact numbers (nil if amounts couldn't be decoded)
+}
+
+// sandwichVictim is one swap caught in the middle of a sandwich
+type sandwichVictim struct {
+    Address    string  `json:"address"`
+    Tx         string  `json:"tx"`
+    Pool       string  `json:"pool"`
+    TokenIn    string  `json:"tokenIn,omitempty"`
+    AmountIn   string  `json:"amountIn,omitempty"`
+    AmountOut  string  `json:"amountOut,omitempty"`
+    Rate       float64 `json:"rate,omitempty"`       // tokens out per token in
+    WorseByPct float64 `json:"worseByPct,omitempty"` // vs the frontrun's rate
+}
+
+// sandwichEstimate puts numbers on a detected sandwich, all in the pool's own tokens.
+// Token amounts are raw integer units (no decimals applied) - we'd need each token's decimals()
+// to turn them into human amounts.
+type sandwichEstimate struct {
+    // Attacker's net result per token from frontrun + backrun (positive = gained, negative = spent).
+    // A classic sandwich ends roughly flat in one token and up in the other.
+    AttackerNet map[string]string `json:"attackerNet"`
+
+    // What the attacker paid to get the two txs included (gasUsed * effectiveGasPrice).
+    // Direct payments to the builder (coinbase transfers) are NOT included - those are in the tx value.
+    GasUsed    uint64 `json:"gasUsed"`
+    GasCostWei string `json:"gasCostWei"`
+    GasCostEth string `json:"gasCostEth"`
+
+    // The victim's trade, and how much worse it executed than the frontrun right before it
+    VictimTokenIn    string  `json:"victimTokenIn"`
+    VictimAmountIn   string  `json:"victimAmountIn"`
+    VictimAmountOut  string  `json:"victimAmountOut"`
+    FrontrunRate     float64 `json:"frontrunRate"`     // tokens out per token in for the frontrun
+    VictimRate       float64 `json:"victimRate"`       // tokens out per token in for the victim
+    VictimWorseByPct float64 `json:"victimWorseByPct"` // (frontrunRate - victimRate) / frontrunRate
+
+    // Concentrated-liquidity pools only: how far the frontrun pushed the pool price, measured against
+    // the price after the backrun (which puts the pool back close to where it started)
+    FrontrunPriceMovePct *float64 `json:"frontrunPriceMovePct,omitempty"`
 }
 
 // keccakTopic computes the Keccak-256 hash of an event signature to get the topic0.
@@ -91,34 +165,19 @@ func keccakTopic(signature string) string {
     return "0x" + hex.EncodeToString(out[:])
 }
 
-var (
-    // swapTopicV2 is the Keccak hash of the Uniswap V2 Swap event signature.
-    // V2 uses: Swap(address indexed sender, uint amount0In, uint amount1In, uint amount0Out, uint amount1Out, address indexed to)
-    swapTopicV2 = strings.ToLower(keccakTopic("Swap(address,uint256,uint256,uint256,uint256,address)"))
-
-    // swapTopicV3 is the Keccak hash of the Uniswap V3 Swap event signature.
-    // V3 uses: Swap(address indexed sender, address indexed recipient, int256 amount0, int256 amount1, uint160 sqrtPriceX96, uint128 liquidity, int24 tick)
-    // Note: V3 is more complex because it uses concentrated liquidity and tick math
-    swapTopicV3 = strings.ToLower(keccakTopic("Swap(address,address,int256,int256,uint160,uint128,int24)"))
-)
-
-// sandwichMaxTx limits how many transactions we'll scan per block to avoid timeouts.
-// Blocks can have 300+ transactions, and fetching receipts for each one is SLOW (lots of RPC calls).
-// We default to 120 txs which should catch most sandwiches while keeping response time reasonable.
-// You can override this with SANDWICH_MAX_TX env var, but don't go crazy - 1000 txs = very slow!
+// sandwichMaxTx optionally limits how many transactions we'll scan per block.
+// Receipts now come from eth_getBlockReceipts (or batches), so scanning a whole block is cheap
+// and that's the default (0 = no limit). Set SANDWICH_MAX_TX if your RPC provider still struggles.
 var sandwichMaxTx = func() int {
-    s := envOr("SANDWICH_MAX_TX", "120")
+    s := envOr("SANDWICH_MAX_TX", "0")
     n, err := strconv.Atoi(s)
-    if err != nil {
-        return 120 // If someone puts "banana" in the env var, just use default
+    if err != nil || n <= 0 {
+        return 0 // If someone puts "banana" in the env var, just scan everything
     }
-    // Clamp to reasonable range - we don't want to timeout or scan forever
+    // Clamp to a sane floor - a cap this small would miss most sandwiches
     if n < 10 {
         n = 10
     }
-    if n > 1000 {
-        n = 1000
-    }
     return n
 }()
 
@@ -146,6 +205,9 @@ func fetchReceipt(txHash string) (*receipt, error) {
     if err != nil {
         return nil, err
     }
+    if string(raw) == "null" {
+        return nil, errors.New("receipt not found")
+    }
     var r receipt
     if err := json.Unmarshal(raw, &r); err != nil {
         return nil, err
@@ -153,50 +215,102 @@ func fetchReceipt(txHash string) (*receipt, error) {
     return &r, nil
 }
 
-// collectSwaps scans through the block's transactions and extracts all Uniswap V2/V3 swap events.
-// This is the heavy lifting function - it makes a LOT of RPC calls (one per transaction) to get receipts.
-// That's why we limit it with sandwichMaxTx. On mainnet, this can take 5-10 seconds for a full block!
+// fetchReceiptsBatch grabs many receipts in as few round-trips as possible using JSON-RPC batches.
+// The result lines up with txHashes; a nil entry means that particular receipt couldn't be fetched.
+func fetchReceiptsBatch(txHashes []string) ([]*receipt, error) {
+    calls := make([]rpcBatchRequest, len(txHashes))
+    for i, h := range txHashes {
+        calls[i] = rpcBatchRequest{Method: "eth_getTransactionReceipt", Params: []any{h}}
+    }
+    results, err := rpcBatchCall(calls)
+
+    out := make([]*receipt, len(txHashes))
+    for i, res := range results {
+        if res.Err != nil || len(res.Result) == 0 || string(res.Result) == "null" {
+            continue
+        }
+        var r receipt
+        if json.Unmarshal(res.Result, &r) == nil {
+            out[i] = &r
+        }
+    }
+    return out, err
+}
+
+// collectSwaps scans through the block's transactions and extracts every swap event we can decode.
+// This is the heavy lifting function - it needs one receipt per transaction. fetchBlockReceipts
+// gets them with a single eth_getBlockReceipts call when the node supports it, and falls back to
+// batched/concurrent lookups otherwise. The returned stats say how many receipts were missing so
+// callers can report an incomplete scan instead of hiding it.
 //
-// We're looking for event logs where topic[0] matches either the V2 or V3 Swap event signature.
+// We're looking for event logs where topic[0] matches one of the registered swap decoders.
 // Each swap gets recorded with its position in the block (txIndex, logIndex) because ordering
 // is CRITICAL for detecting sandwiches. If tx #5 and tx #7 are from the same address with tx #6
 // in between, that's a potential sandwich!
-func collectSwaps(b *block) ([]swapEvent, error) {
-    var swaps []swapEvent
-    maxN := len(b.Transactions)
-    if sandwichMaxTx < maxN {
-        maxN = sandwichMaxTx // Don't scan more than our limit
+func collectSwaps(b *block) ([]swapEvent, receiptStats, error) {
+    receipts, stats, err := scanBlockReceipts(b)
+    if err != nil {
+        return nil, stats, err
+    }
+    return swapsFromReceipts(b, receipts), stats, nil
+}
+
+// scanBlockReceipts fetches the receipts every MEV scan works from (capped by SANDWICH_MAX_TX).
+// It only fails when none at all could be fetched - a few missing ones are reported in the stats.
+func scanBlockReceipts(b *block) ([]*receipt, receiptStats, error) {
+    receipts, stats := fetchBlockReceipts(b, sandwichMaxTx)
+    if stats.Scanned > 0 && stats.Missing == stats.Scanned {
+        return nil, stats, errors.New("no receipts could be fetched for this block")
     }
+    return receipts, stats, nil
+}
+
+// swapsFromReceipts decodes the swaps out of already-fetched receipts (lined up with b.Transactions)
+func swapsFromReceipts(b *block, receipts []*receipt) []swapEvent {
+    var swaps []swapEvent
 
     // Loop through transactions in order - ORDER MATTERS for sandwich detection!
-    for idx := 0; idx < maxN; idx++ {
+    for idx := 0; idx < len(receipts); idx++ {
         tx := b.Transactions[idx]
-        // Fetch the receipt to see what events were emitted
-        rcpt, err := fetchReceipt(tx.Hash)
-        if err != nil || rcpt == nil {
-            continue // Skip if receipt fetch fails (might be pending or node issue)
+        rcpt := receipts[idx]
+        if rcpt == nil {
+            continue // Counted in stats.Missing - reported back to the caller
         }
 
         // Scan through all event logs in this transaction
         for logIdx, lg := range rcpt.Logs {
-            if len(lg.Topics) == 0 {
-                continue // Malformed log, skip it
-            }
-
-            // topic[0] is the event signature hash - check if it's a Swap event
-            topic := strings.ToLower(lg.Topics[0])
-            if topic != swapTopicV2 && topic != swapTopicV3 {
+            // topic[0] is the event signature hash - is it one of the swap events we know?
+            ds, ok := decodeSwapLog(lg.Address, lg.Topics, lg.Data)
+            if !ok {
                 continue // Not a swap, we don't care about it
             }
 
             // Found a swap! Record all the details we need for sandwich detection
-            swaps = append(swaps, swapEvent{
+            ev := swapEvent{
                 TxHash:   strings.ToLower(tx.Hash),
                 TxFrom:   strings.ToLower(tx.From),        // Who sent this tx?
-                Pool:     strings.ToLower(lg.Address),     // Which pool did they swap in?
+                Pool:     ds.Pool,                         // Which pool did they swap in?
+                Contract: strings.ToLower(lg.Address),     // Who emitted the event (and holds the tokens)?
                 TxIndex:  idx,                             // Where in the block?
                 LogIndex: logIdx,                          // Where in the transaction?
-            })
+
+                Sender:       ds.Sender,
+                Recipient:    ds.Recipient,
+                Dex:          ds.Protocol,
+                TokenIn:      ds.TokenIn,
+                TokenOut:     ds.TokenOut,
+                AmountIn:     ds.AmountIn,
+                AmountOut:    ds.AmountOut,
+                SqrtPriceX96: ds.SqrtPriceX96,
+            }
+            if tx.To != nil {
+                ev.TxTo = strings.ToLower(*tx.To)
+            }
+            if gu, err := parseHexUint64(rcpt.GasUsed); err == nil {
+                ev.TxGasUsed = gu
+            }
+            ev.TxGasPrice = hexBig(&rcpt.EffectiveGasPrice)
+            swaps = append(swaps, ev)
         }
     }
 
@@ -209,14 +323,24 @@ func collectSwaps(b *block) ([]swapEvent, error) {
         return swaps[i].TxIndex < swaps[j].TxIndex
     })
 
-    return swaps, nil
+    // Put real token addresses on V2/V3-style swaps (one cached lookup per pool)
+    resolveSwapTokens(swaps)
+
+    return swaps
 }
 
 // detectSandwiches analyzes the list of swaps and finds sandwich attack patterns.
-// The algorithm is pretty simple but effective:
-//   1. Group swaps by pool (attackers sandwich in the same pool)
-//   2. For each pool, look for sequences where address A swaps, then address B swaps, then address A swaps again
-//   3. If we find this pattern, it's likely a sandwich (A frontran B, then backran B)
+// The algorithm:
+//   1. Merge each transaction's swaps in one pool into a single "leg" (a route can touch a pool twice)
+//   2. Group legs by pool and walk them in block order
+//   3. A frontrun leg, then up to sandwichMaxVictims victim legs, then a backrun leg from the SAME
+//      attacker that trades in the opposite direction = sandwich
+//   4. Sandwiches that share a frontrun and backrun tx across several pools are one multi-hop attack
+//
+// "Same attacker" doesn't just mean the same sending address. Bots rotate EOAs but call their own
+// contract, and that contract is what shows up as the swap's sender/recipient. So two legs match if
+// they share the called contract (tx.to), the sender, or the swap's sender/recipient - ignoring
+// public routers that everyone (victims included) goes through.
 //
 // This is a heuristic! Not every detected "sandwich" is malicious:
 //   - Could be arbitrage (buying low in one pool, selling high in another)
@@ -225,52 +349,363 @@ func collectSwaps(b *block) ([]swapEvent, error) {
 //
 // But in practice, most of these patterns ARE sandwiches. The MEV bots are VERY active.
 func detectSandwiches(swaps []swapEvent, blockNum string) []sandwich {
-    // Group swaps by pool address - we only care about swaps in the same pool
-    grouped := map[string][]swapEvent{}
+    // Pools seen in the block - a V2 swap's recipient is often the NEXT pool in a route, which says
+    // nothing about who is trading
+    pools := map[string]bool{}
     for _, s := range swaps {
-        grouped[s.Pool] = append(grouped[s.Pool], s)
+        pools[s.Pool] = true
     }
 
-    var out []sandwich
+    // Group legs by pool address - we only care about swaps in the same pool
+    grouped := map[string][]swapEvent{}
+    var poolOrder []string
+    for _, leg := range mergeSwapLegs(swaps) {
+        if _, ok := grouped[leg.Pool]; !ok {
+            poolOrder = append(poolOrder, leg.Pool)
+        }
+        grouped[leg.Pool] = append(grouped[leg.Pool], leg)
+    }
 
-    // For each pool, scan through the swap sequence looking for sandwich patterns
-    for pool, seq := range grouped {
-        // We need at least 3 swaps to have a sandwich (pre, victim, post)
-        for i := 0; i+2 < len(seq); i++ {
-            pre := seq[i]       // Potential frontrun
-            victim := seq[i+1]  // Potential victim
-            post := seq[i+2]    // Potential backrun
+    var found []sandwich
 
-            // Sanity check - all three should be in the same pool (they are, by construction, but be safe)
-            if pre.Pool != victim.Pool || victim.Pool != post.Pool {
+    // For each pool, scan through the leg sequence looking for sandwich patterns
+    for _, pool := range poolOrder {
+        seq := grouped[pool]
+        for i := 0; i+2 < len(seq); {
+            sw, next := matchSandwichAt(seq, i, pools, blockNum)
+            if sw == nil {
+                i++
                 continue
             }
+            found = append(found, *sw)
+            i = next // Skip past the backrun so we don't double-count overlapping sandwiches
+        }
+    }
 
-            // Make sure we have valid addresses (shouldn't happen, but handle gracefully)
-            if pre.TxFrom == "" || post.TxFrom == "" || victim.TxFrom == "" {
-                continue
+    // One frontrun/backrun pair that hit several pools is a single multi-hop sandwich
+    var out []sandwich
+    byPair := map[string]int{}
+    for _, sw := range found {
+        key := sw.PreTx + "/" + sw.PostTx
+        if idx, ok := byPair[key]; ok {
+            merged := &out[idx]
+            merged.Pools = append(merged.Pools, sw.Pool)
+            seen := map[string]bool{}
+            for _, v := range merged.Victims {
+                seen[v.Tx+v.Pool] = true
+            }
+            for _, v := range sw.Victims {
+                if !seen[v.Tx+v.Pool] {
+                    merged.Victims = append(merged.Victims, v)
+                }
             }
+            continue
+        }
+        byPair[key] = len(out)
+        out = append(out, sw)
+    }
+    return out
+}
+
+// sandwichMaxVictims is how many swaps we allow between a frontrun and its backrun.
+// Bots happily wrap several victims at once when they all trade in the same direction.
+const sandwichMaxVictims = 8
+
+// publicRouters are contracts everyone trades through. Sharing one of these says nothing about
+// who is behind two transactions, so they never count as an attacker identity.
+var publicRouters = map[string]bool{
+    "0x7a250d5630b4cf539739df2c5dacb4c659f2488d": true, // Uniswap V2 Router02
+    "0xe592427a0aece92de3edee1f18e0157c05861564": true, // Uniswap V3 SwapRouter
+    "0x68b3465833fb72a70ecdf485e0e4c7bd8665fc45": true, // Uniswap SwapRouter02
+    "0xef1c6e67703c7bd7107eed8303fbe6ec2554bf6b": true, // Uniswap Universal Router (old)
+    "0x3fc91a3afd70395cd496c647d5a6cc9d4b2b7fad": true, // Uniswap Universal Router
+    "0x66a9893cc07d91d95644aedd05d03f95e1dba8af": true, // Uniswap Universal Router (V4)
+    "0xd9e1ce17f2641f24ae83637ab66a2cca9c378b9f": true, // SushiSwap Router
+    "0x1111111254eeb25477b68fb85ed929f73a960582": true, // 1inch v5
+    "0x111111125421ca6dc452d289314280a0f8842a65": true, // 1inch v6
+    "0xdef1c0ded9bec7f1a1670819833240f027b25eff": true, // 0x Exchange Proxy
+    "0xdef171fe48cf0115b1d80b88dc8eab59176fee57": true, // ParaSwap
+    "0x881d40237659c251811cec9c364ef91dc08d300c": true, // MetaMask Swap Router
+    "0x9008d19f58aabd9ed0d60971565aa8510560ab41": true, // CoW Protocol settlement
+}
 
-            // THE SANDWICH PATTERN: pre and post from same address, victim from different address
-            // This is the smoking gun! If address X swaps before and after address Y, X probably sandwiched Y.
-            if pre.TxFrom == post.TxFrom && pre.TxFrom != victim.TxFrom {
-                out = append(out, sandwich{
-                    Pool:     pool,
-                    Attacker: pre.TxFrom,
-                    Victim:   victim.TxFrom,
-                    PreTx:    pre.TxHash,
-                    VictimTx: victim.TxHash,
-                    PostTx:   post.TxHash,
-                    Block:    blockNum,
-                })
-                // Skip ahead by 2 since we just consumed these swaps
-                // This prevents detecting overlapping sandwiches (which would double-count)
-                i += 2
+// topicAddress pulls an address out of a 32-byte indexed topic
+func topicAddress(topic string) string {
+    t := strings.ToLower(strings.TrimPrefix(topic, "0x"))
+    if len(t) < 40 {
+        return ""
+    }
+    return "0x" + t[len(t)-40:]
+}
+
+// mergeSwapLegs folds each transaction's swaps in the same pool into one leg (amounts summed),
+// keeping block order. swaps must already be sorted by (TxIndex, LogIndex).
+func mergeSwapLegs(swaps []swapEvent) []swapEvent {
+    var legs []swapEvent
+    at := map[string]int{} // tx+pool -> index in legs
+    for _, s := range swaps {
+        key := s.TxHash + s.Pool
+        idx, ok := at[key]
+        if !ok {
+            at[key] = len(legs)
+            legs = append(legs, s)
+            continue
+        }
+        leg := &legs[idx]
+        if leg.AmountIn != nil && s.AmountIn != nil {
+            // Net the two swaps per token, then read the direction back off the result
+            net := traderNet(*leg)
+            for tok, amt := range traderNet(s) {
+                if cur, ok := net[tok]; ok {
+                    net[tok] = new(big.Int).Add(cur, amt)
+                } else {
+                    net[tok] = amt
+                }
+            }
+            leg.TokenIn, leg.TokenOut, leg.AmountIn, leg.AmountOut = "", "", nil, nil
+            var ins, outs int
+            for tok, amt := range net {
+                switch amt.Sign() {
+                case -1:
+                    ins++
+                    leg.TokenIn, leg.AmountIn = tok, new(big.Int).Neg(amt)
+                case 1:
+                    outs++
+                    leg.TokenOut, leg.AmountOut = tok, amt
+                }
             }
+            if ins != 1 || outs != 1 {
+                leg.TokenIn, leg.TokenOut, leg.AmountIn, leg.AmountOut = "", "", nil, nil
+            }
+        } else {
+            leg.AmountIn, leg.AmountOut = nil, nil
+        }
+        if s.SqrtPriceX96 != nil {
+            leg.SqrtPriceX96 = s.SqrtPriceX96 // the pool's price after the tx's last swap in it
         }
     }
+    return legs
+}
 
-    return out
+// attackerKeys lists the identities a leg can be tied to, strongest first
+func attackerKeys(s swapEvent, pools map[string]bool) [][2]string {
+    var keys [][2]string
+    if s.TxTo != "" && !publicRouters[s.TxTo] {
+        keys = append(keys, [2]string{"contract", s.TxTo})
+    }
+    if s.TxFrom != "" {
+        keys = append(keys, [2]string{"eoa", s.TxFrom})
+    }
+    for _, addr := range []string{s.Sender, s.Recipient} {
+        if addr != "" && !publicRouters[addr] && !pools[addr] && addr != s.TxTo && addr != s.TxFrom {
+            keys = append(keys, [2]string{"swap", addr})
+        }
+    }
+    return keys
+}
+
+// sharedAttackerKey returns the first identity legs a and b have in common
+func sharedAttackerKey(a, b swapEvent, pools map[string]bool) (kind, addr string) {
+    bKeys := map[string]bool{}
+    for _, k := range attackerKeys(b, pools) {
+        bKeys[k[1]] = true
+    }
+    for _, k := range attackerKeys(a, pools) {
+        if bKeys[k[1]] {
+            return k[0], k[1]
+        }
+    }
+    return "", ""
+}
+
+// hasAttackerKey reports whether leg s carries identity addr
+func hasAttackerKey(s swapEvent, addr string, pools map[string]bool) bool {
+    for _, k := range attackerKeys(s, pools) {
+        if k[1] == addr {
+            return true
+        }
+    }
+    return false
+}
+
+// matchSandwichAt tries to read a sandwich starting with seq[i] as the frontrun.
+// It returns the sandwich and the index to continue scanning from, or nil.
+func matchSandwichAt(seq []swapEvent, i int, pools map[string]bool, blockNum string) (*sandwich, int) {
+    pre := seq[i]
+    preDir, _, _, preKnown := swapDirection(pre)
+
+    for j := i + 2; j < len(seq) && j-i-1 <= sandwichMaxVictims; j++ {
+        post := seq[j]
+        kind, attacker := sharedAttackerKey(pre, post, pools)
+        if attacker == "" || post.TxIndex == pre.TxIndex {
+            continue
+        }
+
+        // The backrun undoes the frontrun: it must trade the other way
+        if postDir, _, _, ok := swapDirection(post); ok && preKnown && postDir == preDir {
+            return nil, 0 // same attacker, same direction - a split frontrun, not a backrun
+        }
+
+        // Everything in between is a victim: someone else, trading the same way as the frontrun
+        victims := seq[i+1 : j]
+        ok := true
+        for _, v := range victims {
+            if v.TxIndex == pre.TxIndex || v.TxIndex == post.TxIndex || hasAttackerKey(v, attacker, pools) {
+                ok = false
+                break
+            }
+            if vDir, _, _, known := swapDirection(v); known && preKnown && vDir != preDir {
+                ok = false
+                break
+            }
+        }
+        if !ok {
+            return nil, 0
+        }
+
+        sw := &sandwich{
+            Pool:      pre.Pool,
+            Attacker:  attacker,
+            Victim:    victims[0].TxFrom,
+            PreTx:     pre.TxHash,
+            VictimTx:  victims[0].TxHash,
+            PostTx:    post.TxHash,
+            Block:     blockNum,
+            Dex:       pre.Dex,
+            MatchedOn: kind,
+            Pools:     []string{pre.Pool},
+            Estimate:  estimateSandwich(pre, victims[0], post),
+        }
+        sw.AttackerEOAs = []string{pre.TxFrom}
+        if post.TxFrom != pre.TxFrom {
+            sw.AttackerEOAs = append(sw.AttackerEOAs, post.TxFrom)
+        }
+
+        frontrunRate := 0.0
+        if sw.Estimate != nil {
+            frontrunRate = sw.Estimate.FrontrunRate
+        }
+        for _, v := range victims {
+            sv := sandwichVictim{Address: v.TxFrom, Tx: v.TxHash, Pool: v.Pool}
+            if dir, in, out, known := swapDirection(v); known {
+                sv.TokenIn, sv.AmountIn, sv.AmountOut = dir, in.String(), out.String()
+                sv.Rate = swapRate(in, out)
+                if frontrunRate > 0 {
+                    sv.WorseByPct = roundTo((frontrunRate-sv.Rate)*100/frontrunRate, 4)
+                }
+            }
+            sw.Victims = append(sw.Victims, sv)
+        }
+        return sw, j + 1
+    }
+    return nil, 0
+}
+
+// swapDirection reports which token the trader paid in and the in/out amounts (both positive)
+func swapDirection(s swapEvent) (tokenIn string, amountIn, amountOut *big.Int, ok bool) {
+    if s.AmountIn == nil || s.AmountOut == nil || s.TokenIn == "" {
+        return "", nil, nil, false
+    }
+    return s.TokenIn, s.AmountIn, s.AmountOut, true
+}
+
+// traderNet is a swap's effect on the trader per token: -in for the token paid, +out for the token received
+func traderNet(s swapEvent) map[string]*big.Int {
+    net := map[string]*big.Int{}
+    if s.AmountIn == nil || s.AmountOut == nil {
+        return net
+    }
+    net[s.TokenIn] = new(big.Int).Neg(s.AmountIn)
+    if cur, ok := net[s.TokenOut]; ok {
+        net[s.TokenOut] = new(big.Int).Add(cur, s.AmountOut)
+    } else {
+        net[s.TokenOut] = new(big.Int).Set(s.AmountOut)
+    }
+    return net
+}
+
+// swapRate is tokens out per token in (raw units)
+func swapRate(in, out *big.Int) float64 {
+    if in.Sign() == 0 {
+        return 0
+    }
+    f, _ := new(big.Rat).SetFrac(out, in).Float64()
+    return f
+}
+
+// v3Price turns sqrtPriceX96 into the pool price (token1 per token0, raw units)
+func v3Price(sqrtPriceX96 *big.Int) float64 {
+    sq := new(big.Float).Quo(new(big.Float).SetInt(sqrtPriceX96), new(big.Float).SetInt(new(big.Int).Lsh(big.NewInt(1), 96)))
+    f, _ := new(big.Float).Mul(sq, sq).Float64()
+    return f
+}
+
+// estimateSandwich puts numbers on a sandwich from the three swaps' decoded amounts.
+//
+// Attacker profit: add up what the frontrun and backrun paid and received, per token. A swap
+// that the decoder couldn't read gives no estimate at all.
+//
+// Victim impact: the frontrun trades in the same direction as the victim just before it, so its
+// rate is roughly what the victim would have got. The gap between the two rates is what the
+// frontrun (plus the victim's own size) cost them.
+func estimateSandwich(pre, victim, post swapEvent) *sandwichEstimate {
+    if pre.AmountIn == nil || post.AmountIn == nil || victim.AmountIn == nil {
+        return nil
+    }
+    est := &sandwichEstimate{AttackerNet: map[string]string{}}
+    net := traderNet(pre)
+    for tok, amt := range traderNet(post) {
+        if cur, ok := net[tok]; ok {
+            net[tok] = new(big.Int).Add(cur, amt)
+        } else {
+            net[tok] = amt
+        }
+    }
+    for tok, amt := range net {
+        est.AttackerNet[tok] = amt.String()
+    }
+
+    // Gas for the frontrun and backrun (the same tx can hold both legs - only count it once)
+    gasCost := new(big.Int)
+    seen := map[string]bool{}
+    for _, leg := range []swapEvent{pre, post} {
+        if seen[leg.TxHash] {
+            continue
+        }
+        seen[leg.TxHash] = true
+        est.GasUsed += leg.TxGasUsed
+        if leg.TxGasPrice != nil {
+            gasCost.Add(gasCost, new(big.Int).Mul(new(big.Int).SetUint64(leg.TxGasUsed), leg.TxGasPrice))
+        }
+    }
+    est.GasCostWei = gasCost.String()
+    est.GasCostEth = weiToETHDecimal(gasCost)
+
+    vIn, vAmtIn, vAmtOut, ok := swapDirection(victim)
+    if !ok {
+        return est
+    }
+    est.VictimTokenIn, est.VictimAmountIn, est.VictimAmountOut = vIn, vAmtIn.String(), vAmtOut.String()
+    est.VictimRate = swapRate(vAmtIn, vAmtOut)
+
+    if pIn, pAmtIn, pAmtOut, ok := swapDirection(pre); ok && pIn == vIn {
+        est.FrontrunRate = swapRate(pAmtIn, pAmtOut)
+        if est.FrontrunRate > 0 {
+            est.VictimWorseByPct = roundTo((est.FrontrunRate-est.VictimRate)*100/est.FrontrunRate, 4)
+        }
+    }
+
+    if pre.SqrtPriceX96 != nil && post.SqrtPriceX96 != nil && post.SqrtPriceX96.Sign() > 0 {
+        after, base := v3Price(pre.SqrtPriceX96), v3Price(post.SqrtPriceX96)
+        if base > 0 {
+            move := roundTo((after-base)*100/base, 4)
+            if move < 0 {
+                move = -move
+            }
+            est.FrontrunPriceMovePct = &move
+        }
+    }
+    return est
 }
 
 // handleSandwich is the HTTP handler for GET /api/mev/sandwich?block=<number|latest>
@@ -296,7 +731,7 @@ func handleSandwich(w http.ResponseWriter, r *http.Request) {
 
     // Step 2: Scan through transactions and collect all Swap events
     // This is the slow part - we're making tons of RPC calls here
-    swaps, err := collectSwaps(b)
+    swaps, stats, err := collectSwaps(b)
     if err != nil {
         writeErr(w, http.StatusInternalServerError, "EL_RECEIPTS", "Failed to scan receipts", "Node may still be syncing or pruning receipts")
         return
@@ -311,7 +746,8 @@ func handleSandwich(w http.ResponseWriter, r *http.Request) {
         "blockHash":  b.Hash,
         "swapCount":  len(swaps), // Total swaps found
         "sandwiches": sandwiches,  // Detected sandwiches (could be empty array)
+        "receipts":   stats,       // How receipts were fetched and how many are missing
         "sources":    sourcesInfo(),
-        "note":       "Heuristic: same address swaps before and after a victim in the same pool (Uniswap V2/V3).",
+        "note":       "Heuristic: the same attacker (bot contract, sender or swap recipient) swaps before and after one or more victims in the same pool and reverses direction (Uniswap V2/V3/V4 and V3 forks, Curve, Balancer V2; multi-pool routes are merged). Estimates are in raw pool token units; attacker gas excludes direct builder payments.",
     })
 }
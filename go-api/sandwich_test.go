@@ -0,0 +1,257 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// Synthetic blocks for the sandwich detector: every test builds a block and its receipts from
+// Uniswap V2 Swap logs and runs them through swapsFromReceipts -> detectSandwiches, the same path
+// handleSandwich takes.
+
+var (
+	tokA = "0x00000000000000000000000000000000000000aa"
+	tokB = "0x00000000000000000000000000000000000000bb"
+	tokC = "0x00000000000000000000000000000000000000cc"
+
+	poolAB = "0x0000000000000000000000000000000000000a0b"
+	poolBC = "0x0000000000000000000000000000000000000b0c"
+
+	botContract = "0x00000000000000000000000000000000000b0770"
+	router      = "0x7a250d5630b4cf539739df2c5dacb4c659f2488d" // Uniswap V2 Router02, a public router
+)
+
+// testAddr makes a distinct address for a test account
+func testAddr(n int) string {
+	return fmt.Sprintf("0x%040x", 0xe0a0000+n)
+}
+
+func init() {
+	// Pool token lookups would go to the node; answer them from the cache instead
+	poolTokenCache[poolAB] = &poolTokens{Token0: tokA, Token1: tokB}
+	poolTokenCache[poolBC] = &poolTokens{Token0: tokB, Token1: tokC}
+}
+
+// synthSwap is one V2 swap: the trader pays amountIn of tokenIn and gets amountOut of the other token
+type synthSwap struct {
+	pool      string
+	sender    string // who called the pool
+	recipient string // who got the output
+	tokenIn   string
+	amountIn  int64
+	amountOut int64
+}
+
+// synthTx is one transaction with its swaps
+type synthTx struct {
+	from, to string
+	swaps    []synthSwap
+}
+
+func word(v int64) string {
+	return fmt.Sprintf("%064x", v)
+}
+
+func topic(addr string) string {
+	return "0x000000000000000000000000" + strings.TrimPrefix(addr, "0x")
+}
+
+// buildBlock turns transactions into a block and index-aligned receipts
+func buildBlock(t *testing.T, txs []synthTx) (*block, []*receipt) {
+	t.Helper()
+	type jsonLog struct {
+		Address string   `json:"address"`
+		Topics  []string `json:"topics"`
+		Data    string   `json:"data"`
+	}
+	type jsonTx struct {
+		Hash string `json:"hash"`
+		From string `json:"from"`
+		To   string `json:"to"`
+	}
+	v2 := keccakTopic("Swap(address,uint256,uint256,uint256,uint256,address)")
+
+	var bTxs []jsonTx
+	var receipts []*receipt
+	for i, tx := range txs {
+		hash := fmt.Sprintf("0x%064x", i+1)
+		bTxs = append(bTxs, jsonTx{Hash: hash, From: tx.from, To: tx.to})
+		var logs []jsonLog
+		for _, s := range tx.swaps {
+			pt := poolTokenCache[s.pool]
+			in0, in1, out0, out1 := int64(0), int64(0), int64(0), int64(0)
+			if s.tokenIn == pt.Token0 {
+				in0, out1 = s.amountIn, s.amountOut
+			} else {
+				in1, out0 = s.amountIn, s.amountOut
+			}
+			logs = append(logs, jsonLog{
+				Address: s.pool,
+				Topics:  []string{v2, topic(s.sender), topic(s.recipient)},
+				Data:    "0x" + word(in0) + word(in1) + word(out0) + word(out1),
+			})
+		}
+		raw, _ := json.Marshal(map[string]any{
+			"transactionHash":   hash,
+			"gasUsed":           "0x30d40",
+			"effectiveGasPrice": "0x3b9aca00",
+			"logs":              logs,
+		})
+		var r receipt
+		if err := json.Unmarshal(raw, &r); err != nil {
+			t.Fatal(err)
+		}
+		receipts = append(receipts, &r)
+	}
+
+	raw, _ := json.Marshal(map[string]any{"number": "0x100", "hash": "0xb10c", "transactions": bTxs})
+	var b block
+	if err := json.Unmarshal(raw, &b); err != nil {
+		t.Fatal(err)
+	}
+	return &b, receipts
+}
+
+func detect(t *testing.T, txs []synthTx) []sandwich {
+	t.Helper()
+	b, receipts := buildBlock(t, txs)
+	return detectSandwiches(swapsFromReceipts(b, receipts), b.Number)
+}
+
+// botSwap is a swap by the bot contract in one pool
+func botSwap(pool, tokenIn string, in, out int64) synthSwap {
+	return synthSwap{pool: pool, sender: botContract, recipient: botContract, tokenIn: tokenIn, amountIn: in, amountOut: out}
+}
+
+// userSwap is a swap by user n through the public router
+func userSwap(n int, pool, tokenIn string, in, out int64) synthTx {
+	return synthTx{from: testAddr(n), to: router, swaps: []synthSwap{
+		{pool: pool, sender: router, recipient: testAddr(n), tokenIn: tokenIn, amountIn: in, amountOut: out},
+	}}
+}
+
+func TestSandwichBotContractRotatingEOAs(t *testing.T) {
+	got := detect(t, []synthTx{
+		{from: testAddr(1), to: botContract, swaps: []synthSwap{botSwap(poolAB, tokA, 100, 90)}},
+		userSwap(50, poolAB, tokA, 100, 80),
+		{from: testAddr(2), to: botContract, swaps: []synthSwap{botSwap(poolAB, tokB, 90, 110)}},
+	})
+	if len(got) != 1 {
+		t.Fatalf("want 1 sandwich, got %d: %+v", len(got), got)
+	}
+	sw := got[0]
+	if sw.MatchedOn != "contract" || sw.Attacker != botContract {
+		t.Errorf("matched on %q/%q, want contract/%s", sw.MatchedOn, sw.Attacker, botContract)
+	}
+	if len(sw.AttackerEOAs) != 2 || sw.AttackerEOAs[0] != testAddr(1) || sw.AttackerEOAs[1] != testAddr(2) {
+		t.Errorf("attacker EOAs = %v", sw.AttackerEOAs)
+	}
+	if sw.Victim != testAddr(50) {
+		t.Errorf("victim = %s", sw.Victim)
+	}
+	if sw.Estimate == nil || sw.Estimate.AttackerNet[tokA] != "10" || sw.Estimate.AttackerNet[tokB] != "0" {
+		t.Errorf("estimate = %+v", sw.Estimate)
+	}
+}
+
+func TestSandwichSeveralVictims(t *testing.T) {
+	got := detect(t, []synthTx{
+		{from: testAddr(1), to: botContract, swaps: []synthSwap{botSwap(poolAB, tokA, 100, 90)}},
+		userSwap(50, poolAB, tokA, 100, 80),
+		userSwap(51, poolAB, tokA, 50, 38),
+		userSwap(52, poolAB, tokA, 20, 15),
+		{from: testAddr(1), to: botContract, swaps: []synthSwap{botSwap(poolAB, tokB, 90, 115)}},
+	})
+	if len(got) != 1 {
+		t.Fatalf("want 1 sandwich, got %d", len(got))
+	}
+	if n := len(got[0].Victims); n != 3 {
+		t.Fatalf("want 3 victims, got %d", n)
+	}
+	for i, v := range got[0].Victims {
+		if v.Address != testAddr(50+i) || v.TokenIn != tokA {
+			t.Errorf("victim %d = %+v", i, v)
+		}
+	}
+}
+
+func TestSandwichSplitFrontrunDoesNotMatch(t *testing.T) {
+	// The bot buys twice in the same direction around someone else - no backrun, no sandwich
+	got := detect(t, []synthTx{
+		{from: testAddr(1), to: botContract, swaps: []synthSwap{botSwap(poolAB, tokA, 100, 90)}},
+		userSwap(50, poolAB, tokA, 100, 80),
+		{from: testAddr(1), to: botContract, swaps: []synthSwap{botSwap(poolAB, tokA, 100, 75)}},
+	})
+	if len(got) != 0 {
+		t.Fatalf("want no sandwich, got %+v", got)
+	}
+}
+
+func TestSandwichOppositeVictimDoesNotMatch(t *testing.T) {
+	// The trade in the middle goes the other way, so the frontrun didn't hurt it
+	got := detect(t, []synthTx{
+		{from: testAddr(1), to: botContract, swaps: []synthSwap{botSwap(poolAB, tokA, 100, 90)}},
+		userSwap(50, poolAB, tokB, 90, 95),
+		{from: testAddr(1), to: botContract, swaps: []synthSwap{botSwap(poolAB, tokB, 90, 110)}},
+	})
+	if len(got) != 0 {
+		t.Fatalf("want no sandwich, got %+v", got)
+	}
+}
+
+func TestSandwichPublicRouterIsNotAnAttacker(t *testing.T) {
+	// Three unrelated users who all went through the same router
+	got := detect(t, []synthTx{
+		userSwap(40, poolAB, tokA, 100, 90),
+		userSwap(50, poolAB, tokA, 100, 80),
+		userSwap(41, poolAB, tokB, 90, 110),
+	})
+	if len(got) != 0 {
+		t.Fatalf("want no sandwich, got %+v", got)
+	}
+}
+
+func TestSandwichMultiHopRouteIsOneSandwich(t *testing.T) {
+	// A -> B -> C through both pools, with the V2 recipient of the first hop being the next pool
+	route := func(from, to string, in int64) synthTx {
+		return synthTx{from: from, to: to, swaps: []synthSwap{
+			{pool: poolAB, sender: to, recipient: poolBC, tokenIn: tokA, amountIn: in, amountOut: in - 10},
+			{pool: poolBC, sender: to, recipient: to, tokenIn: tokB, amountIn: in - 10, amountOut: in - 20},
+		}}
+	}
+	back := synthTx{from: testAddr(2), to: botContract, swaps: []synthSwap{
+		{pool: poolBC, sender: botContract, recipient: poolAB, tokenIn: tokC, amountIn: 80, amountOut: 95},
+		{pool: poolAB, sender: botContract, recipient: botContract, tokenIn: tokB, amountIn: 95, amountOut: 105},
+	}}
+	victim := route(testAddr(50), router, 100)
+	victim.swaps[1].recipient = testAddr(50)
+
+	got := detect(t, []synthTx{route(testAddr(1), botContract, 100), victim, back})
+	if len(got) != 1 {
+		t.Fatalf("want 1 merged sandwich, got %d: %+v", len(got), got)
+	}
+	sw := got[0]
+	if len(sw.Pools) != 2 || sw.Pools[0] != poolAB || sw.Pools[1] != poolBC {
+		t.Errorf("pools = %v, want [%s %s]", sw.Pools, poolAB, poolBC)
+	}
+	if len(sw.Victims) != 2 {
+		t.Errorf("want the victim's swap in each pool, got %+v", sw.Victims)
+	}
+}
+
+func TestMergeSwapLegsNetsSamePool(t *testing.T) {
+	legs := mergeSwapLegs([]swapEvent{
+		{TxHash: "0x1", Pool: poolAB, TokenIn: tokA, TokenOut: tokB, AmountIn: big.NewInt(100), AmountOut: big.NewInt(90)},
+		{TxHash: "0x1", Pool: poolAB, TokenIn: tokB, TokenOut: tokA, AmountIn: big.NewInt(40), AmountOut: big.NewInt(45)},
+	})
+	if len(legs) != 1 {
+		t.Fatalf("want 1 leg, got %d", len(legs))
+	}
+	l := legs[0]
+	if l.TokenIn != tokA || l.AmountIn.Int64() != 55 || l.TokenOut != tokB || l.AmountOut.Int64() != 50 {
+		t.Errorf("merged leg = %s %v -> %s %v", l.TokenIn, l.AmountIn, l.TokenOut, l.AmountOut)
+	}
+}
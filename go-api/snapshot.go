@@ -39,7 +39,7 @@ var (
 	// We use RWMutex because reads are way more common than writes (many users, one cache update per TTL).
 	snapshotMu sync.RWMutex
 
-	// snapshotMemo is our in-memory cache. Key is built from query params (limit, sandwich, block).
+	// snapshotMemo is our in-memory cache. Key is built from query params (limit, sandwich, arbitrage, block).
 	// This is super simple caching - production apps would use Redis or Memcached, but for an
 	// educational tool, a map works fine!
 	snapshotMemo = map[string]snapshotEntry{}
@@ -143,12 +143,18 @@ func handleSnapshot(w http.ResponseWriter, r *http.Request) {
 			includeSandwich = true
 		}
 	}
+	includeArbitrage := false
+	if s := r.URL.Query().Get("arbitrage"); s != "" {
+		if s == "1" || s == "true" || s == "yes" {
+			includeArbitrage = true
+		}
+	}
 	blockTag := r.URL.Query().Get("block")
 	if blockTag == "" {
 		blockTag = "latest"
 	}
 
-	cacheKey := fmt.Sprintf("limit=%d|sandwich=%v|block=%s", limit, includeSandwich, blockTag)
+	cacheKey := fmt.Sprintf("limit=%d|sandwich=%v|arbitrage=%v|block=%s", limit, includeSandwich, includeArbitrage, blockTag)
 	if body, ok := snapshotCacheGet(cacheKey); ok && len(body) > 0 {
 		w.Header().Set("content-type", "application/json")
 		_, _ = w.Write(body)
@@ -169,31 +175,30 @@ func handleSnapshot(w http.ResponseWriter, r *http.Request) {
 
 	// Fetch upstream in parallel with a soft overall budget
 	// Expected individual timeouts are enforced in respective HTTP clients (3s default)
-	type arrR = []R
-	recCh := make(chan arrR, 1)
-	delCh := make(chan arrR, 1)
+	recCh := make(chan []*ReceivedBlock, 1)
+	delCh := make(chan []*BidTrace, 1)
 	hdrCh := make(chan json.RawMessage, 1)
 	finCh := make(chan json.RawMessage, 1)
 
 	go func() {
-		var out []R
 		// Try builder_blocks_received first (shows all submissions)
-		if raw, err := relayGET(fmt.Sprintf("/relay/v1/data/bidtraces/builder_blocks_received?limit=%d", limit)); err == nil && raw != nil {
-			if err := json.Unmarshal(raw, &out); err == nil && len(out) > 0 {
+		if raw, err := relayGET(relayQuery{Limit: limit}.path(relayReceivedEndpoint)); err == nil && raw != nil {
+			if out, _, err := decodeReceivedBlocks(raw); err == nil && len(out) > 0 {
 				recCh <- out
 				return
 			}
 		}
 		// Fallback: Use delivered payloads as a proxy for received blocks
-		if raw, err := relayGET(fmt.Sprintf("/relay/v1/data/bidtraces/proposer_payload_delivered?limit=%d", limit)); err == nil && raw != nil {
-			_ = json.Unmarshal(raw, &out)
+		var out []*ReceivedBlock
+		if raw, err := relayGET(relayQuery{Limit: limit}.path(relayDeliveredEndpoint)); err == nil && raw != nil {
+			out, _, _ = decodeReceivedBlocks(raw)
 		}
 		recCh <- out
 	}()
 	go func() {
-		var out []R
-		if raw, err := relayGET(fmt.Sprintf("/relay/v1/data/bidtraces/proposer_payload_delivered?limit=%d", limit)); err == nil && raw != nil {
-			_ = json.Unmarshal(raw, &out)
+		var out []*BidTrace
+		if raw, err := relayGET(relayQuery{Limit: limit}.path(relayDeliveredEndpoint)); err == nil && raw != nil {
+			out, _, _ = decodeBidTraces(raw)
 		}
 		delCh <- out
 	}()
@@ -201,24 +206,26 @@ func handleSnapshot(w http.ResponseWriter, r *http.Request) {
 		var out json.RawMessage
 		// Use relay data as primary source since beacon API only returns 1 header
 		// Relay data includes all the info we need: slot, proposer, gas, payments, etc.
-		if relayRaw, relayErr := relayGET(fmt.Sprintf("/relay/v1/data/bidtraces/proposer_payload_delivered?limit=%d", limit)); relayErr == nil && relayRaw != nil {
-			var bids []map[string]any
-			if err := json.Unmarshal(relayRaw, &bids); err == nil {
+		if relayRaw, relayErr := relayGET(relayQuery{Limit: limit}.path(relayDeliveredEndpoint)); relayErr == nil && relayRaw != nil {
+			if bids, _, err := decodeBidTraces(relayRaw); err == nil {
 				log.Printf("snapshot: got %d relay bids for proposed blocks\n", len(bids))
 				// Build enriched response directly from relay data
 				enriched := make([]R, 0, len(bids))
 				for _, bid := range bids {
 					item := R{
-						"slot":                bid["slot"],
-						"proposer_pubkey":     bid["proposer_pubkey"],
+						"slot":                strconv.FormatUint(bid.Slot, 10),
+						"proposer_pubkey":     bid.ProposerPubkey,
 						"proposer_index":      "", // Not in relay data, but we have pubkey
-						"builder_payment_eth": bid["value"],
-						"block_number":        bid["block_number"],
-						"gas_used":            bid["gas_used"],
-						"gas_limit":           bid["gas_limit"],
-						"num_tx":              bid["num_tx"],
-						"builder_pubkey":      bid["builder_pubkey"],
-						"block_hash":          bid["block_hash"],
+						"builder_payment_wei": bid.Value.String(),
+						"builder_payment_eth": bid.ValueETH(),
+						"value_eth":           bid.ValueETH(),
+						"block_number":        strconv.FormatUint(bid.BlockNumber, 10),
+						"gas_used":            strconv.FormatUint(bid.GasUsed, 10),
+						"gas_limit":           strconv.FormatUint(bid.GasLimit, 10),
+						"gas_utilization":     roundTo(bid.GasUtilization(), 2),
+						"num_tx":              strconv.FormatUint(bid.NumTx, 10),
+						"builder_pubkey":      bid.BuilderPubkey,
+						"block_hash":          bid.BlockHash,
 					}
 					enriched = append(enriched, item)
 					if len(enriched) >= limit {
@@ -234,9 +241,10 @@ func handleSnapshot(w http.ResponseWriter, r *http.Request) {
 		hdrCh <- out
 	}()
 	go func() {
+		// The same view /api/finality serves, so the two never disagree
 		var out json.RawMessage
-		if raw, _, err := beaconGET("/eth/v1/beacon/states/finalized/finality_checkpoints"); err == nil && raw != nil {
-			out = raw
+		if fs, err := loadFinality(); err == nil {
+			out, _ = json.Marshal(fs)
 		}
 		finCh <- out
 	}()
@@ -244,8 +252,8 @@ func handleSnapshot(w http.ResponseWriter, r *http.Request) {
 	// Soft overall wait with fallback defaults
 	timeout := time.After(4500 * time.Millisecond)
 	var (
-		receivedBlocks                 []R
-		deliveredPayloads              []R
+		receivedBlocks                 []*ReceivedBlock
+		deliveredPayloads              []*BidTrace
 		headersOut                     json.RawMessage
 		finalityOut                    json.RawMessage
 		gotRec, gotDel, gotHdr, gotFin bool
@@ -268,10 +276,10 @@ func handleSnapshot(w http.ResponseWriter, r *http.Request) {
 
 	// Build response with status indicators - ensure non-nil values
 	if receivedBlocks == nil {
-		receivedBlocks = []R{}
+		receivedBlocks = []*ReceivedBlock{}
 	}
 	if deliveredPayloads == nil {
-		deliveredPayloads = []R{}
+		deliveredPayloads = []*BidTrace{}
 	}
 
 	relaysData := R{
@@ -302,23 +310,35 @@ func handleSnapshot(w http.ResponseWriter, r *http.Request) {
 		"sources":   sourcesInfo(),
 	}
 
-	if includeSandwich {
-		// Sandwich computation can be heavy; run with a soft budget and don't block the whole snapshot
+	if includeSandwich || includeArbitrage {
+		// MEV computation can be heavy; run with a soft budget and don't block the whole snapshot.
+		// Sandwiches and arbitrage share one receipt scan.
 		mevCh := make(chan R, 1)
 		go func() {
 			b, err := fetchBlockFull(blockTag)
 			var mev R
 			if err == nil && b != nil {
-				if swaps, err2 := collectSwaps(b); err2 == nil {
-					s := detectSandwiches(swaps, b.Number)
-					if len(s) > limit {
-						s = s[:limit]
-					}
+				if receipts, stats, err2 := scanBlockReceipts(b); err2 == nil {
+					swaps := swapsFromReceipts(b, receipts)
 					mev = R{
-						"block":      b.Number,
-						"blockHash":  b.Hash,
-						"swapCount":  len(swaps),
-						"sandwiches": s,
+						"block":     b.Number,
+						"blockHash": b.Hash,
+						"swapCount": len(swaps),
+						"receipts":  stats,
+					}
+					if includeSandwich {
+						s := detectSandwiches(swaps, b.Number)
+						if len(s) > limit {
+							s = s[:limit]
+						}
+						mev["sandwiches"] = s
+					}
+					if includeArbitrage {
+						a := detectArbitrage(receipts, swaps)
+						if len(a) > limit {
+							a = a[:limit]
+						}
+						mev["arbitrages"] = a
 					}
 				} else {
 					mev = R{"error": "receipt scan failed"}
@@ -0,0 +1,387 @@
+// stream.go
+// Server-Sent Events endpoint (/api/stream) that pushes live updates to the browser instead of
+// making it poll /api/snapshot. Every event gets an increasing ID and is kept in a bounded ring
+// buffer per event type, so a client that reconnects with Last-Event-ID picks up exactly where it
+// left off (as long as it wasn't gone so long that a buffer it cares about wrapped). Separate rings
+// keep a busy mempool from pushing the occasional reorg or finality event out of the replay.
+//
+// Event types:
+//
+//	pending_tx           - a tx entered our mempool pool
+//	tx_included          - a tx we were tracking showed up in a block
+//	new_head             - a new execution block
+//	relay_bid            - a builder submitted a new block to a relay
+//	finalized_checkpoint - the finalized epoch advanced
+//	chain_reorg          - the beacon node switched to a different head (see beacon_events.go)
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// streamEvent is one message on the stream
+type streamEvent struct {
+	ID   uint64 `json:"id"`
+	Type string `json:"type"`
+	Time int64  `json:"time"`
+	Data any    `json:"data"`
+}
+
+// streamTopics lists the event types clients may filter on
+var streamTopics = []string{"pending_tx", "tx_included", "new_head", "relay_bid", "finalized_checkpoint", "chain_reorg"}
+
+// streamSub is one connected client
+type streamSub struct {
+	ch     chan streamEvent
+	topics map[string]bool // nil means everything
+}
+
+// wants reports whether the client asked for this event type
+func (s *streamSub) wants(typ string) bool {
+	return s.topics == nil || s.topics[typ]
+}
+
+// eventRing holds the most recent events of one type
+type eventRing struct {
+	events  []streamEvent // oldest first, at most size entries
+	evicted uint64        // ID of the newest event pushed out, 0 if none
+	skipped uint64        // newest ID at the last poll skipped for lack of listeners, 0 if none
+}
+
+// eventHub fans events out to subscribers and remembers the most recent ones for resume
+type eventHub struct {
+	mu     sync.Mutex
+	nextID uint64
+	rings  map[string]*eventRing // by event type
+	size   int                   // per ring
+	subs   map[*streamSub]struct{}
+}
+
+// streamHub is the process-wide hub all feeds publish into
+var streamHub = newEventHub(func() int {
+	if s := os.Getenv("STREAM_BUFFER"); s != "" {
+		if n, err := strconv.Atoi(s); err == nil && n > 0 && n <= 100000 {
+			return n
+		}
+	}
+	return 2048
+}())
+
+// streamHeartbeat is how often we send a comment line so proxies don't kill idle connections
+var streamHeartbeat = func() time.Duration {
+	if s := os.Getenv("STREAM_HEARTBEAT_SECONDS"); s != "" {
+		if n, err := strconv.Atoi(s); err == nil && n > 0 && n <= 300 {
+			return time.Duration(n) * time.Second
+		}
+	}
+	return 15 * time.Second
+}()
+
+// newEventHub creates a hub that keeps the last size events of each type
+func newEventHub(size int) *eventHub {
+	return &eventHub{size: size, rings: map[string]*eventRing{}, subs: map[*streamSub]struct{}{}}
+}
+
+// publish records an event and hands it to every interested subscriber.
+// A subscriber that can't keep up is disconnected; it can resume with Last-Event-ID.
+func (h *eventHub) publish(typ string, data any) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.nextID++
+	ev := streamEvent{ID: h.nextID, Type: typ, Time: time.Now().Unix(), Data: data}
+	ring := h.ring(typ)
+	ring.events = append(ring.events, ev)
+	if over := len(ring.events) - h.size; over > 0 {
+		ring.evicted = ring.events[over-1].ID
+		ring.events = ring.events[over:]
+	}
+
+	for sub := range h.subs {
+		if !sub.wants(typ) {
+			continue
+		}
+		select {
+		case sub.ch <- ev:
+		default:
+			delete(h.subs, sub)
+			close(sub.ch)
+		}
+	}
+}
+
+// ring returns the ring for typ, creating it if needed. Callers hold h.mu.
+func (h *eventHub) ring(typ string) *eventRing {
+	ring := h.rings[typ]
+	if ring == nil {
+		ring = &eventRing{}
+		h.rings[typ] = ring
+	}
+	return ring
+}
+
+// subscribe registers a client and returns the buffered events after lastID it should replay first,
+// in ID order. missed is true when a ring the client wants has dropped events newer than lastID,
+// a poller skipped its topic since then, or lastID is from before a restart (newer than any we issued).
+func (h *eventHub) subscribe(topics map[string]bool, lastID uint64) (sub *streamSub, backlog []streamEvent, missed bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	sub = &streamSub{ch: make(chan streamEvent, 256), topics: topics}
+	h.subs[sub] = struct{}{}
+
+	if lastID == 0 {
+		return sub, nil, false
+	}
+	if lastID > h.nextID {
+		return sub, nil, true
+	}
+	for typ, ring := range h.rings {
+		if !sub.wants(typ) {
+			continue
+		}
+		if ring.evicted > lastID || ring.skipped >= lastID {
+			missed = true
+		}
+		for _, ev := range ring.events {
+			if ev.ID > lastID {
+				backlog = append(backlog, ev)
+			}
+		}
+	}
+	sort.Slice(backlog, func(i, j int) bool { return backlog[i].ID < backlog[j].ID })
+	return sub, backlog, missed
+}
+
+// unsubscribe removes a client (safe to call after publish already dropped it)
+func (h *eventHub) unsubscribe(sub *streamSub) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subs[sub]; ok {
+		delete(h.subs, sub)
+		close(sub.ch)
+	}
+}
+
+// skipIdle lets pollers skip work nobody is listening for. It reports whether there are no
+// subscribers, and if so notes the gap in typ so clients resuming from before it get a resync.
+func (h *eventHub) skipIdle(typ string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.subs) > 0 {
+		return false
+	}
+	h.ring(typ).skipped = h.nextID
+	return true
+}
+
+// writeSSE writes one event in text/event-stream framing
+func writeSSE(w http.ResponseWriter, ev streamEvent) error {
+	payload, err := json.Marshal(ev.Data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, payload)
+	return err
+}
+
+// handleStream serves GET /api/stream?topics=new_head,tx_included
+// Resume by sending the Last-Event-ID header (browsers' EventSource does this automatically)
+// or a lastEventId query param.
+func handleStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeErr(w, http.StatusInternalServerError, "STREAM", "Streaming not supported", "")
+		return
+	}
+
+	// Parse topic filter
+	var topics map[string]bool
+	if s := r.URL.Query().Get("topics"); s != "" {
+		topics = map[string]bool{}
+		for _, t := range strings.Split(s, ",") {
+			t = strings.TrimSpace(t)
+			if t == "" {
+				continue
+			}
+			known := false
+			for _, k := range streamTopics {
+				if k == t {
+					known = true
+					break
+				}
+			}
+			if !known {
+				writeErr(w, http.StatusBadRequest, "BAD_REQUEST", "Unknown topic "+t, "Valid topics: "+strings.Join(streamTopics, ", "))
+				return
+			}
+			topics[t] = true
+		}
+	}
+
+	// Resume point
+	var lastID uint64
+	lastRaw := r.Header.Get("Last-Event-ID")
+	if lastRaw == "" {
+		lastRaw = r.URL.Query().Get("lastEventId")
+	}
+	if lastRaw != "" {
+		if n, err := strconv.ParseUint(lastRaw, 10, 64); err == nil {
+			lastID = n
+		}
+	}
+
+	sub, backlog, missed := streamHub.subscribe(topics, lastID)
+	defer streamHub.unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // stop nginx from buffering the stream
+	w.WriteHeader(http.StatusOK)
+
+	// Tell the client how long to wait before reconnecting
+	fmt.Fprintf(w, "retry: 3000\n\n")
+	if missed {
+		// Events were lost while the client was away (a ring wrapped, a poller stood down, or we
+		// restarted) - it should refetch a snapshot
+		fmt.Fprintf(w, "event: resync\ndata: {}\n\n")
+	}
+	for _, ev := range backlog {
+		if writeSSE(w, ev) != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-sub.ch:
+			if !ok {
+				return // too slow - dropped by the hub
+			}
+			if writeSSE(w, ev) != nil {
+				return
+			}
+			flusher.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprintf(w, ": heartbeat %d\n\n", time.Now().Unix()); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// === Feeds ===
+// The mempool monitor publishes pending_tx / tx_included / new_head itself.
+// These pollers cover relays and finality, and only do work while someone is listening.
+
+// startStreamPollers kicks off the background pollers that feed the stream
+func startStreamPollers() {
+	go pollRelayBids()
+	go pollFinalizedCheckpoint()
+}
+
+// pollRelayBids publishes builder submissions we haven't seen before.
+// builder_blocks_received needs a filter (relays answer 400 without one), so we ask for the
+// previous and the current slot by number.
+func pollRelayBids() {
+	seen := map[string]time.Time{}
+	ticker := time.NewTicker(12 * time.Second) // one slot
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if streamHub.skipIdle("relay_bid") {
+			continue
+		}
+		clk, err := getChainClock()
+		if err != nil {
+			continue
+		}
+		current := clk.CurrentSlot(time.Now())
+		slots := []uint64{current}
+		if current > 0 {
+			slots = []uint64{current - 1, current}
+		}
+
+		for _, slot := range slots {
+			slot := slot
+			raw, err := relayGET(relayQuery{Slot: &slot}.path(relayReceivedEndpoint))
+			if err != nil {
+				continue
+			}
+			bids, _, err := decodeReceivedBlocks(raw)
+			if err != nil {
+				continue
+			}
+
+			// Relays return newest first; publish oldest first so the stream reads in order
+			for i := len(bids) - 1; i >= 0; i-- {
+				if _, dup := seen[bids[i].BlockHash]; dup {
+					continue
+				}
+				seen[bids[i].BlockHash] = time.Now()
+				streamHub.publish("relay_bid", bids[i])
+			}
+		}
+
+		// Forget old hashes so the seen set stays small
+		for h, t := range seen {
+			if time.Since(t) > 10*time.Minute {
+				delete(seen, h)
+			}
+		}
+	}
+}
+
+// pollFinalizedCheckpoint publishes when the finalized epoch moves forward.
+// While the beacon event stream is live it publishes finality itself, so we stand down.
+func pollFinalizedCheckpoint() {
+	var lastEpoch string
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if beaconState.live() || streamHub.skipIdle("finalized_checkpoint") {
+			continue
+		}
+		raw, status, err := beaconGET("/eth/v1/beacon/states/finalized/finality_checkpoints")
+		if err != nil || status/100 != 2 {
+			continue
+		}
+		var cp struct {
+			Data struct {
+				Finalized struct {
+					Epoch string `json:"epoch"`
+					Root  string `json:"root"`
+				} `json:"finalized"`
+			} `json:"data"`
+		}
+		if json.Unmarshal(raw, &cp) != nil || cp.Data.Finalized.Epoch == "" {
+			continue
+		}
+		if cp.Data.Finalized.Epoch != lastEpoch {
+			lastEpoch = cp.Data.Finalized.Epoch
+			streamHub.publish("finalized_checkpoint", map[string]any{
+				"epoch": cp.Data.Finalized.Epoch,
+				"root":  cp.Data.Finalized.Root,
+			})
+			log.Printf("stream: finalized epoch %s\n", lastEpoch)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import "testing"
+
+func TestEventHubRingPerTopic(t *testing.T) {
+	h := newEventHub(2)
+	h.publish("new_head", 1)    // ID 1
+	h.publish("chain_reorg", 2) // ID 2
+	for i := 0; i < 5; i++ {
+		h.publish("pending_tx", i) // IDs 3..7, only 6 and 7 are kept
+	}
+
+	// A flood of pending txs doesn't push the reorg out of the replay
+	sub, backlog, missed := h.subscribe(map[string]bool{"chain_reorg": true, "new_head": true}, 1)
+	h.unsubscribe(sub)
+	if missed || len(backlog) != 1 || backlog[0].ID != 2 {
+		t.Errorf("reorg-only resume: missed=%v backlog=%+v", missed, backlog)
+	}
+
+	// Everything after ID 1, in order; the pending_tx ring wrapped, so the client must resync
+	sub, backlog, missed = h.subscribe(nil, 1)
+	h.unsubscribe(sub)
+	var ids []uint64
+	for _, ev := range backlog {
+		ids = append(ids, ev.ID)
+	}
+	if !missed || len(ids) != 3 || ids[0] != 2 || ids[1] != 6 || ids[2] != 7 {
+		t.Errorf("full resume: missed=%v ids=%v", missed, ids)
+	}
+
+	// Resuming past the evicted events loses nothing
+	sub, backlog, missed = h.subscribe(nil, 5)
+	h.unsubscribe(sub)
+	if missed || len(backlog) != 2 {
+		t.Errorf("resume from 5: missed=%v backlog=%+v", missed, backlog)
+	}
+}
+
+func TestEventHubResumeAfterGap(t *testing.T) {
+	h := newEventHub(10)
+	h.publish("new_head", 1)  // ID 1
+	h.publish("relay_bid", 2) // ID 2
+
+	// Nobody listening: the relay poller stands down and leaves a gap after ID 2
+	if !h.skipIdle("relay_bid") {
+		t.Fatal("skipIdle with no subscribers = false")
+	}
+	h.publish("new_head", 3) // ID 3
+
+	sub, _, missed := h.subscribe(map[string]bool{"relay_bid": true}, 2)
+	h.unsubscribe(sub)
+	if !missed {
+		t.Error("resume across a skipped relay poll didn't report missed")
+	}
+	sub, backlog, missed := h.subscribe(map[string]bool{"new_head": true}, 2)
+	if missed || len(backlog) != 1 || backlog[0].ID != 3 {
+		t.Errorf("new_head resume: missed=%v backlog=%+v", missed, backlog)
+	}
+
+	// With a subscriber connected the poller does its work
+	if h.skipIdle("relay_bid") {
+		t.Error("skipIdle with a subscriber = true")
+	}
+	h.unsubscribe(sub)
+
+	// An ID from before a server restart is newer than anything this hub issued
+	sub, backlog, missed = h.subscribe(nil, 500)
+	h.unsubscribe(sub)
+	if !missed || len(backlog) != 0 {
+		t.Errorf("resume from a previous run: missed=%v backlog=%+v", missed, backlog)
+	}
+}
@@ -0,0 +1,367 @@
+// swap_decoders.go
+// Every DEX announces a trade with its own event, so recognizing swaps is a registry: one decoder
+// per event signature (topic0), each turning its log into the same normalized shape - which pool,
+// which token went in and which came out, and how much. Sandwich and arbitrage detection only ever
+// see that normalized stream.
+//
+// Covered: Uniswap V2 and its forks, Uniswap V3 and the Sushi V3 fork (same event), PancakeSwap V3,
+// Uniswap V4 (one PoolManager for every pool), Curve TokenExchange/TokenExchangeUnderlying, and the
+// Balancer V2 Vault.
+//
+// Some events don't name their tokens. Uniswap V2/V3-style pools expose token0()/token1(), which
+// we look up once per pool (they never change) and cache. Where we can't, the token is named by
+// its position in the pool ("token0", "coin2") - still enough to tell trade directions apart.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+)
+
+// decodedSwap is one swap, normalized from the trader's point of view
+type decodedSwap struct {
+	Protocol     string
+	Pool         string // pool contract, or pool id for singleton designs (Uniswap V4)
+	Sender       string // who called the pool (often a router or bot contract)
+	Recipient    string // who received the output, when the event says
+	TokenIn      string // token address, or a pool-relative name if the event doesn't say
+	TokenOut     string
+	AmountIn     *big.Int // nil if the log data couldn't be decoded
+	AmountOut    *big.Int
+	SqrtPriceX96 *big.Int // concentrated-liquidity pools: the price after the swap
+}
+
+// swapLog is the part of a receipt log the decoders need
+type swapLog struct {
+	Address string
+	Topics  []string
+	Data    []byte
+}
+
+// swapDecoder recognizes one swap event
+type swapDecoder struct {
+	Protocol  string
+	Signature string
+	Decode    func(lg swapLog) *decodedSwap
+}
+
+// swapDecoders maps topic0 to its decoder; see registerSwapDecoder
+var swapDecoders = map[string]*swapDecoder{}
+
+// registerSwapDecoder adds a decoder, keyed by the keccak of its event signature
+func registerSwapDecoder(d *swapDecoder) {
+	swapDecoders[strings.ToLower(keccakTopic(d.Signature))] = d
+}
+
+func init() {
+	registerSwapDecoder(&swapDecoder{
+		Protocol:  "uniswap_v2",
+		Signature: "Swap(address,uint256,uint256,uint256,uint256,address)",
+		Decode:    decodeUniswapV2Swap,
+	})
+	registerSwapDecoder(&swapDecoder{
+		Protocol:  "uniswap_v3",
+		Signature: "Swap(address,address,int256,int256,uint160,uint128,int24)",
+		Decode:    decodeUniswapV3Swap("uniswap_v3"),
+	})
+	registerSwapDecoder(&swapDecoder{
+		Protocol:  "pancakeswap_v3",
+		Signature: "Swap(address,address,int256,int256,uint160,uint128,int24,uint128,uint128)",
+		Decode:    decodeUniswapV3Swap("pancakeswap_v3"),
+	})
+	registerSwapDecoder(&swapDecoder{
+		Protocol:  "uniswap_v4",
+		Signature: "Swap(bytes32,address,int128,int128,uint160,uint128,int24,uint24)",
+		Decode:    decodeUniswapV4Swap,
+	})
+	registerSwapDecoder(&swapDecoder{
+		Protocol:  "curve",
+		Signature: "TokenExchange(address,int128,uint256,int128,uint256)",
+		Decode:    decodeCurveExchange("coin"),
+	})
+	registerSwapDecoder(&swapDecoder{
+		Protocol:  "curve",
+		Signature: "TokenExchange(address,uint256,uint256,uint256,uint256)", // crypto (v2) pools
+		Decode:    decodeCurveExchange("coin"),
+	})
+	registerSwapDecoder(&swapDecoder{
+		Protocol:  "curve",
+		Signature: "TokenExchangeUnderlying(address,int128,uint256,int128,uint256)",
+		Decode:    decodeCurveExchange("underlying"),
+	})
+	registerSwapDecoder(&swapDecoder{
+		Protocol:  "balancer_v2",
+		Signature: "Swap(bytes32,address,address,uint256,uint256)",
+		Decode:    decodeBalancerSwap,
+	})
+}
+
+// decodeSwapLog runs the matching decoder; ok is false if topic0 isn't a known swap event
+func decodeSwapLog(address string, topics []string, data string) (*decodedSwap, bool) {
+	if len(topics) == 0 {
+		return nil, false
+	}
+	d, ok := swapDecoders[strings.ToLower(topics[0])]
+	if !ok {
+		return nil, false
+	}
+	ds := d.Decode(swapLog{Address: strings.ToLower(address), Topics: topics, Data: decodeHex(data)})
+	ds.Protocol = d.Protocol
+	return ds, true
+}
+
+// logWord returns the i-th 32-byte word of log data as an unsigned integer, or nil
+func logWord(data []byte, i int) *big.Int {
+	if len(data) < (i+1)*32 {
+		return nil
+	}
+	return new(big.Int).SetBytes(data[i*32 : (i+1)*32])
+}
+
+// toSigned256 reinterprets a 256-bit word as a two's complement int256 (smaller signed ints
+// are sign-extended to 256 bits by the ABI, so this covers int128 too)
+func toSigned256(v *big.Int) *big.Int {
+	if v.Bit(255) == 1 {
+		return new(big.Int).Sub(v, new(big.Int).Lsh(big.NewInt(1), 256))
+	}
+	return v
+}
+
+// topicAt returns the address in topics[i], or ""
+func topicAt(topics []string, i int) string {
+	if len(topics) <= i {
+		return ""
+	}
+	return topicAddress(topics[i])
+}
+
+// fromPoolDeltas fills direction and amounts from the pool's net change per token
+// (positive = the pool received it). Anything but one token in and one out leaves amounts nil.
+func (ds *decodedSwap) fromPoolDeltas(delta0, delta1 *big.Int) {
+	switch {
+	case delta0.Sign() > 0 && delta1.Sign() < 0:
+		ds.TokenIn, ds.TokenOut = "token0", "token1"
+		ds.AmountIn, ds.AmountOut = delta0, new(big.Int).Neg(delta1)
+	case delta1.Sign() > 0 && delta0.Sign() < 0:
+		ds.TokenIn, ds.TokenOut = "token1", "token0"
+		ds.AmountIn, ds.AmountOut = delta1, new(big.Int).Neg(delta0)
+	}
+}
+
+// decodeUniswapV2Swap: Swap(address indexed sender, uint amount0In, uint amount1In,
+// uint amount0Out, uint amount1Out, address indexed to)
+func decodeUniswapV2Swap(lg swapLog) *decodedSwap {
+	ds := &decodedSwap{Pool: lg.Address, Sender: topicAt(lg.Topics, 1), Recipient: topicAt(lg.Topics, 2)}
+	in0, in1, out0, out1 := logWord(lg.Data, 0), logWord(lg.Data, 1), logWord(lg.Data, 2), logWord(lg.Data, 3)
+	if out1 == nil {
+		return ds
+	}
+	ds.fromPoolDeltas(new(big.Int).Sub(in0, out0), new(big.Int).Sub(in1, out1))
+	return ds
+}
+
+// decodeUniswapV3Swap: Swap(address indexed sender, address indexed recipient, int256 amount0,
+// int256 amount1, uint160 sqrtPriceX96, ...). Amounts are the pool's deltas. PancakeSwap V3 adds
+// protocol fee fields at the end, which we don't need.
+func decodeUniswapV3Swap(protocol string) func(lg swapLog) *decodedSwap {
+	return func(lg swapLog) *decodedSwap {
+		ds := &decodedSwap{Pool: lg.Address, Sender: topicAt(lg.Topics, 1), Recipient: topicAt(lg.Topics, 2)}
+		a0, a1, sqrtP := logWord(lg.Data, 0), logWord(lg.Data, 1), logWord(lg.Data, 2)
+		if sqrtP == nil {
+			return ds
+		}
+		ds.fromPoolDeltas(toSigned256(a0), toSigned256(a1))
+		ds.SqrtPriceX96 = sqrtP
+		return ds
+	}
+}
+
+// decodeUniswapV4Swap: Swap(PoolId indexed id, address indexed sender, int128 amount0,
+// int128 amount1, uint160 sqrtPriceX96, ...). Every V4 pool lives inside the PoolManager, so the
+// pool is the id, not the log address. Unlike V3 the amounts are the SWAPPER's deltas
+// (negative = paid into the pool), so we flip them.
+func decodeUniswapV4Swap(lg swapLog) *decodedSwap {
+	ds := &decodedSwap{Sender: topicAt(lg.Topics, 2)}
+	if len(lg.Topics) > 1 {
+		ds.Pool = strings.ToLower(lg.Topics[1])
+	}
+	a0, a1, sqrtP := logWord(lg.Data, 0), logWord(lg.Data, 1), logWord(lg.Data, 2)
+	if sqrtP == nil {
+		return ds
+	}
+	ds.fromPoolDeltas(new(big.Int).Neg(toSigned256(a0)), new(big.Int).Neg(toSigned256(a1)))
+	ds.SqrtPriceX96 = sqrtP
+	return ds
+}
+
+// decodeCurveExchange: TokenExchange(address indexed buyer, sold_id, tokens_sold, bought_id,
+// tokens_bought). Curve pools hold 2-8 coins, named here by index ("coin0", or "underlying0" for
+// the lending pools' underlying tokens). We never ask the pool for coins(i): those names only become
+// addresses through the transaction's Transfer logs (resolveByTransfers in arbitrage.go).
+func decodeCurveExchange(prefix string) func(lg swapLog) *decodedSwap {
+	return func(lg swapLog) *decodedSwap {
+		buyer := topicAt(lg.Topics, 1)
+		ds := &decodedSwap{Pool: lg.Address, Sender: buyer, Recipient: buyer}
+		soldID, sold, boughtID, bought := logWord(lg.Data, 0), logWord(lg.Data, 1), logWord(lg.Data, 2), logWord(lg.Data, 3)
+		if bought == nil {
+			return ds
+		}
+		ds.TokenIn = fmt.Sprintf("%s%s", prefix, toSigned256(soldID))
+		ds.TokenOut = fmt.Sprintf("%s%s", prefix, toSigned256(boughtID))
+		ds.AmountIn, ds.AmountOut = sold, bought
+		return ds
+	}
+}
+
+// decodeBalancerSwap: Swap(bytes32 indexed poolId, address indexed tokenIn, address indexed tokenOut,
+// uint256 amountIn, uint256 amountOut), emitted by the Vault. A pool id starts with the pool's address.
+func decodeBalancerSwap(lg swapLog) *decodedSwap {
+	ds := &decodedSwap{TokenIn: topicAt(lg.Topics, 2), TokenOut: topicAt(lg.Topics, 3)}
+	if len(lg.Topics) > 1 && len(lg.Topics[1]) >= 42 {
+		ds.Pool = strings.ToLower(lg.Topics[1][:42])
+	}
+	in, out := logWord(lg.Data, 0), logWord(lg.Data, 1)
+	if out == nil || ds.TokenIn == "" || ds.TokenOut == "" {
+		ds.TokenIn, ds.TokenOut = "", ""
+		return ds
+	}
+	ds.AmountIn, ds.AmountOut = in, out
+	return ds
+}
+
+// === Pool token lookups ===
+// Uniswap V2/V3-style pools (and their forks) expose token0(), token1() and factory(). The answers
+// never change, so each pool is asked once. The factory also tells the forks apart.
+
+// poolTokens is what we know about a pool's tokens
+type poolTokens struct {
+	Token0, Token1, Factory string
+}
+
+var (
+	poolTokenMu    sync.Mutex
+	poolTokenCache = map[string]*poolTokens{} // nil entry = the contract answered but isn't a pool
+)
+
+// poolTokenCacheMax bounds the cache; it's cleared when full (pools are cheap to look up again)
+const poolTokenCacheMax = 50000
+
+// knownFactories names the protocol behind a pool's factory
+var knownFactories = map[string]string{
+	"0x5c69bee701ef814a2b6a3edd4b1652cb9cc5aa6f": "uniswap_v2",
+	"0xc0aee478e3658e2610c5f7a4a2e1777ce9e4f2ac": "sushiswap_v2",
+	"0x1f98431c8ad98523631ae4a59f267346ea31f984": "uniswap_v3",
+	"0xbaceb8ec6b9355dfc0269c18bac9d6e2bdc29c4f": "sushiswap_v3",
+	"0x0bfbcf9fa4f9c56b0f40a671ad40e0805a091865": "pancakeswap_v3",
+}
+
+// Function selectors for the pool getters
+const (
+	selectorToken0  = "0x0dfe1681"
+	selectorToken1  = "0xd21220a7"
+	selectorFactory = "0xc45a0155"
+)
+
+// lookupPoolTokens returns token0/token1/factory for each pool, asking the node (in one batch)
+// only about pools we haven't seen before. Only definitive answers are cached: calls that
+// succeeded or reverted. A pool whose lookup hit a transport or rate-limit error is retried next time.
+func lookupPoolTokens(pools []string) map[string]*poolTokens {
+	out := make(map[string]*poolTokens, len(pools))
+	var missing []string
+	poolTokenMu.Lock()
+	for _, p := range pools {
+		if pt, ok := poolTokenCache[p]; ok {
+			out[p] = pt
+		} else {
+			missing = append(missing, p)
+		}
+	}
+	poolTokenMu.Unlock()
+	if len(missing) == 0 {
+		return out
+	}
+
+	calls := make([]rpcBatchRequest, 0, 3*len(missing))
+	for _, p := range missing {
+		for _, sel := range []string{selectorToken0, selectorToken1, selectorFactory} {
+			calls = append(calls, rpcBatchRequest{Method: "eth_call", Params: []any{map[string]string{"to": p, "data": sel}, "latest"}})
+		}
+	}
+	results, _ := rpcBatchCall(calls) // failed chunks show up as per-call errors
+
+	addrAt := func(i int) string {
+		if results[i].Err != nil {
+			return ""
+		}
+		var h string
+		if json.Unmarshal(results[i].Result, &h) != nil {
+			return ""
+		}
+		h = strings.TrimPrefix(h, "0x")
+		if len(h) != 64 {
+			return ""
+		}
+		return "0x" + strings.ToLower(h[24:])
+	}
+
+	poolTokenMu.Lock()
+	defer poolTokenMu.Unlock()
+	if len(poolTokenCache)+len(missing) > poolTokenCacheMax {
+		poolTokenCache = map[string]*poolTokens{}
+	}
+	for i, p := range missing {
+		t0, t1 := addrAt(3*i), addrAt(3*i+1)
+		var pt *poolTokens
+		if t0 != "" && t1 != "" {
+			pt = &poolTokens{Token0: t0, Token1: t1, Factory: addrAt(3*i + 2)}
+		}
+		if results[3*i].definitive() && results[3*i+1].definitive() && results[3*i+2].definitive() {
+			poolTokenCache[p] = pt
+		}
+		out[p] = pt
+	}
+	return out
+}
+
+// resolveSwapTokens replaces "token0"/"token1" with real addresses where the pool tells us, and
+// names V2/V3 forks by their factory. Curve's "coinN"/"underlyingN" are left alone (see
+// decodeCurveExchange).
+func resolveSwapTokens(swaps []swapEvent) {
+	var pools []string
+	seen := map[string]bool{}
+	for _, s := range swaps {
+		if !strings.HasPrefix(s.TokenIn, "token") || s.Dex == "uniswap_v4" || seen[s.Pool] {
+			continue
+		}
+		seen[s.Pool] = true
+		pools = append(pools, s.Pool)
+	}
+	if len(pools) == 0 {
+		return
+	}
+	info := lookupPoolTokens(pools)
+
+	name := func(pt *poolTokens, t string) string {
+		switch t {
+		case "token0":
+			return pt.Token0
+		case "token1":
+			return pt.Token1
+		}
+		return t
+	}
+	for i := range swaps {
+		s := &swaps[i]
+		pt := info[s.Pool]
+		if pt == nil || !seen[s.Pool] {
+			continue
+		}
+		s.TokenIn, s.TokenOut = name(pt, s.TokenIn), name(pt, s.TokenOut)
+		if proto, ok := knownFactories[pt.Factory]; ok {
+			s.Dex = proto
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// abiWord encodes a decimal integer (negative for int types) as one 32-byte log data word
+func abiWord(dec string) string {
+	v, ok := new(big.Int).SetString(dec, 10)
+	if !ok {
+		panic("bad decimal " + dec)
+	}
+	if v.Sign() < 0 {
+		v.Add(v, new(big.Int).Lsh(big.NewInt(1), 256))
+	}
+	return fmt.Sprintf("%064x", v)
+}
+
+// logData joins words into a log's data field
+func logData(words ...string) string {
+	return "0x" + strings.Join(words, "")
+}
+
+// Logs laid out as mainnet emits them: real topic0s and contracts, with round amounts
+func TestDecodeSwapLog(t *testing.T) {
+	const (
+		v2Pair      = "0xB4e16d0168e52d35CaCD2c6185b44281Ec28C9Dc" // USDC/WETH
+		v3Pool      = "0x88e6A0c2dDD26FEEb64F039a2c41296FcB3f5640" // USDC/WETH 0.05%
+		poolManager = "0x000000000004444c5dc75cB358380D2e3dE08A90"
+		vault       = "0xBA12222222228d8Ba445958a75a0704d566BF2C8"
+		threePool   = "0xbEbc44782C7dB0a1A60Cb6fe97d0b483032FF1C7"
+		tricrypto2  = "0xD51a44d3FaE010294C616388b506AcdA1bfAAE46"
+		aavePool    = "0xDeBF20617708857ebe4F679508E7b7863a8A8EeE"
+
+		router = "0x000000000000000000000000" + "7a250d5630b4cf539739df2c5dacb4c659f2488d"
+		trader = "0x000000000000000000000000" + "00000000000000000000000000000000000e0a01"
+		weth   = "0x000000000000000000000000" + "c02aaa39b223fe8d0a0e5c4f27ead9083c756cc2"
+		bal    = "0x000000000000000000000000" + "ba100000625a3754423978a60c9317c58a424e3d"
+	)
+	sqrtP := "1584563250285286751870879006720000"
+
+	tests := []struct {
+		name    string
+		address string
+		topics  []string
+		data    string
+
+		protocol, pool, sender string
+		tokenIn, tokenOut      string
+		amountIn, amountOut    string
+		sqrtPriceX96           string
+	}{
+		{
+			name:     "uniswap v2 sells USDC for WETH",
+			address:  v2Pair,
+			topics:   []string{"0xd78ad95fa46c994b6551d0da85fc275fe613ce37657fb8d5e3d130840159d822", router, trader},
+			data:     logData(abiWord("2500000000"), abiWord("0"), abiWord("0"), abiWord("1000000000000000000")),
+			protocol: "uniswap_v2", pool: strings.ToLower(v2Pair), sender: topicAddress(router),
+			tokenIn: "token0", tokenOut: "token1", amountIn: "2500000000", amountOut: "1000000000000000000",
+		},
+		{
+			// Both in-amounts set (a flash swap paid back partly in token1): only the net deltas count
+			name:     "uniswap v2 nets in and out per token",
+			address:  v2Pair,
+			topics:   []string{"0xd78ad95fa46c994b6551d0da85fc275fe613ce37657fb8d5e3d130840159d822", router, trader},
+			data:     logData(abiWord("100"), abiWord("5"), abiWord("0"), abiWord("40")),
+			protocol: "uniswap_v2", pool: strings.ToLower(v2Pair), sender: topicAddress(router),
+			tokenIn: "token0", tokenOut: "token1", amountIn: "100", amountOut: "35",
+		},
+		{
+			name:     "uniswap v3 pool deltas",
+			address:  v3Pool,
+			topics:   []string{"0xc42079f94a6350d7e6235f29174924f928cc2ac818eb64fed8004e115fbcca67", router, trader},
+			data:     logData(abiWord("-2500000000"), abiWord("1000000000000000000"), abiWord(sqrtP), abiWord("1000"), abiWord("-200000")),
+			protocol: "uniswap_v3", pool: strings.ToLower(v3Pool), sender: topicAddress(router),
+			tokenIn: "token1", tokenOut: "token0", amountIn: "1000000000000000000", amountOut: "2500000000", sqrtPriceX96: sqrtP,
+		},
+		{
+			// V4 reports the swapper's deltas: -1 ETH paid in, +2500 USDC out
+			name:    "uniswap v4 flips swapper deltas",
+			address: poolManager,
+			topics: []string{"0x40e9cecb9f5f1f1c5b9c97dec2917b7ee92e57ba5563708daca94dd84ad7112f",
+				"0x21C67E77068DE97969BA93D4AAB21826D33CA12BB9F565D8496E8FDA8A82CA27", router},
+			data:     logData(abiWord("-1000000000000000000"), abiWord("2500000000"), abiWord(sqrtP), abiWord("1000"), abiWord("-200000"), abiWord("500")),
+			protocol: "uniswap_v4", pool: "0x21c67e77068de97969ba93d4aab21826d33ca12bb9f565d8496e8fda8a82ca27", sender: topicAddress(router),
+			tokenIn: "token0", tokenOut: "token1", amountIn: "1000000000000000000", amountOut: "2500000000", sqrtPriceX96: sqrtP,
+		},
+		{
+			// The pool id is the pool address followed by its specialization and nonce
+			name:    "balancer vault names tokens and the pool",
+			address: vault,
+			topics: []string{"0x2170c741c41531aec20e7c107c24eecfdd15e69c9bb0a8dd37b1840b9e0b207b",
+				"0x5c6ee304399dbdb9c8ef030ab642b10820db8f56000200000000000000000014", weth, bal},
+			data:     logData(abiWord("1000000000000000000"), abiWord("350000000000000000000")),
+			protocol: "balancer_v2", pool: "0x5c6ee304399dbdb9c8ef030ab642b10820db8f56",
+			tokenIn: topicAddress(weth), tokenOut: topicAddress(bal), amountIn: "1000000000000000000", amountOut: "350000000000000000000",
+		},
+		{
+			name:     "curve 3pool USDC to USDT",
+			address:  threePool,
+			topics:   []string{"0x8b3e96f2b889fa771c53c981b40daf005f63f637f1869f707052d15a3dd97140", trader},
+			data:     logData(abiWord("1"), abiWord("1000000000"), abiWord("2"), abiWord("999800000")),
+			protocol: "curve", pool: strings.ToLower(threePool), sender: topicAddress(trader),
+			tokenIn: "coin1", tokenOut: "coin2", amountIn: "1000000000", amountOut: "999800000",
+		},
+		{
+			// int128 ids are sign-extended; a negative one must not read as a huge index
+			name:     "curve signed ids",
+			address:  threePool,
+			topics:   []string{"0x8b3e96f2b889fa771c53c981b40daf005f63f637f1869f707052d15a3dd97140", trader},
+			data:     logData(abiWord("-1"), abiWord("7"), abiWord("0"), abiWord("6")),
+			protocol: "curve", pool: strings.ToLower(threePool), sender: topicAddress(trader),
+			tokenIn: "coin-1", tokenOut: "coin0", amountIn: "7", amountOut: "6",
+		},
+		{
+			name:     "curve crypto pool WETH to USDT",
+			address:  tricrypto2,
+			topics:   []string{"0xb2e76ae99761dc136e598d4a629bb347eccb9532a5f8bbd72e18467c3c34cc98", trader},
+			data:     logData(abiWord("2"), abiWord("1000000000000000000"), abiWord("0"), abiWord("2498000000")),
+			protocol: "curve", pool: strings.ToLower(tricrypto2), sender: topicAddress(trader),
+			tokenIn: "coin2", tokenOut: "coin0", amountIn: "1000000000000000000", amountOut: "2498000000",
+		},
+		{
+			name:     "curve underlying",
+			address:  aavePool,
+			topics:   []string{"0xd013ca23e77a65003c2c659c5442c00c805371b7fc1ebd4c206c41d1536bd90b", trader},
+			data:     logData(abiWord("0"), abiWord("1000000000000000000000"), abiWord("1"), abiWord("999500000")),
+			protocol: "curve", pool: strings.ToLower(aavePool), sender: topicAddress(trader),
+			tokenIn: "underlying0", tokenOut: "underlying1", amountIn: "1000000000000000000000", amountOut: "999500000",
+		},
+		{
+			name:     "truncated data decodes the pool but no amounts",
+			address:  v3Pool,
+			topics:   []string{"0xc42079f94a6350d7e6235f29174924f928cc2ac818eb64fed8004e115fbcca67", router, trader},
+			data:     logData(abiWord("-2500000000")),
+			protocol: "uniswap_v3", pool: strings.ToLower(v3Pool), sender: topicAddress(router),
+		},
+	}
+
+	str := func(v *big.Int) string {
+		if v == nil {
+			return ""
+		}
+		return v.String()
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ds, ok := decodeSwapLog(tc.address, tc.topics, tc.data)
+			if !ok {
+				t.Fatal("topic0 not recognized")
+			}
+			got := []string{ds.Protocol, ds.Pool, ds.Sender, ds.TokenIn, ds.TokenOut, str(ds.AmountIn), str(ds.AmountOut), str(ds.SqrtPriceX96)}
+			want := []string{tc.protocol, tc.pool, tc.sender, tc.tokenIn, tc.tokenOut, tc.amountIn, tc.amountOut, tc.sqrtPriceX96}
+			if fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("got  %q\nwant %q", got, want)
+			}
+		})
+	}
+
+	if _, ok := decodeSwapLog(v2Pair, []string{"0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"}, "0x"); ok {
+		t.Error("a Transfer log decoded as a swap")
+	}
+}
+
+func TestLookupPoolTokensCachesOnlyDefinitiveAnswers(t *testing.T) {
+	const (
+		goodPool    = "0x0000000000000000000000000000000000001001"
+		notAPool    = "0x0000000000000000000000000000000000001002"
+		limitedPool = "0x0000000000000000000000000000000000001003"
+	)
+	stubRPC(t, func(method string, params []json.RawMessage) (any, *rpcError) {
+		to, data := ethCallTarget(params)
+		switch to {
+		case goodPool:
+			// Answer with an address ending in the selector's last byte
+			return "0x" + strings.Repeat("0", 62) + data[8:], nil
+		case notAPool:
+			return nil, &rpcError{Code: 3, Message: "execution reverted"}
+		}
+		return nil, &rpcError{Code: -32005, Message: "rate limit exceeded"}
+	})
+
+	got := lookupPoolTokens([]string{goodPool, notAPool, limitedPool})
+	if pt := got[goodPool]; pt == nil || !strings.HasSuffix(pt.Token0, "81") || !strings.HasSuffix(pt.Token1, "a7") || !strings.HasSuffix(pt.Factory, "55") {
+		t.Errorf("good pool = %+v", pt)
+	}
+	if got[notAPool] != nil || got[limitedPool] != nil {
+		t.Errorf("non-pools resolved: %+v %+v", got[notAPool], got[limitedPool])
+	}
+
+	poolTokenMu.Lock()
+	_, goodCached := poolTokenCache[goodPool]
+	revert, revertCached := poolTokenCache[notAPool]
+	_, limitedCached := poolTokenCache[limitedPool]
+	poolTokenMu.Unlock()
+	if !goodCached || !revertCached || revert != nil {
+		t.Errorf("definitive answers not cached: good=%v revert=%v (%+v)", goodCached, revertCached, revert)
+	}
+	if limitedCached {
+		t.Error("a rate-limited lookup was cached; it should be retried")
+	}
+}
@@ -75,13 +75,24 @@ func handleTrackTx(w http.ResponseWriter, r *http.Request) {
         "beacon":     nil,
         "decoded":    nil,
     }
+    if pending {
+        resp["confirmation"] = txConfirmation{State: "pending"}
+    }
 
     var rawReceipt json.RawMessage
+    var rawBlock json.RawMessage
 
-    // Get receipt for actual gas used and status
+    // Get receipt (actual gas used and status) and the containing block in one batched round-trip
     if !pending {
-        receiptData, err := rpcCall("eth_getTransactionReceipt", []any{t.Hash})
-        if err == nil && string(receiptData) != "null" {
+        results, _ := rpcBatchCall([]rpcBatchRequest{
+            {Method: "eth_getTransactionReceipt", Params: []any{t.Hash}},
+            {Method: "eth_getBlockByNumber", Params: []any{*t.BlockNumber, true}},
+        })
+        if results[1].Err == nil {
+            rawBlock = results[1].Result
+        }
+        receiptData, err := results[0].Result, results[0].Err
+        if err == nil && len(receiptData) > 0 && string(receiptData) != "null" {
             rawReceipt = receiptData
             var receipt struct {
                 Status          string `json:"status"`
@@ -113,8 +124,7 @@ func handleTrackTx(w http.ResponseWriter, r *http.Request) {
             inclusion["transaction_index"] = *t.TransactionIndex
         }
 
-        rawBlock, err := rpcCall("eth_getBlockByNumber", []any{*t.BlockNumber, true})
-        if err == nil && string(rawBlock) != "null" {
+        if len(rawBlock) > 0 && string(rawBlock) != "null" {
             var b struct {
                 Hash         string `json:"hash"`
                 Timestamp    string `json:"timestamp"`
@@ -163,56 +173,55 @@ func handleTrackTx(w http.ResponseWriter, r *http.Request) {
 
                 // track relays by block number
                 if n, err := parseHexUint64(*t.BlockNumber); err == nil {
-                    rawRel, relErr := relayGET("/relay/v1/data/bidtraces/proposer_payload_delivered?limit=200")
-                    if relErr == nil {
-                        var entries []map[string]any
-                        if json.Unmarshal(rawRel, &entries) == nil {
-                            for _, entry := range entries {
-                                if bn, ok := entry["block_number"].(string); ok && bn == strconv.FormatUint(n, 10) {
-                                    resp["pbs_relay"] = map[string]any{
-                                        "builder_pubkey": entry["builder_pubkey"],
-                                        "proposer_pubkey": entry["proposer_pubkey"],
-                                        "value": entry["value"],
-                                        "relay": entry["relay"],
-                                    }
-                                    break
-                                }
-                            }
+                    // Ask every relay for exactly this block instead of scanning recent payloads,
+                    // so older txs get PBS info too
+                    if entry, _, relErr := relayDeliveredFor(n); relErr == nil && entry != nil {
+                        resp["pbs_relay"] = map[string]any{
+                            "builder_pubkey": entry.BuilderPubkey,
+                            "proposer_pubkey": entry.ProposerPubkey,
+                            "value": entry.Value.String(),
+                            "value_eth": entry.ValueETH(),
+                            "relay": entry.Relay,
+                            "relays": entry.Relays,
+                            "slot": strconv.FormatUint(entry.Slot, 10),
                         }
                     }
 
-                    if rawGenesis, _, err := beaconGET("/eth/v1/beacon/genesis"); err == nil {
-                        var genesis struct {
-                            Data struct {
-                                GenesisTime string `json:"genesis_time"`
-                            } `json:"data"`
-                        }
-                        if json.Unmarshal(rawGenesis, &genesis) == nil {
-                            tsHex := strings.TrimPrefix(b.Timestamp, "0x")
-                            blockTs, _ := strconv.ParseUint(tsHex, 16, 64)
-                            genesisTs, _ := strconv.ParseUint(genesis.Data.GenesisTime, 10, 64)
-                            var slot uint64
-                            if blockTs >= genesisTs {
-                                slot = (blockTs - genesisTs) / 12
+                    // Find the beacon block that actually carries this payload - the timestamp only
+                    // tells us which slot to look in, not whether the block is canonical
+                    if clk, err := getChainClock(); err == nil {
+                        blockTs, _ := parseHexUint64(b.Timestamp)
+                        slot, _ := clk.SlotAt(blockTs)
+                        match, _ := resolveBeaconBlock(b.Hash, blockTs)
+                        if fs, err := loadFinality(); err == nil {
+                            // The finalized checkpoint is the first slot of its epoch; that slot
+                            // and everything before it is final
+                            epoch := fs.Finalized.Epoch
+                            finalizedSlot := fs.Finalized.Slot
+                            // A block that lost its slot never becomes final, whatever the epoch says
+                            canonical := match == nil || match.Status == "canonical" || match.Status == "unknown"
+                            resp["beacon"] = map[string]any{
+                                "slot":            slot,
+                                "epoch":           clk.EpochOf(slot),
+                                "is_finalized":    canonical && slot <= finalizedSlot,
+                                "finalized_epoch": epoch,
+                                "finalized_slot":  finalizedSlot,
+                                "justified_epoch": fs.CurrentJustified.Epoch,
+                                "block":           match,
+                            }
+                            conf := fs.confirmationFor(&n, blockTs)
+                            if !canonical {
+                                // The EL may still call it safe, but the beacon chain dropped it
+                                conf.State = "included"
+                                conf.Note = "beacon block is " + match.Status + "; this inclusion will not finalize"
+                                conf.ExpectedFinalizedAt, conf.EtaSeconds = nil, nil
                             }
-                            rawFinality, _, err := beaconGET("/eth/v1/beacon/states/finalized/finality_checkpoints")
-                            if err == nil {
-                                var final struct {
-                                    Data struct {
-                                        Finalized struct {
-                                            Epoch string `json:"epoch"`
-                                        } `json:"finalized"`
-                                    } `json:"data"`
-                                }
-                                if json.Unmarshal(rawFinality, &final) == nil {
-                                    epoch, _ := strconv.ParseUint(final.Data.Finalized.Epoch, 10, 64)
-                                    finalizedSlot := epoch*32 + 31
-                                    resp["beacon"] = map[string]any{
-                                        "slot":            slot,
-                                        "is_finalized":    slot <= finalizedSlot,
-                                        "finalized_epoch": epoch,
-                                    }
-                                }
+                            resp["confirmation"] = conf
+                        } else if match != nil {
+                            resp["beacon"] = map[string]any{
+                                "slot":  slot,
+                                "epoch": clk.EpochOf(slot),
+                                "block": match,
                             }
                         }
                     }
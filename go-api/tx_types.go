@@ -0,0 +1,124 @@
+// tx_types.go
+// Helpers for the different Ethereum transaction types and their fee rules.
+//   - type 0 (legacy):       one gasPrice, everything above the base fee is the tip
+//   - type 1 (EIP-2930):     legacy pricing plus an access list of pre-warmed addresses/slots
+//   - type 2 (EIP-1559):     maxFeePerGas + maxPriorityFeePerGas; you pay base fee + tip, capped at maxFee
+//   - type 3 (EIP-4844):     EIP-1559 pricing plus blobs, which have their own maxFeePerBlobGas
+//   - type 4 (EIP-7702):     EIP-1559 pricing plus an authorization list that lets EOAs delegate to code
+package main
+
+import (
+	"math/big"
+	"strings"
+)
+
+// accessListEntry is one address (and its storage slots) from an EIP-2930 access list
+type accessListEntry struct {
+	Address     string   `json:"address"`
+	StorageKeys []string `json:"storageKeys"`
+}
+
+// setCodeAuthorization is one EIP-7702 authorization tuple
+type setCodeAuthorization struct {
+	ChainID string `json:"chainId"`
+	Address string `json:"address"` // contract the EOA delegates to
+	Nonce   string `json:"nonce"`
+	YParity string `json:"yParity"`
+	R       string `json:"r"`
+	S       string `json:"s"`
+}
+
+// txTypeNames maps the hex type field to a readable name (also used as metrics keys)
+var txTypeNames = map[string]string{
+	"0x0": "legacy",
+	"0x1": "accessList",
+	"0x2": "dynamicFee",
+	"0x3": "blob",
+	"0x4": "setCode",
+}
+
+// txTypeName returns the readable type of a tx; a missing type means legacy
+func txTypeName(tx PendingTx) string {
+	if tx.Type == nil {
+		return "legacy"
+	}
+	t := strings.ToLower(*tx.Type)
+	if name, ok := txTypeNames[t]; ok {
+		return name
+	}
+	return "type" + strings.TrimPrefix(t, "0x")
+}
+
+// usesDynamicFee reports whether the tx is priced with maxFeePerGas/maxPriorityFeePerGas
+func usesDynamicFee(tx PendingTx) bool {
+	switch txTypeName(tx) {
+	case "legacy", "accessList":
+		return false
+	}
+	return tx.MaxFeePerGas != nil
+}
+
+// hexBig parses an optional hex quantity; nil or garbage gives nil
+func hexBig(h *string) *big.Int {
+	if h == nil || *h == "" {
+		return nil
+	}
+	v, ok := new(big.Int).SetString(strings.TrimPrefix(*h, "0x"), 16)
+	if !ok {
+		return nil
+	}
+	return v
+}
+
+// effectiveTip is what the block proposer actually earns per gas from tx at the given base fee.
+// The result can be negative, meaning the tx can't be included until the base fee drops.
+// With no base fee known we fall back to the tip the sender asked for.
+func effectiveTip(tx PendingTx, baseFee *big.Int) *big.Int {
+	if usesDynamicFee(tx) {
+		maxFee := hexBig(tx.MaxFeePerGas)
+		tip := hexBig(tx.MaxPriorityFeePerGas)
+		if tip == nil {
+			tip = new(big.Int)
+		}
+		if baseFee == nil || maxFee == nil {
+			return tip
+		}
+		headroom := new(big.Int).Sub(maxFee, baseFee)
+		if headroom.Cmp(tip) < 0 {
+			return headroom
+		}
+		return tip
+	}
+
+	gasPrice := hexBig(tx.GasPrice)
+	if gasPrice == nil {
+		return new(big.Int)
+	}
+	if baseFee == nil {
+		return gasPrice
+	}
+	return new(big.Int).Sub(gasPrice, baseFee)
+}
+
+// effectiveGasPrice is what the sender pays per gas at the given base fee (base fee + tip)
+func effectiveGasPrice(tx PendingTx, baseFee *big.Int) *big.Int {
+	tip := effectiveTip(tx, baseFee)
+	if baseFee == nil {
+		if usesDynamicFee(tx) {
+			if maxFee := hexBig(tx.MaxFeePerGas); maxFee != nil {
+				return maxFee // best guess without a base fee: the cap
+			}
+		}
+		return tip
+	}
+	return new(big.Int).Add(baseFee, tip)
+}
+
+// weiToGwei converts wei to a float gwei value for display
+func weiToGwei(v *big.Int) float64 {
+	if v == nil {
+		return 0
+	}
+	f, _ := new(big.Float).Quo(new(big.Float).SetInt(v), big.NewFloat(1e9)).Float64()
+	return f
+}
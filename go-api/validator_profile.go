@@ -0,0 +1,355 @@
+// validator_profile.go
+// Follow one validator end to end: its status and balance, the duties the beacon chain has
+// assigned it (proposing blocks, attesting every epoch, sync committees), and what it earned for
+// the blocks it recently proposed.
+//
+// Duties are only known a little ahead: proposers for the current epoch (most nodes also answer
+// for the next one), attesters for the current and next epoch, sync committees for the current
+// and next ~27-hour period. Everything comes from the standard beacon API via beaconGET/beaconPOST.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// validatorDuty is one upcoming proposer or attester duty
+type validatorDuty struct {
+	Kind              string  `json:"kind"` // proposer or attester
+	Slot              uint64  `json:"slot"`
+	Epoch             uint64  `json:"epoch"`
+	SlotStart         uint64  `json:"slot_start"`
+	InSeconds         int64   `json:"in_seconds"`
+	CommitteeIndex    *uint64 `json:"committee_index,omitempty"`
+	CommitteePosition *uint64 `json:"committee_position,omitempty"`
+}
+
+// proposalReward is what the validator earned (or missed) for one past proposal slot
+type proposalReward struct {
+	Slot              uint64 `json:"slot"`
+	Epoch             uint64 `json:"epoch"`
+	Status            string `json:"status"` // proposed, missed or unknown
+	TotalGwei         uint64 `json:"total_gwei"`
+	TotalETH          string `json:"total_eth"`
+	AttestationsGwei  uint64 `json:"attestations_gwei"`
+	SyncAggregateGwei uint64 `json:"sync_aggregate_gwei"`
+	SlashingsGwei     uint64 `json:"slashings_gwei"` // proposer + attester slashings included
+}
+
+// syncCommitteeMembership is whether the validator sits in one sync committee period
+type syncCommitteeMembership struct {
+	Period    uint64   `json:"period"`
+	FromEpoch uint64   `json:"from_epoch"`
+	ToEpoch   uint64   `json:"to_epoch"` // exclusive
+	Member    bool     `json:"member"`
+	Positions []string `json:"positions,omitempty"` // indices in the 512-member committee
+}
+
+// gweiToETH formats a gwei amount as ETH
+func gweiToETH(gwei uint64) string {
+	return weiToETHDecimal(new(big.Int).Mul(new(big.Int).SetUint64(gwei), big.NewInt(1e9)))
+}
+
+// beaconData decodes the "data" field of a beacon API response, turning non-2xx into an error
+func beaconData(raw json.RawMessage, status int, err error, out any) error {
+	if err != nil {
+		return err
+	}
+	if status/100 != 2 {
+		return fmt.Errorf("HTTP %d", status)
+	}
+	var resp struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return err
+	}
+	return json.Unmarshal(resp.Data, out)
+}
+
+// proposerSlotsFor returns the slots in epoch that index is scheduled to propose
+func proposerSlotsFor(epoch uint64, index string) ([]uint64, error) {
+	var duties []struct {
+		ValidatorIndex string `json:"validator_index"`
+		Slot           string `json:"slot"`
+	}
+	raw, status, err := beaconGET(fmt.Sprintf("/eth/v1/validator/duties/proposer/%d", epoch))
+	if err := beaconData(raw, status, err, &duties); err != nil {
+		return nil, err
+	}
+	var slots []uint64
+	for _, d := range duties {
+		if d.ValidatorIndex != index {
+			continue
+		}
+		if n, err := strconv.ParseUint(d.Slot, 10, 64); err == nil {
+			slots = append(slots, n)
+		}
+	}
+	return slots, nil
+}
+
+// attesterDutyFor returns index's attestation duty in epoch (every active validator has one)
+func attesterDutyFor(epoch uint64, index string) (*validatorDuty, error) {
+	var duties []struct {
+		Slot                    string `json:"slot"`
+		CommitteeIndex          string `json:"committee_index"`
+		ValidatorCommitteeIndex string `json:"validator_committee_index"`
+	}
+	raw, status, err := beaconPOST(fmt.Sprintf("/eth/v1/validator/duties/attester/%d", epoch), []string{index})
+	if err := beaconData(raw, status, err, &duties); err != nil {
+		return nil, err
+	}
+	if len(duties) == 0 {
+		return nil, nil // not active in that epoch
+	}
+	slot, err := strconv.ParseUint(duties[0].Slot, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("bad slot %q", duties[0].Slot)
+	}
+	d := &validatorDuty{Kind: "attester", Slot: slot}
+	if n, err := strconv.ParseUint(duties[0].CommitteeIndex, 10, 64); err == nil {
+		d.CommitteeIndex = &n
+	}
+	if n, err := strconv.ParseUint(duties[0].ValidatorCommitteeIndex, 10, 64); err == nil {
+		d.CommitteePosition = &n
+	}
+	return d, nil
+}
+
+// syncMembershipFor checks whether index is in the sync committee for the period containing epoch
+func syncMembershipFor(clk *chainClock, epoch uint64, index string) (*syncCommitteeMembership, error) {
+	var duties []struct {
+		ValidatorSyncCommitteeIndices []string `json:"validator_sync_committee_indices"`
+	}
+	raw, status, err := beaconPOST(fmt.Sprintf("/eth/v1/validator/duties/sync/%d", epoch), []string{index})
+	if err := beaconData(raw, status, err, &duties); err != nil {
+		return nil, err
+	}
+	period := epoch / clk.SyncCommitteePeriod
+	m := &syncCommitteeMembership{
+		Period:    period,
+		FromEpoch: period * clk.SyncCommitteePeriod,
+		ToEpoch:   (period + 1) * clk.SyncCommitteePeriod,
+	}
+	if len(duties) > 0 {
+		m.Member = true
+		m.Positions = duties[0].ValidatorSyncCommitteeIndices
+	}
+	return m, nil
+}
+
+// proposalRewardAt looks up the block reward for a slot the validator was due to propose
+func proposalRewardAt(clk *chainClock, slot uint64) proposalReward {
+	pr := proposalReward{Slot: slot, Epoch: clk.EpochOf(slot), Status: "unknown", TotalETH: "0"}
+	raw, status, err := beaconGET(fmt.Sprintf("/eth/v1/beacon/rewards/blocks/%d", slot))
+	if err == nil && status == http.StatusNotFound {
+		pr.Status = "missed" // no block in that slot
+		return pr
+	}
+	var rw struct {
+		Total             string `json:"total"`
+		Attestations      string `json:"attestations"`
+		SyncAggregate     string `json:"sync_aggregate"`
+		ProposerSlashings string `json:"proposer_slashings"`
+		AttesterSlashings string `json:"attester_slashings"`
+	}
+	if beaconData(raw, status, err, &rw) != nil {
+		return pr
+	}
+	num := func(s string) uint64 {
+		n, _ := strconv.ParseUint(s, 10, 64)
+		return n
+	}
+	pr.Status = "proposed"
+	pr.TotalGwei = num(rw.Total)
+	pr.TotalETH = gweiToETH(pr.TotalGwei)
+	pr.AttestationsGwei = num(rw.Attestations)
+	pr.SyncAggregateGwei = num(rw.SyncAggregate)
+	pr.SlashingsGwei = num(rw.ProposerSlashings) + num(rw.AttesterSlashings)
+	return pr
+}
+
+// handleValidatorProfile serves GET /api/validator/{index}?epochs=8
+func handleValidatorProfile(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/api/validator/")
+	if _, err := strconv.ParseUint(id, 10, 64); err != nil && !reBLSKey.MatchString(id) {
+		writeErr(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid validator", "Use /api/validator/{index} with a decimal validator index (a 0x pubkey works too)")
+		return
+	}
+	lookback := uint64(8)
+	if s := r.URL.Query().Get("epochs"); s != "" {
+		if n, err := strconv.ParseUint(s, 10, 64); err == nil && n > 0 && n <= 64 {
+			lookback = n
+		}
+	}
+
+	clk, err := getChainClock()
+	if err != nil {
+		writeErr(w, http.StatusTooManyRequests, "BEACON", "Could not load chain genesis/spec", "The slot clock comes from /eth/v1/beacon/genesis and /eth/v1/config/spec. Check BEACON_API_URL.")
+		return
+	}
+	val, found, err := fetchBeaconValidator(strings.ToLower(id))
+	if err != nil {
+		writeErr(w, http.StatusTooManyRequests, "BEACON", "Validator lookup failed", "Public beacon API may be rate limiting. Try again or point BEACON_API_URL to your own consensus client.")
+		return
+	}
+	if !found {
+		writeErr(w, http.StatusNotFound, "NOT_FOUND", "Unknown validator", "The beacon node has no validator with this index or pubkey (deposits take a while to be processed)")
+		return
+	}
+	index := val.Index
+
+	now := time.Now()
+	currentSlot := clk.CurrentSlot(now)
+	currentEpoch := clk.EpochOf(currentSlot)
+	duty := func(d *validatorDuty) *validatorDuty {
+		d.Epoch = clk.EpochOf(d.Slot)
+		d.SlotStart = clk.SlotStart(d.Slot)
+		d.InSeconds = int64(d.SlotStart) - now.Unix()
+		return d
+	}
+
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		upcoming  = []*validatorDuty{}
+		attesting = []*validatorDuty{}
+		past      = []proposalReward{}
+		syncs     = []*syncCommitteeMembership{}
+		problems  = []string{}
+	)
+	problem := func(what string, err error) {
+		mu.Lock()
+		problems = append(problems, what+": "+err.Error())
+		mu.Unlock()
+	}
+
+	// Proposer duties: the lookback epochs (for rewards) plus the current and next epoch
+	from := uint64(0)
+	if currentEpoch+1 > lookback {
+		from = currentEpoch + 1 - lookback
+	}
+	for epoch := from; epoch <= currentEpoch+1; epoch++ {
+		wg.Add(1)
+		go func(epoch uint64) {
+			defer wg.Done()
+			slots, err := proposerSlotsFor(epoch, index)
+			if err != nil {
+				if epoch <= currentEpoch { // many nodes don't know next epoch's proposers yet
+					problem(fmt.Sprintf("proposer duties for epoch %d", epoch), err)
+				}
+				return
+			}
+			for _, slot := range slots {
+				if slot >= currentSlot {
+					d := duty(&validatorDuty{Kind: "proposer", Slot: slot})
+					mu.Lock()
+					upcoming = append(upcoming, d)
+					mu.Unlock()
+					continue
+				}
+				pr := proposalRewardAt(clk, slot)
+				mu.Lock()
+				past = append(past, pr)
+				mu.Unlock()
+			}
+		}(epoch)
+	}
+
+	// Attester duties for the current and next epoch
+	for _, epoch := range []uint64{currentEpoch, currentEpoch + 1} {
+		wg.Add(1)
+		go func(epoch uint64) {
+			defer wg.Done()
+			d, err := attesterDutyFor(epoch, index)
+			if err != nil {
+				problem(fmt.Sprintf("attester duties for epoch %d", epoch), err)
+				return
+			}
+			if d != nil {
+				duty(d)
+				mu.Lock()
+				attesting = append(attesting, d)
+				mu.Unlock()
+			}
+		}(epoch)
+	}
+
+	// Sync committee for the current and next period (post-Altair only)
+	if clk.SyncCommitteePeriod > 0 {
+		nextPeriodStart := (currentEpoch/clk.SyncCommitteePeriod + 1) * clk.SyncCommitteePeriod
+		for _, epoch := range []uint64{currentEpoch, nextPeriodStart} {
+			wg.Add(1)
+			go func(epoch uint64) {
+				defer wg.Done()
+				m, err := syncMembershipFor(clk, epoch, index)
+				if err != nil {
+					problem(fmt.Sprintf("sync committee duties for epoch %d", epoch), err)
+					return
+				}
+				mu.Lock()
+				syncs = append(syncs, m)
+				mu.Unlock()
+			}(epoch)
+		}
+	}
+	wg.Wait()
+
+	sort.Slice(upcoming, func(i, j int) bool { return upcoming[i].Slot < upcoming[j].Slot })
+	sort.Slice(attesting, func(i, j int) bool { return attesting[i].Slot < attesting[j].Slot })
+	sort.Slice(past, func(i, j int) bool { return past[i].Slot > past[j].Slot })
+	sort.Slice(syncs, func(i, j int) bool { return syncs[i].Period < syncs[j].Period })
+
+	var totalGwei uint64
+	missed := 0
+	for _, pr := range past {
+		totalGwei += pr.TotalGwei
+		if pr.Status == "missed" {
+			missed++
+		}
+	}
+	gwei := func(s string) uint64 {
+		n, _ := strconv.ParseUint(s, 10, 64)
+		return n
+	}
+
+	resp := map[string]any{
+		"index":                  index,
+		"pubkey":                 val.Validator.Pubkey,
+		"status":                 val.Status,
+		"balance_gwei":           gwei(val.Balance),
+		"balance_eth":            gweiToETH(gwei(val.Balance)),
+		"effective_balance_gwei": gwei(val.Validator.EffectiveBalance),
+		"effective_balance_eth":  gweiToETH(gwei(val.Validator.EffectiveBalance)),
+		"slashed":                val.Validator.Slashed,
+		"activation_epoch":       val.Validator.ActivationEpoch,
+		"exit_epoch":             val.Validator.ExitEpoch,
+		"withdrawal_credentials": val.Validator.WithdrawalCredentials,
+		"current_slot":           currentSlot,
+		"current_epoch":          currentEpoch,
+		"upcoming_proposals":     upcoming,
+		"attester_duties":        attesting,
+		"sync_committee":         syncs,
+		"recent_proposals":       past,
+		"recent_proposals_summary": map[string]any{
+			"lookback_epochs": lookback,
+			"proposals":       len(past),
+			"missed":          missed,
+			"total_gwei":      totalGwei,
+			"total_eth":       gweiToETH(totalGwei),
+		},
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		resp["unavailable"] = problems
+	}
+	writeOK(w, resp)
+}
@@ -0,0 +1,424 @@
+// ws_client.go
+// A small WebSocket (RFC 6455) JSON-RPC client built on the standard library.
+// Ethereum nodes push subscription events (eth_subscribe) over WebSocket, so this is what lets us
+// see pending transactions and new heads the moment the node does, instead of polling.
+// We only implement what a JSON-RPC client needs: text frames, fragmentation, ping/pong and close.
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WebSocket opcodes we care about
+const (
+	wsOpContinuation = 0x0
+	wsOpText         = 0x1
+	wsOpBinary       = 0x2
+	wsOpClose        = 0x8
+	wsOpPing         = 0x9
+	wsOpPong         = 0xA
+)
+
+// wsMaxMessage guards against a misbehaving server sending us an enormous frame
+const wsMaxMessage = 32 << 20
+
+// wsGUID is the magic string from RFC 6455 used to compute Sec-WebSocket-Accept
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// wsConn is one client-side WebSocket connection
+type wsConn struct {
+	conn    net.Conn
+	br      *bufio.Reader
+	writeMu sync.Mutex // frames must not interleave
+}
+
+// wsDial opens a ws:// or wss:// connection and performs the upgrade handshake
+func wsDial(rawURL string, timeout time.Duration) (*wsConn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	host := u.Host
+	dialer := &net.Dialer{Timeout: timeout}
+	var conn net.Conn
+	switch u.Scheme {
+	case "ws":
+		if u.Port() == "" {
+			host += ":80"
+		}
+		conn, err = dialer.Dial("tcp", host)
+	case "wss":
+		if u.Port() == "" {
+			host += ":443"
+		}
+		conn, err = tls.DialWithDialer(dialer, "tcp", host, &tls.Config{ServerName: u.Hostname()})
+	default:
+		return nil, fmt.Errorf("unsupported websocket scheme %q", u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Random 16-byte key; the server proves it speaks WebSocket by hashing it back to us
+	keyBytes := make([]byte, 16)
+	if _, err := rand.Read(keyBytes); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(keyBytes)
+
+	path := u.RequestURI()
+	req, _ := http.NewRequest("GET", "http://"+u.Host+path, nil)
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	if u.User != nil {
+		pass, _ := u.User.Password()
+		req.SetBasicAuth(u.User.Username(), pass)
+	}
+
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		conn.Close()
+		return nil, fmt.Errorf("websocket upgrade failed: HTTP %d", resp.StatusCode)
+	}
+
+	h := sha1.Sum([]byte(key + wsGUID))
+	if resp.Header.Get("Sec-WebSocket-Accept") != base64.StdEncoding.EncodeToString(h[:]) {
+		conn.Close()
+		return nil, errors.New("websocket upgrade failed: bad Sec-WebSocket-Accept")
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	return &wsConn{conn: conn, br: br}, nil
+}
+
+// writeFrame sends a single (unfragmented) frame. Client frames must be masked.
+func (c *wsConn) writeFrame(opcode byte, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	header := []byte{0x80 | opcode} // FIN bit + opcode
+	n := len(payload)
+	switch {
+	case n < 126:
+		header = append(header, 0x80|byte(n))
+	case n <= 0xFFFF:
+		header = append(header, 0x80|126, byte(n>>8), byte(n))
+	default:
+		header = append(header, 0x80|127)
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+
+	mask := make([]byte, 4)
+	if _, err := rand.Read(mask); err != nil {
+		return err
+	}
+	header = append(header, mask...)
+
+	masked := make([]byte, n)
+	for i := range payload {
+		masked[i] = payload[i] ^ mask[i%4]
+	}
+
+	_ = c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	if _, err := c.conn.Write(append(header, masked...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// WriteText sends a text message (JSON-RPC always uses text frames)
+func (c *wsConn) WriteText(p []byte) error {
+	return c.writeFrame(wsOpText, p)
+}
+
+// ReadMessage returns the next complete data message, answering pings along the way
+func (c *wsConn) ReadMessage() ([]byte, error) {
+	var msg []byte
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+
+		switch opcode {
+		case wsOpPing:
+			if err := c.writeFrame(wsOpPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case wsOpPong:
+			continue
+		case wsOpClose:
+			_ = c.writeFrame(wsOpClose, nil)
+			return nil, io.EOF
+		case wsOpText, wsOpBinary, wsOpContinuation:
+			msg = append(msg, payload...)
+			if len(msg) > wsMaxMessage {
+				return nil, errors.New("websocket message too large")
+			}
+			if fin {
+				return msg, nil
+			}
+		default:
+			return nil, fmt.Errorf("unknown websocket opcode %d", opcode)
+		}
+	}
+}
+
+// readFrame reads one raw frame off the wire
+func (c *wsConn) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	var head [2]byte
+	if _, err = io.ReadFull(c.br, head[:]); err != nil {
+		return
+	}
+	fin = head[0]&0x80 != 0
+	opcode = head[0] & 0x0F
+	masked := head[1]&0x80 != 0
+	length := uint64(head[1] & 0x7F)
+
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if length > wsMaxMessage {
+		err = errors.New("websocket frame too large")
+		return
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err = io.ReadFull(c.br, mask[:]); err != nil {
+			return
+		}
+	}
+
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(c.br, payload); err != nil {
+		return
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return
+}
+
+// Close sends a close frame and tears down the TCP connection
+func (c *wsConn) Close() error {
+	_ = c.writeFrame(wsOpClose, nil)
+	return c.conn.Close()
+}
+
+// === JSON-RPC over WebSocket ===
+
+// wsRPCClient multiplexes request/response calls and subscription notifications over one wsConn
+type wsRPCClient struct {
+	conn *wsConn
+
+	mu      sync.Mutex
+	nextID  int
+	pending map[int]*wsPending
+	subs    map[string]chan json.RawMessage
+
+	done chan struct{} // closed when the read loop exits
+	err  error         // why the read loop exited
+}
+
+// wsPending is a call waiting for its response
+type wsPending struct {
+	resp chan rpcResponse
+	sub  chan json.RawMessage // eth_subscribe only: registered by the read loop the moment the response arrives
+}
+
+// wsNotification is the shape of an eth_subscription push message
+type wsNotification struct {
+	ID     *int   `json:"id"`
+	Method string `json:"method"`
+	Params struct {
+		Subscription string          `json:"subscription"`
+		Result       json.RawMessage `json:"result"`
+	} `json:"params"`
+}
+
+// dialWSRPC connects to a JSON-RPC WebSocket endpoint and starts reading
+func dialWSRPC(rawURL string) (*wsRPCClient, error) {
+	conn, err := wsDial(rawURL, 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	c := &wsRPCClient{
+		conn:    conn,
+		pending: map[int]*wsPending{},
+		subs:    map[string]chan json.RawMessage{},
+		done:    make(chan struct{}),
+	}
+	go c.readLoop()
+	return c, nil
+}
+
+// readLoop routes responses to waiting callers and notifications to subscription channels
+func (c *wsRPCClient) readLoop() {
+	var loopErr error
+	defer func() {
+		c.mu.Lock()
+		c.err = loopErr
+		for _, ch := range c.subs {
+			close(ch)
+		}
+		c.subs = map[string]chan json.RawMessage{}
+		c.mu.Unlock()
+		close(c.done)
+	}()
+
+	for {
+		msg, err := c.conn.ReadMessage()
+		if err != nil {
+			loopErr = err
+			return
+		}
+
+		var note wsNotification
+		if err := json.Unmarshal(msg, &note); err != nil {
+			continue // not JSON - ignore
+		}
+
+		if note.Method == "eth_subscription" {
+			c.mu.Lock()
+			ch := c.subs[note.Params.Subscription]
+			c.mu.Unlock()
+			if ch != nil {
+				select {
+				case ch <- note.Params.Result:
+				default:
+					// Consumer is behind - drop rather than stall the whole connection
+				}
+			}
+			continue
+		}
+
+		var resp rpcResponse
+		if err := json.Unmarshal(msg, &resp); err != nil {
+			continue
+		}
+		c.mu.Lock()
+		p := c.pending[resp.ID]
+		delete(c.pending, resp.ID)
+		if p != nil && p.sub != nil && resp.Error == nil {
+			// Register before reading on: the node may send the first notification right behind
+			// the eth_subscribe response, in the same read
+			var subID string
+			if json.Unmarshal(resp.Result, &subID) == nil {
+				c.subs[subID] = p.sub
+			}
+		}
+		c.mu.Unlock()
+		if p != nil {
+			p.resp <- resp
+		}
+	}
+}
+
+// call sends a request and waits for the matching response
+func (c *wsRPCClient) call(method string, params any) (json.RawMessage, error) {
+	return c.do(method, params, nil)
+}
+
+// do is call, optionally handing the read loop a subscription channel to register
+func (c *wsRPCClient) do(method string, params any, sub chan json.RawMessage) (json.RawMessage, error) {
+	c.mu.Lock()
+	c.nextID++
+	id := c.nextID
+	ch := make(chan rpcResponse, 1)
+	c.pending[id] = &wsPending{resp: ch, sub: sub}
+	c.mu.Unlock()
+
+	payload, _ := json.Marshal(rpcRequest{JSONRPC: "2.0", ID: id, Method: method, Params: params})
+	if err := c.conn.WriteText(payload); err != nil {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+		return nil, err
+	}
+
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return nil, resp.Error
+		}
+		return resp.Result, nil
+	case <-c.done:
+		return nil, fmt.Errorf("websocket closed: %v", c.err)
+	case <-time.After(15 * time.Second):
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+		return nil, fmt.Errorf("%s timed out over websocket", method)
+	}
+}
+
+// subscribe calls eth_subscribe and returns a channel of notification payloads.
+// The channel is closed when the connection drops.
+func (c *wsRPCClient) subscribe(params ...any) (<-chan json.RawMessage, error) {
+	ch := make(chan json.RawMessage, 1024)
+	raw, err := c.do("eth_subscribe", params, ch)
+	if err != nil {
+		return nil, err
+	}
+	var subID string
+	if err := json.Unmarshal(raw, &subID); err != nil {
+		return nil, fmt.Errorf("unexpected eth_subscribe result: %s", strings.TrimSpace(string(raw)))
+	}
+	return ch, nil
+}
+
+// Done is closed once the connection has gone away
+func (c *wsRPCClient) Done() <-chan struct{} {
+	return c.done
+}
+
+// Close shuts the connection down
+func (c *wsRPCClient) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,384 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// A local WebSocket stand-in: httptest hijacks the connection after the upgrade and the test
+// drives the frames by hand, so every part of the client's framing can be exercised.
+
+// wsPeer is the server side of one test connection
+type wsPeer struct {
+	t    *testing.T
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+// newWSServer starts a server that upgrades every request and hands the connection to handle
+func newWSServer(t *testing.T, handle func(p *wsPeer)) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "websocket" || r.Header.Get("Sec-WebSocket-Version") != "13" {
+			http.Error(w, "not a websocket upgrade", http.StatusBadRequest)
+			return
+		}
+		h := sha1.Sum([]byte(r.Header.Get("Sec-WebSocket-Key") + wsGUID))
+		conn, brw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("hijack: %v", err)
+			return
+		}
+		defer conn.Close()
+		brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+		brw.WriteString("Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(h[:]) + "\r\n\r\n")
+		brw.Flush()
+		handle(&wsPeer{t: t, conn: conn, br: brw.Reader})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func wsURL(srv *httptest.Server) string {
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+// frame encodes one unmasked server frame
+func frame(fin bool, opcode byte, payload []byte) []byte {
+	b0 := opcode
+	if fin {
+		b0 |= 0x80
+	}
+	out := []byte{b0}
+	n := len(payload)
+	switch {
+	case n < 126:
+		out = append(out, byte(n))
+	case n <= 0xFFFF:
+		out = append(out, 126, byte(n>>8), byte(n))
+	default:
+		out = append(out, 127)
+		out = binary.BigEndian.AppendUint64(out, uint64(n))
+	}
+	return append(out, payload...)
+}
+
+// send writes raw frames in a single write (so the client sees them in one read)
+func (p *wsPeer) send(frames ...[]byte) {
+	var all []byte
+	for _, f := range frames {
+		all = append(all, f...)
+	}
+	if _, err := p.conn.Write(all); err != nil {
+		p.t.Errorf("server write: %v", err)
+	}
+}
+
+// read reads one client frame, checking that it is masked as RFC 6455 requires
+func (p *wsPeer) read() (opcode byte, payload []byte, err error) {
+	_ = p.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var head [2]byte
+	if _, err = io.ReadFull(p.br, head[:]); err != nil {
+		return
+	}
+	if head[0]&0x80 == 0 {
+		return 0, nil, errors.New("client sent a fragmented frame")
+	}
+	if head[1]&0x80 == 0 {
+		return 0, nil, errors.New("client frame is not masked")
+	}
+	opcode = head[0] & 0x0F
+	n := uint64(head[1] & 0x7F)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(p.br, ext[:]); err != nil {
+			return
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(p.br, ext[:]); err != nil {
+			return
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	var mask [4]byte
+	if _, err = io.ReadFull(p.br, mask[:]); err != nil {
+		return
+	}
+	payload = make([]byte, n)
+	if _, err = io.ReadFull(p.br, payload); err != nil {
+		return
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return
+}
+
+// readRequest reads the client's next JSON-RPC request
+func (p *wsPeer) readRequest() rpcRequest {
+	op, payload, err := p.read()
+	if err != nil {
+		p.t.Fatalf("server read: %v", err)
+	}
+	if op != wsOpText {
+		p.t.Fatalf("want a text frame, got opcode %d", op)
+	}
+	var req rpcRequest
+	if err := json.Unmarshal(payload, &req); err != nil {
+		p.t.Fatalf("bad request %q: %v", payload, err)
+	}
+	return req
+}
+
+func TestWSHandshakeRejectsBadAccept(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, brw, _ := w.(http.Hijacker).Hijack()
+		defer conn.Close()
+		brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: bogus\r\n\r\n")
+		brw.Flush()
+	}))
+	defer srv.Close()
+
+	if _, err := wsDial(wsURL(srv), 2*time.Second); err == nil || !strings.Contains(err.Error(), "Sec-WebSocket-Accept") {
+		t.Fatalf("want a Sec-WebSocket-Accept error, got %v", err)
+	}
+}
+
+func TestWSHandshakeRejectsNonUpgrade(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	if _, err := wsDial(wsURL(srv), 2*time.Second); err == nil || !strings.Contains(err.Error(), "HTTP 403") {
+		t.Fatalf("want an HTTP 403 error, got %v", err)
+	}
+}
+
+func TestWSMaskedFramesAndExtendedLengths(t *testing.T) {
+	big := strings.Repeat("x", 70000) // needs the 64-bit length form
+	mid := strings.Repeat("y", 300)   // needs the 16-bit length form
+	got := make(chan string, 2)
+
+	srv := newWSServer(t, func(p *wsPeer) {
+		for i := 0; i < 2; i++ {
+			op, payload, err := p.read()
+			if err != nil || op != wsOpText {
+				t.Errorf("read %d: op=%d err=%v", i, op, err)
+				return
+			}
+			got <- string(payload)
+		}
+		p.send(frame(true, wsOpText, []byte(mid)), frame(true, wsOpText, []byte(big)))
+		p.read() // wait for the client to go away
+	})
+
+	c, err := wsDial(wsURL(srv), 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err := c.WriteText([]byte(mid)); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.WriteText([]byte(big)); err != nil {
+		t.Fatal(err)
+	}
+	if s := <-got; s != mid {
+		t.Errorf("server got %d bytes, want %d", len(s), len(mid))
+	}
+	if s := <-got; s != big {
+		t.Errorf("server got %d bytes, want %d", len(s), len(big))
+	}
+
+	for _, want := range []string{mid, big} {
+		msg, err := c.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(msg) != want {
+			t.Errorf("client got %d bytes, want %d", len(msg), len(want))
+		}
+	}
+}
+
+func TestWSFragmentsWithPingInBetween(t *testing.T) {
+	pong := make(chan string, 1)
+	srv := newWSServer(t, func(p *wsPeer) {
+		// A message in three fragments, with a ping between them (control frames may interleave)
+		p.send(
+			frame(false, wsOpText, []byte(`{"hello":`)),
+			frame(true, wsOpPing, []byte("are you there")),
+			frame(false, wsOpContinuation, []byte(`"wor`)),
+			frame(true, wsOpContinuation, []byte(`ld"}`)),
+		)
+		op, payload, err := p.read()
+		if err != nil || op != wsOpPong {
+			t.Errorf("want a pong, got op=%d err=%v", op, err)
+			return
+		}
+		pong <- string(payload)
+		p.read()
+	})
+
+	c, err := wsDial(wsURL(srv), 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	msg, err := c.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(msg) != `{"hello":"world"}` {
+		t.Errorf("reassembled %q", msg)
+	}
+	select {
+	case p := <-pong:
+		if p != "are you there" {
+			t.Errorf("pong echoed %q", p)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no pong")
+	}
+}
+
+func TestWSCloseFrame(t *testing.T) {
+	closed := make(chan byte, 1)
+	srv := newWSServer(t, func(p *wsPeer) {
+		p.send(frame(true, wsOpClose, []byte{0x03, 0xe8})) // 1000 normal closure
+		op, _, _ := p.read()
+		closed <- op
+	})
+
+	c, err := wsDial(wsURL(srv), 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if _, err := c.ReadMessage(); err != io.EOF {
+		t.Fatalf("want io.EOF after a close frame, got %v", err)
+	}
+	if op := <-closed; op != wsOpClose {
+		t.Errorf("client answered the close with opcode %d", op)
+	}
+}
+
+func TestWSRPCCallAndSubscription(t *testing.T) {
+	srv := newWSServer(t, func(p *wsPeer) {
+		req := p.readRequest()
+		if req.Method != "eth_blockNumber" {
+			t.Errorf("first call = %s", req.Method)
+		}
+		resp, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": "0x10"})
+		p.send(frame(true, wsOpText, resp))
+
+		req = p.readRequest()
+		if req.Method != "eth_subscribe" {
+			t.Errorf("second call = %s", req.Method)
+		}
+		// The subscription response and the first notification arrive in the same read
+		resp, _ = json.Marshal(map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": "0xsub1"})
+		note := func(n int) []byte {
+			b, _ := json.Marshal(map[string]any{
+				"jsonrpc": "2.0",
+				"method":  "eth_subscription",
+				"params":  map[string]any{"subscription": "0xsub1", "result": map[string]any{"n": n}},
+			})
+			return frame(true, wsOpText, b)
+		}
+		other, _ := json.Marshal(map[string]any{
+			"jsonrpc": "2.0",
+			"method":  "eth_subscription",
+			"params":  map[string]any{"subscription": "0xunknown", "result": "ignored"},
+		})
+		p.send(frame(true, wsOpText, resp), note(1), frame(true, wsOpText, other), note(2))
+
+		// Then drop the connection
+		p.send(frame(true, wsOpClose, nil))
+		p.read()
+	})
+
+	c, err := dialWSRPC(wsURL(srv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	raw, err := c.call("eth_blockNumber", []any{})
+	if err != nil || string(raw) != `"0x10"` {
+		t.Fatalf("call = %s, %v", raw, err)
+	}
+
+	ch, err := c.subscribe("newHeads")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for want := 1; want <= 2; want++ {
+		select {
+		case msg, ok := <-ch:
+			if !ok {
+				t.Fatalf("subscription closed before notification %d", want)
+			}
+			var body struct{ N int }
+			if err := json.Unmarshal(msg, &body); err != nil || body.N != want {
+				t.Errorf("notification %d = %s", want, msg)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("notification %d never arrived", want)
+		}
+	}
+
+	// The close frame ends the read loop, which closes the subscription and Done
+	select {
+	case <-c.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("Done not closed after the server closed")
+	}
+	if _, ok := <-ch; ok {
+		t.Error("subscription channel still open after the connection closed")
+	}
+	if _, err := c.call("eth_blockNumber", []any{}); err == nil {
+		t.Error("call on a closed connection succeeded")
+	}
+}
+
+func TestWSRPCErrorResponse(t *testing.T) {
+	srv := newWSServer(t, func(p *wsPeer) {
+		req := p.readRequest()
+		resp, _ := json.Marshal(map[string]any{
+			"jsonrpc": "2.0", "id": req.ID,
+			"error": map[string]any{"code": -32601, "message": "the method eth_subscribe does not exist"},
+		})
+		p.send(frame(true, wsOpText, resp))
+		p.read()
+	})
+
+	c, err := dialWSRPC(wsURL(srv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	_, err = c.subscribe("newPendingTransactions")
+	var rpcErr *rpcError
+	if !errors.As(err, &rpcErr) || rpcErr.Code != -32601 {
+		t.Fatalf("want the node's -32601 error, got %v", err)
+	}
+}
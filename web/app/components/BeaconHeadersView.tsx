@@ -22,12 +22,12 @@ export default function BeaconHeadersView({ data }: BeaconHeadersViewProps) {
   const headers = data.headers;
 
   // Separate blocks with and without MEV payments
-  const mevBlocks = headers.filter(h => h.builder_payment_eth);
-  const vanillaBlocks = headers.filter(h => !h.builder_payment_eth);
+  const mevBlocks = headers.filter(h => h.builder_payment_wei);
+  const vanillaBlocks = headers.filter(h => !h.builder_payment_wei);
 
   // Calculate metrics for MEV blocks
   const totalMevPayments = mevBlocks.reduce((sum, header) => {
-    const value = header.builder_payment_eth ? BigInt(header.builder_payment_eth) : BigInt(0);
+    const value = header.builder_payment_wei ? BigInt(header.builder_payment_wei) : BigInt(0);
     return sum + value;
   }, BigInt(0));
 
@@ -123,12 +123,12 @@ export default function BeaconHeadersView({ data }: BeaconHeadersViewProps) {
               {headers.slice(0, 20).map((header, idx) => {
                 const slot = header.slot ? parseInt(header.slot) : 0;
                 const epoch = slotToEpoch(slot);
-                const payment = header.builder_payment_eth ? weiToEth(header.builder_payment_eth) : null;
+                const payment = header.builder_payment_wei ? weiToEth(header.builder_payment_wei) : null;
                 const gasUsed = header.gas_used ? hexToNumber(header.gas_used) : 0;
                 const gasLimit = header.gas_limit ? hexToNumber(header.gas_limit) : 0;
                 const gasPercent = gasLimit > 0 ? Math.round((gasUsed / gasLimit) * 100) : 0;
                 const numTx = header.num_tx ? hexToNumber(header.num_tx) : 0;
-                const isMev = !!header.builder_payment_eth;
+                const isMev = !!header.builder_payment_wei;
 
                 return (
                   <tr key={idx} className="border-b border-white/5 hover:bg-white/5">
@@ -7,32 +7,29 @@ import React from 'react';
 import { hexToNumber, formatNumber, slotToEpoch, slotToTime } from '../utils/format';
 
 interface FinalityViewProps {
-  data: any;
+  data: any; // the same finality status /api/finality returns
 }
 
 export default function FinalityView({ data }: FinalityViewProps) {
-  if (!data || !data.data) {
+  if (!data || !data.finalized) {
     return <p className="text-white/60">No finality data available</p>;
   }
 
-  const checkpoints = data.data;
+  const checkpoints = data;
 
-  // Parse checkpoint data
-  const previousJustified = checkpoints.previous_justified?.epoch ? parseInt(checkpoints.previous_justified.epoch) : 0;
-  const currentJustified = checkpoints.current_justified?.epoch ? parseInt(checkpoints.current_justified.epoch) : 0;
-  const finalized = checkpoints.finalized?.epoch ? parseInt(checkpoints.finalized.epoch) : 0;
+  // Checkpoint epochs and their first slots
+  const previousJustified = checkpoints.previous_justified?.epoch ?? 0;
+  const currentJustified = checkpoints.current_justified?.epoch ?? 0;
+  const finalized = checkpoints.finalized.epoch ?? 0;
+  const finalizedSlot = checkpoints.finalized.slot ?? 0;
+  const justifiedSlot = checkpoints.current_justified?.slot ?? 0;
+  const previousJustifiedSlot = checkpoints.previous_justified?.slot ?? 0;
 
-  // Calculate slots (32 slots per epoch)
-  const finalizedSlot = finalized * 32;
-  const justifiedSlot = currentJustified * 32;
-  const previousJustifiedSlot = previousJustified * 32;
-
-  // Calculate epoch differences
-  const epochsSinceFinality = currentJustified - finalized;
-  const epochsSinceJustification = currentJustified - previousJustified;
+  // Epochs from the finalized checkpoint to the current epoch
+  const epochsSinceFinality = data.epochs_since_finality ?? 0;
 
   // Determine network health
-  const isHealthy = epochsSinceFinality <= 2; // Normal is 2 epochs to finality
+  const isHealthy = data.healthy ?? epochsSinceFinality <= 2; // Normal is 2 epochs to finality
   const isCritical = epochsSinceFinality > 4;
 
   return (
@@ -56,7 +53,7 @@ export default function FinalityView({ data }: FinalityViewProps) {
               {isCritical ? 'Finality Issues Detected' : isHealthy ? 'Network Finalizing Normally' : 'Slow Finality'}
             </div>
             <div className="text-white/70 text-sm mt-1">
-              {epochsSinceFinality} epoch{epochsSinceFinality !== 1 ? 's' : ''} between justified and finalized
+              {epochsSinceFinality} epoch{epochsSinceFinality !== 1 ? 's' : ''} since the last finalized checkpoint
               {isHealthy ? ' (normal)' : isCritical ? ' (critical)' : ' (delayed)'}
             </div>
           </div>
@@ -100,7 +97,7 @@ export default function FinalityView({ data }: FinalityViewProps) {
               <li><strong>Why This Matters:</strong> Exchanges wait for finality before crediting large deposits. After finalization, your transaction is as permanent as Bitcoin's 6-block confirmation</li>
             </ul>
             <div className="text-cyan-400 text-xs bg-cyan-400/10 border border-cyan-400/20 rounded p-2 mt-2">
-              🔒 <strong>Security Insight:</strong> Currently {epochsSinceFinality} epoch{epochsSinceFinality !== 1 ? 's' : ''} between justified and finalized.
+              🔒 <strong>Security Insight:</strong> Currently {epochsSinceFinality} epoch{epochsSinceFinality !== 1 ? 's' : ''} since the last finalized checkpoint.
               {isHealthy
                 ? ' Network is healthy - transactions finalizing normally!'
                 : isCritical
@@ -171,7 +168,7 @@ export default function FinalityView({ data }: FinalityViewProps) {
         <div className="bg-black/40 border border-white/10 rounded-lg p-3">
           <div className="text-white/60 text-xs mb-1">Blocks Since Finality</div>
           <div className="text-white text-lg font-bold">
-            {formatNumber((currentJustified - finalized) * 32)}
+            {formatNumber(epochsSinceFinality * 32)}
           </div>
           <div className="text-white/50 text-xs mt-1">
             slots (32 slots/epoch × {epochsSinceFinality} epochs)
@@ -597,12 +597,12 @@ export default function Page() {
             </div>
           )}
 
-          {/* High Priority Badge - shows when there are expensive transactions */}
+          {/* High Priority Badge - shows when transactions tip at least the server's threshold */}
           {mempool?.metrics?.highPriorityCount > 0 && (
             <div className="mt-3 inline-flex items-center gap-2 px-3 py-1.5 bg-red-500/10 border border-red-500/30 rounded-full text-sm">
               <span className="text-red-400">🔥</span>
               <span className="text-white/90">
-                {mempool.metrics.highPriorityCount} high-priority tx{mempool.metrics.highPriorityCount !== 1 ? 's' : ''} (&gt;50 gwei)
+                {mempool.metrics.highPriorityCount} high-priority tx{mempool.metrics.highPriorityCount !== 1 ? 's' : ''} (tip &ge; {mempool.metrics.highPriorityTipGwei ?? 2} gwei)
               </span>
             </div>
           )}